@@ -16,6 +16,10 @@ var GetAccountQuery *graphql.Field = &graphql.Field{
 			Type:        graphql.String,
 			Description: "account address",
 		},
+		"height": &graphql.ArgumentConfig{
+			Type:        graphql.Int,
+			Description: "account state at this block height",
+		},
 	},
 	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 		potion, err := GetPotionFromParams(p)
@@ -32,6 +36,15 @@ var GetAccountQuery *graphql.Field = &graphql.Field{
 			return nil, InValidPublicAddress.New()
 		}
 
+		if h, ok := p.Args["height"]; ok {
+			height, ok := h.(int)
+			if !ok || height < 0 {
+				return nil, errors.New("invalid `height` value found")
+			}
+
+			return potion.AccountAt(address, uint64(height))
+		}
+
 		ac, err := potion.Account(address)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,200 @@
+package graphqlapiv1
+
+import (
+	"errors"
+	"time"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/common/keypair"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+	"github.com/graphql-go/graphql"
+
+	"github.com/spikeekips/naru/element"
+)
+
+var GetOperationsQuery *graphql.Field = &graphql.Field{
+	Type: graphql.NewList(OperationType),
+	Args: NewListOptonsArgument().
+		Add(
+			"account",
+			&graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "operations of account",
+			},
+		).
+		Add(
+			"types",
+			&graphql.ArgumentConfig{
+				Type:        graphql.NewList(graphql.String),
+				Description: "operation types",
+			},
+		).
+		Add(
+			"min_amount",
+			&graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "minimum amount",
+			},
+		).
+		Add(
+			"max_amount",
+			&graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "maximum amount",
+			},
+		).
+		Add(
+			"counterparty",
+			&graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "the other account of operation",
+			},
+		).
+		Add(
+			"from_height",
+			&graphql.ArgumentConfig{
+				Type:        graphql.Int,
+				Description: "operations from this block height",
+			},
+		).
+		Add(
+			"to_height",
+			&graphql.ArgumentConfig{
+				Type:        graphql.Int,
+				Description: "operations until this block height",
+			},
+		).
+		Add(
+			"from",
+			&graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "operations of the blocks confirmed from this time, RFC3339",
+			},
+		).
+		Add(
+			"to",
+			&graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "operations of the blocks confirmed until this time, RFC3339",
+			},
+		).
+		Done(),
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		var account string
+		if e, ok := p.Args["account"]; !ok {
+			return nil, errors.New("`account` argument is missing")
+		} else if account, ok = e.(string); !ok {
+			return nil, errors.New("invalid `account` value found")
+		} else if _, err := keypair.Parse(account); err != nil {
+			return nil, InValidPublicAddress.New()
+		}
+
+		potion, err := GetPotionFromParams(p)
+		if err != nil {
+			return nil, err
+		}
+
+		options, err := ListOptonsArgument{}.ListOptions(p)
+		if err != nil {
+			return nil, err
+		}
+
+		filter, found, err := operationFilterArgument(potion, p)
+		if err != nil {
+			return nil, err
+		} else if !found {
+			return []element.Operation{}, nil
+		}
+
+		var iterFunc func() (element.Operation, bool, []byte)
+		var closeFunc func()
+		if filter.IsEmpty() {
+			iterFunc, closeFunc = potion.OperationsByAccount(account, options)
+		} else {
+			iterFunc, closeFunc = potion.OperationsByAccountFilter(account, filter, options)
+		}
+		defer closeFunc()
+
+		var operations []element.Operation
+		for {
+			op, next, _ := iterFunc()
+			if !next {
+				break
+			}
+			operations = append(operations, op)
+		}
+
+		return operations, nil
+	},
+}
+
+// operationFilterArgument makes the filter from the arguments; like the rest
+// api, the time range is converted to the block height range and if no block
+// is in the time range, `found` is false.
+func operationFilterArgument(potion element.Potion, p graphql.ResolveParams) (filter element.OperationFilter, found bool, err error) {
+	if e, ok := p.Args["types"]; ok {
+		l, ok := e.([]interface{})
+		if !ok {
+			return element.OperationFilter{}, false, InValidArgument.New().SetData("types", e)
+		}
+		for _, i := range l {
+			t, ok := i.(string)
+			if !ok {
+				return element.OperationFilter{}, false, InValidArgument.New().SetData("types", e)
+			}
+			filter.Types = append(filter.Types, sebakoperation.OperationType(t))
+		}
+	}
+
+	for k, a := range map[string]*sebakcommon.Amount{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount} {
+		e, ok := p.Args[k]
+		if !ok {
+			continue
+		}
+
+		s, ok := e.(string)
+		if !ok {
+			return element.OperationFilter{}, false, InValidArgument.New().SetData(k, e)
+		}
+		if *a, err = sebakcommon.AmountFromString(s); err != nil {
+			return element.OperationFilter{}, false, InValidArgument.New().SetData(k, s)
+		}
+	}
+
+	if e, ok := p.Args["counterparty"]; ok {
+		if filter.Counterparty, ok = e.(string); !ok {
+			return element.OperationFilter{}, false, InValidArgument.New().SetData("counterparty", e)
+		}
+	}
+
+	for k, h := range map[string]*uint64{"from_height": &filter.FromHeight, "to_height": &filter.ToHeight} {
+		e, ok := p.Args[k]
+		if !ok {
+			continue
+		}
+
+		i, ok := e.(int)
+		if !ok || i < 0 {
+			return element.OperationFilter{}, false, InValidArgument.New().SetData(k, e)
+		}
+		*h = uint64(i)
+	}
+
+	var from, to time.Time
+	for k, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		e, ok := p.Args[k]
+		if !ok {
+			continue
+		}
+
+		s, ok := e.(string)
+		if !ok {
+			return element.OperationFilter{}, false, InValidArgument.New().SetData(k, e)
+		}
+		if *t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return element.OperationFilter{}, false, InValidArgument.New().SetData(k, s)
+		}
+	}
+
+	return filter.WithTime(potion, from, to)
+}
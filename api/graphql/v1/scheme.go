@@ -18,6 +18,7 @@ func NewSchema() graphql.Schema {
 		"block":        GetBlockQuery,
 		"transaction":  GetTransactionQuery,
 		"transactions": GetTransactionsQuery,
+		"operations":   GetOperationsQuery,
 	}
 
 	rootQuery := graphql.ObjectConfig{Name: "RootQuery", Fields: fields}
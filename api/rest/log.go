@@ -1,6 +1,8 @@
 package rest
 
 import (
+	"bufio"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -8,8 +10,11 @@ import (
 
 	sebakcommon "boscoin.io/sebak/lib/common"
 	sebakmetrics "boscoin.io/sebak/lib/metrics"
+	"github.com/gorilla/mux"
 	logging "github.com/inconshreveable/log15"
 	"github.com/prometheus/common/log"
+
+	"github.com/spikeekips/naru/metrics"
 )
 
 type HTTP2ErrorLog15Writer struct {
@@ -80,6 +85,15 @@ func (l *HTTP2ResponseLog15Writer) CloseNotify() <-chan bool {
 	return l.closeNotifier.CloseNotify()
 }
 
+func (l *HTTP2ResponseLog15Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(l.ResponseWriter)
+	if err == nil {
+		l.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
 type HTTP2Log15Handler struct {
 	Log     logging.Logger
 	Handler http.Handler
@@ -152,5 +166,29 @@ func (l HTTP2Log15Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if writer.Status() >= 500 {
 			sebakmetrics.API.RequestErrorsTotal.With(labels...).Add(1)
 		}
+
+		metrics.ObserveHTTPRequest(routeTemplate(l.Handler, r), r.Method, writer.Status(), elapsed)
 	}
 }
+
+// routeTemplate returns the path template of the matched route like
+// `/api/v1/accounts/{id}`, so the metrics labels are not exploded by the
+// request path.
+func routeTemplate(handler http.Handler, r *http.Request) string {
+	router, ok := handler.(*mux.Router)
+	if !ok {
+		return "unknown"
+	}
+
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return "not-found"
+	}
+
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return "unknown"
+	}
+
+	return template
+}
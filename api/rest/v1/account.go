@@ -4,11 +4,16 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
+	sebakapi "boscoin.io/sebak/lib/node/runner/api"
 	sebakresource "boscoin.io/sebak/lib/node/runner/api/resource"
 	"github.com/gorilla/mux"
 
 	"github.com/spikeekips/naru/api/rest"
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/storage"
 )
 
 var (
@@ -21,7 +26,22 @@ func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request) {
 
 	jw := rest.NewJSONWriter(w, r)
 
-	ac, err := h.potion.Account(address)
+	var ac element.Account
+	var err error
+	if s := r.URL.Query().Get("height"); len(s) > 0 {
+		height, e := strconv.ParseUint(s, 10, 64)
+		if e != nil {
+			jw.WriteObject(BadRequestParameter.New().SetData("height", s))
+			return
+		}
+		ac, err = h.potion.AccountAt(address, height)
+		if element.AccountStateNotAvailable.Equal(err) {
+			err = err.(*common.Error).SetData("status", http.StatusNotFound)
+		}
+	} else {
+		ac, err = h.potion.Account(address)
+	}
+
 	if err != nil {
 		jw.WriteObject(err)
 		return
@@ -66,3 +86,30 @@ func (h *Handler) GetAccounts(w http.ResponseWriter, r *http.Request) {
 
 	jw.WriteObject(sebakresource.NewResourceList(rs, "", "", ""))
 }
+
+// GetAccountTransactions lists the transactions of account with the page
+// query.
+func (h *Handler) GetAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	jw := rest.NewJSONWriter(w, r)
+
+	query, err := sebakapi.NewPageQuery(r)
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	address := mux.Vars(r)["id"]
+	if _, err := h.potion.Account(address); err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	lo := query.ListOptions()
+	iterFunc, closeFunc := h.potion.TransactionsByAccount(
+		address,
+		storage.NewDefaultListOptions(lo.Reverse(), lo.Cursor(), lo.Limit()),
+	)
+	defer closeFunc()
+
+	jw.WriteObject(transactionsResourceList(r, lo.Reverse(), iterFunc))
+}
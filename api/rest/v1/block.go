@@ -1,11 +1,15 @@
 package restv1
 
 import (
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakapi "boscoin.io/sebak/lib/node/runner/api"
 	sebakresource "boscoin.io/sebak/lib/node/runner/api/resource"
 	"github.com/gorilla/mux"
 	"github.com/nvellon/hal"
@@ -13,6 +17,7 @@ import (
 	"github.com/spikeekips/naru/api/rest"
 	resourcev1 "github.com/spikeekips/naru/api/rest/v1/resource"
 	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/storage"
 )
 
 func (h *Handler) GetBlock(w http.ResponseWriter, r *http.Request) {
@@ -25,16 +30,7 @@ func (h *Handler) GetBlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var block element.Block
-	var err error
-	var height uint64
-	height, err = strconv.ParseUint(hash, 10, 64)
-	switch {
-	case err == nil:
-		block, err = h.potion.BlockByHeight(height)
-	case err != nil:
-		block, err = h.potion.Block(hash)
-	}
+	block, err := h.blockByHashOrHeight(hash)
 	if err != nil {
 		jw.WriteObject(err)
 		return
@@ -66,3 +62,321 @@ func (h *Handler) GetBlock(w http.ResponseWriter, r *http.Request) {
 
 	jw.WriteObject(rs)
 }
+
+func (h *Handler) blockByHashOrHeight(s string) (element.Block, error) {
+	if height, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return h.potion.BlockByHeight(height)
+	}
+
+	return h.potion.Block(s)
+}
+
+// GetBlockTransactions lists the transactions of block with the page query;
+// the proposer transaction is not included.
+func (h *Handler) GetBlockTransactions(w http.ResponseWriter, r *http.Request) {
+	jw := rest.NewJSONWriter(w, r)
+
+	query, err := sebakapi.NewPageQuery(r)
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	block, err := h.blockByHashOrHeight(mux.Vars(r)["id"])
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	lo := query.ListOptions()
+	iterFunc, closeFunc := h.potion.TransactionsByBlock(
+		block.Hash,
+		storage.NewDefaultListOptions(lo.Reverse(), lo.Cursor(), lo.Limit()),
+	)
+	defer closeFunc()
+
+	jw.WriteObject(transactionsResourceList(r, lo.Reverse(), iterFunc))
+}
+
+// GetBlocksScanSize is the number of blocks, which are read at once to fill
+// the page of GetBlocks.
+var GetBlocksScanSize uint64 = 100
+
+// GetBlocks lists the blocks by height with the page query, `cursor`, `limit`
+// and `reverse`; the cursor is the block height. The blocks can be filtered by
+// `proposer` and the confirmed time, `from` and `to` in RFC3339 format.
+func (h *Handler) GetBlocks(w http.ResponseWriter, r *http.Request) {
+	jw := rest.NewJSONWriter(w, r)
+
+	query, err := sebakapi.NewPageQuery(r)
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	filter, err := newBlocksFilter(r)
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	lo := query.ListOptions()
+
+	var cursor uint64
+	if c := lo.Cursor(); len(c) > 0 {
+		if cursor, err = strconv.ParseUint(string(c), 10, 64); err != nil {
+			jw.WriteObject(BadRequestParameter.New().SetData("cursor", string(c)))
+			return
+		}
+	}
+
+	lastBlock, err := h.potion.LastBlock()
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	blocks := h.blocksPage(lastBlock.Header.Height, cursor, lo.Reverse(), lo.Limit(), filter)
+
+	var rs []sebakresource.Resource
+	for i := range blocks {
+		rs = append(rs, resourcev1.NewBlock(&blocks[i]))
+	}
+
+	var first, last string
+	if len(blocks) > 0 {
+		first = strconv.FormatUint(blocks[0].Header.Height, 10)
+		last = strconv.FormatUint(blocks[len(blocks)-1].Header.Height, 10)
+	}
+
+	jw.WriteObject(newPageResourceList(r, rs, lo.Reverse(), first, last))
+}
+
+// blocksPage reads the blocks after cursor; the blocks are read by
+// GetBlocksScanSize until the page is filled, because some blocks can be
+// filtered out.
+func (h *Handler) blocksPage(last, cursor uint64, reverse bool, limit uint64, filter blocksFilter) []element.Block {
+	var blocks []element.Block
+
+	add := func(block element.Block) bool {
+		if filter.match(block) {
+			blocks = append(blocks, block)
+		}
+
+		return uint64(len(blocks)) < limit && !filter.passed(block, reverse)
+	}
+
+	if !reverse {
+		start := cursor + 1
+		if start < sebakcommon.GenesisBlockHeight {
+			start = sebakcommon.GenesisBlockHeight
+		}
+
+		for ; start <= last; start += GetBlocksScanSize {
+			var stop bool
+			iterFunc, closeFunc := h.potion.BlocksByHeight(start, start+GetBlocksScanSize)
+			for {
+				block, next, _ := iterFunc()
+				if !next {
+					break
+				}
+				if stop = !add(block); stop {
+					break
+				}
+			}
+			closeFunc()
+
+			if stop {
+				break
+			}
+		}
+
+		return blocks
+	}
+
+	top := last
+	if cursor > 0 {
+		top = cursor - 1
+	}
+
+	for top >= sebakcommon.GenesisBlockHeight {
+		start := sebakcommon.GenesisBlockHeight
+		if top >= start+GetBlocksScanSize {
+			start = top - GetBlocksScanSize + 1
+		}
+
+		var chunk []element.Block
+		iterFunc, closeFunc := h.potion.BlocksByHeight(start, top+1)
+		for {
+			block, next, _ := iterFunc()
+			if !next {
+				break
+			}
+			chunk = append(chunk, block)
+		}
+		closeFunc()
+
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if !add(chunk[i]) {
+				return blocks
+			}
+		}
+
+		top = start - 1
+	}
+
+	return blocks
+}
+
+type blocksFilter struct {
+	proposer string
+	from     time.Time
+	to       time.Time
+}
+
+func newBlocksFilter(r *http.Request) (blocksFilter, error) {
+	q := r.URL.Query()
+
+	f := blocksFilter{proposer: q.Get("proposer")}
+	for k, t := range map[string]*time.Time{"from": &f.from, "to": &f.to} {
+		s := q.Get(k)
+		if len(s) < 1 {
+			continue
+		}
+
+		var err error
+		if *t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return blocksFilter{}, BadRequestParameter.New().SetData(k, s)
+		}
+	}
+
+	return f, nil
+}
+
+func (f blocksFilter) match(block element.Block) bool {
+	if len(f.proposer) > 0 && block.Proposer != f.proposer {
+		return false
+	}
+	if !f.from.IsZero() && block.Confirmed.Before(f.from) {
+		return false
+	}
+	if !f.to.IsZero() && block.Confirmed.After(f.to) {
+		return false
+	}
+
+	return true
+}
+
+// passed returns true if the next blocks can not be matched with the time
+// range; the blocks are confirmed in order of height.
+func (f blocksFilter) passed(block element.Block, reverse bool) bool {
+	if reverse {
+		return !f.from.IsZero() && block.Confirmed.Before(f.from)
+	}
+
+	return !f.to.IsZero() && block.Confirmed.After(f.to)
+}
+
+// BlocksStreamHandler streams the new blocks. With `cursor` query or
+// `Last-Event-ID`, the blocks after the cursor height are sent first.
+type BlocksStreamHandler struct {
+	BaseStreamHandler
+	H          *Handler
+	cursor     uint64
+	hasCursor  bool
+	catchupEnd uint64
+	stream     <-chan interface{}
+	closeFunc  func()
+}
+
+func (g BlocksStreamHandler) NewRequest(base BaseStreamHandler) (StreamHandler, error) {
+	// NOTE the event id of block is its height, so `Last-Event-ID` is used
+	// as cursor.
+	var cursor uint64
+	var hasCursor bool
+	if s := base.LastEventID(); len(s) > 0 {
+		var err error
+		if cursor, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return nil, InvalidEventID.New().SetData("id", s)
+		}
+		hasCursor = true
+	} else if s := base.Request().URL.Query().Get("cursor"); len(s) > 0 {
+		var err error
+		if cursor, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return nil, BadRequestParameter
+		}
+		hasCursor = true
+	}
+
+	return &BlocksStreamHandler{
+		BaseStreamHandler: base,
+		H:                 g.H,
+		cursor:            cursor,
+		hasCursor:         hasCursor,
+		catchupEnd:        math.MaxUint64,
+	}, nil
+}
+
+// Init subscribes the new block event before catching up, so the blocks saved
+// during catching up are not missed; the blocks, which are sent by catching
+// up, are ignored in Stream.
+func (g *BlocksStreamHandler) Init() <-chan interface{} {
+	g.stream, g.closeFunc = subscribeStream(element.EventNewBlock, func(v interface{}) interface{} {
+		block, ok := v.(element.Block)
+		if !ok || block.Header.Height <= atomic.LoadUint64(&g.catchupEnd) {
+			return nil
+		}
+
+		return newBlockStreamEvent(block)
+	})
+
+	ch := make(chan interface{})
+	if !g.hasCursor {
+		atomic.StoreUint64(&g.catchupEnd, 0)
+
+		go func() {
+			defer close(ch)
+			ch <- true
+		}()
+
+		return ch
+	}
+
+	var end uint64
+	if last, err := g.H.potion.LastBlock(); err == nil {
+		end = last.Header.Height
+	}
+	atomic.StoreUint64(&g.catchupEnd, end)
+
+	go func() {
+		defer close(ch)
+
+		if g.cursor < end {
+			iterFunc, closeFunc := g.H.potion.BlocksByHeight(g.cursor+1, end+1)
+			for {
+				block, next, _ := iterFunc()
+				if !next {
+					break
+				}
+
+				ch <- newBlockStreamEvent(block)
+			}
+			closeFunc()
+		}
+
+		ch <- true
+	}()
+
+	return ch
+}
+
+func (g *BlocksStreamHandler) Stream() (<-chan interface{}, func()) {
+	return g.stream, g.closeFunc
+}
+
+func newBlockStreamEvent(block element.Block) StreamEvent {
+	return StreamEvent{
+		ID:   strconv.FormatUint(block.Header.Height, 10),
+		Item: resourcev1.NewBlock(&block),
+	}
+}
@@ -0,0 +1,122 @@
+package restv1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	leveldbitem "github.com/spikeekips/naru/element/leveldb"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type testBlocks struct {
+	suite.Suite
+	s       *leveldbstorage.Storage
+	h       *Handler
+	started time.Time
+}
+
+func (t *testBlocks) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+	t.h = &Handler{potion: leveldbitem.NewPotion(s)}
+	t.started = time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	for height := uint64(1); height <= 10; height++ {
+		proposer := "even"
+		if height%2 == 1 {
+			proposer = "odd"
+		}
+
+		block := element.Block{
+			Header:    element.BlockHeader{Height: height},
+			Hash:      fmt.Sprintf("block%d", height),
+			Proposer:  proposer,
+			Confirmed: t.started.Add(time.Second * time.Duration(height)),
+		}
+		t.NoError(s.Insert(element.GetBlockKey(block.Hash), block))
+		t.NoError(s.Insert(leveldbitem.GetBlockHeightKey(height), block.Hash))
+	}
+}
+
+func (t *testBlocks) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testBlocks) get(query string) (heights []uint64, links map[string]string) {
+	w := httptest.NewRecorder()
+	t.h.GetBlocks(w, httptest.NewRequest("GET", "/api/v1/blocks?"+query, nil))
+	t.Equal(200, w.Code, w.Body.String())
+
+	var body struct {
+		Links map[string]struct {
+			Href string `json:"href"`
+		} `json:"_links"`
+		Embedded struct {
+			Records []struct {
+				Height uint64 `json:"height"`
+			} `json:"records"`
+		} `json:"_embedded"`
+	}
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &body))
+
+	links = map[string]string{}
+	for k, l := range body.Links {
+		links[k] = l.Href
+	}
+	for _, r := range body.Embedded.Records {
+		heights = append(heights, r.Height)
+	}
+
+	return
+}
+
+func (t *testBlocks) TestPage() {
+	defer func(s uint64) { GetBlocksScanSize = s }(GetBlocksScanSize)
+	GetBlocksScanSize = 3
+
+	heights, links := t.get("limit=4")
+	t.Equal([]uint64{1, 2, 3, 4}, heights)
+	t.Contains(links["next"], "cursor=4")
+	t.Contains(links["next"], "reverse=false")
+
+	heights, _ = t.get("limit=4&cursor=8")
+	t.Equal([]uint64{9, 10}, heights)
+
+	heights, links = t.get("limit=4&reverse=true")
+	t.Equal([]uint64{10, 9, 8, 7}, heights)
+	t.Contains(links["next"], "cursor=7")
+	t.Contains(links["next"], "reverse=true")
+
+	heights, _ = t.get("limit=4&reverse=true&cursor=3")
+	t.Equal([]uint64{2, 1}, heights)
+}
+
+func (t *testBlocks) TestFilter() {
+	heights, _ := t.get("proposer=even&limit=3")
+	t.Equal([]uint64{2, 4, 6}, heights)
+
+	from := t.started.Add(time.Second * 3).Format(time.RFC3339Nano)
+	to := t.started.Add(time.Second * 6).Format(time.RFC3339Nano)
+
+	heights, _ = t.get(fmt.Sprintf("from=%s&to=%s", from, to))
+	t.Equal([]uint64{3, 4, 5, 6}, heights)
+
+	heights, _ = t.get(fmt.Sprintf("from=%s&to=%s&reverse=true&proposer=odd", from, to))
+	t.Equal([]uint64{5, 3}, heights)
+}
+
+func TestBlocks(t *testing.T) {
+	suite.Run(t, new(testBlocks))
+}
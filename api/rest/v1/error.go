@@ -9,6 +9,12 @@ const (
 	TransactionNotFoundCode
 	BadRequestParameterCode
 	PageQueryLimitMaxExceedCode
+	InvalidEventIDCode
+	InvalidSubscriptionCode
+	TooManySubscriptionsCode
+	SubscriptionNotFoundCode
+	StreamTooSlowCode
+	UnauthorizedCode
 )
 
 var (
@@ -16,4 +22,10 @@ var (
 	TransactionNotFound     = common.NewError(TransactionNotFoundCode, "transaction not found")
 	BadRequestParameter     = common.NewError(BadRequestParameterCode, "found invalid request")
 	PageQueryLimitMaxExceed = common.NewError(PageQueryLimitMaxExceedCode, "limit exceeded in page")
+	InvalidEventID          = common.NewError(InvalidEventIDCode, "invalid event id")
+	InvalidSubscription     = common.NewError(InvalidSubscriptionCode, "invalid subscription")
+	TooManySubscriptions    = common.NewError(TooManySubscriptionsCode, "too many subscriptions")
+	SubscriptionNotFound    = common.NewError(SubscriptionNotFoundCode, "subscription not found")
+	StreamTooSlow           = common.NewError(StreamTooSlowCode, "stream is too slow to follow the events")
+	Unauthorized            = common.NewError(UnauthorizedCode, "unauthorized")
 )
@@ -0,0 +1,81 @@
+package restv1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spikeekips/naru/element"
+)
+
+// EventID is the id of streamed operation; it is ordered by block height,
+// position of transaction in block and operation index in transaction. The
+// proposer transaction is placed before the other transactions of block.
+type EventID struct {
+	Height  uint64
+	TxIndex uint64
+	OpIndex uint64
+}
+
+func ParseEventID(s string) (EventID, error) {
+	var ns []uint64
+	for _, i := range strings.Split(s, "-") {
+		n, err := strconv.ParseUint(i, 10, 64)
+		if err != nil {
+			return EventID{}, InvalidEventID.New().SetData("id", s)
+		}
+		ns = append(ns, n)
+	}
+
+	if len(ns) != 3 {
+		return EventID{}, InvalidEventID.New().SetData("id", s)
+	}
+
+	return EventID{Height: ns[0], TxIndex: ns[1], OpIndex: ns[2]}, nil
+}
+
+func (e EventID) String() string {
+	return fmt.Sprintf("%d-%d-%d", e.Height, e.TxIndex, e.OpIndex)
+}
+
+func (e EventID) After(b EventID) bool {
+	if e.Height != b.Height {
+		return e.Height > b.Height
+	}
+	if e.TxIndex != b.TxIndex {
+		return e.TxIndex > b.TxIndex
+	}
+
+	return e.OpIndex > b.OpIndex
+}
+
+// operationEventIDs makes EventID of operations; the transaction positions of
+// the last block are kept, because the operations of same block come together.
+type operationEventIDs struct {
+	potion element.Potion
+	height uint64
+	txs    map[string]uint64
+}
+
+func newOperationEventIDs(potion element.Potion) *operationEventIDs {
+	return &operationEventIDs{potion: potion}
+}
+
+func (o *operationEventIDs) ID(op element.Operation) (EventID, error) {
+	if o.txs == nil || o.height != op.Block {
+		block, err := o.potion.BlockByHeight(op.Block)
+		if err != nil {
+			return EventID{}, err
+		}
+
+		txs := map[string]uint64{block.ProposerTransaction: 0}
+		for i, hash := range block.Transactions {
+			txs[hash] = uint64(i + 1)
+		}
+
+		o.height = op.Block
+		o.txs = txs
+	}
+
+	return EventID{Height: op.Block, TxIndex: o.txs[op.TxHash], OpIndex: op.OpIndex}, nil
+}
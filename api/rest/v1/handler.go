@@ -1,7 +1,11 @@
 package restv1
 
 import (
+	"net/http"
+	"strconv"
+
 	sebaknode "boscoin.io/sebak/lib/node"
+	sebakresource "boscoin.io/sebak/lib/node/runner/api/resource"
 
 	"github.com/spikeekips/naru/cache"
 	"github.com/spikeekips/naru/element"
@@ -18,3 +22,24 @@ type Handler struct {
 func NewHandler(sst *sebak.Storage, potion element.Potion, cch *cache.Cache, sebakInfo sebaknode.NodeInfo) *Handler {
 	return &Handler{sst: sst, potion: potion, cch: cch, sebakInfo: sebakInfo}
 }
+
+// newPageResourceList makes the resource list of the page query; `next` link
+// continues after the last cursor and `prev` link goes back from the first
+// cursor. If the page is empty, the links are omitted.
+func newPageResourceList(r *http.Request, rs []sebakresource.Resource, reverse bool, first, last string) *sebakresource.ResourceList {
+	var next, prev string
+	if len(rs) > 0 {
+		next = pageLink(r, last, reverse)
+		prev = pageLink(r, first, !reverse)
+	}
+
+	return sebakresource.NewResourceList(rs, r.URL.String(), next, prev)
+}
+
+func pageLink(r *http.Request, cursor string, reverse bool) string {
+	q := r.URL.Query()
+	q.Set("cursor", cursor)
+	q.Set("reverse", strconv.FormatBool(reverse))
+
+	return r.URL.Path + "?" + q.Encode()
+}
@@ -0,0 +1,55 @@
+package restv1
+
+import (
+	"net/http"
+
+	"github.com/spikeekips/naru/api/rest"
+)
+
+type readinessCheck struct {
+	name  string
+	check func() error
+}
+
+// AddReadinessCheck adds the condition of `/readyz`; when one of the checks
+// returns error, `/readyz` responds with 503. The checks should be added
+// before Start.
+func (s *Server) AddReadinessCheck(name string, check func() error) {
+	s.readinessChecks = append(s.readinessChecks, readinessCheck{name: name, check: check})
+}
+
+// Healthz responds 200 while the server is serving.
+func (s *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, http.StatusOK, map[string]interface{}{"status": "ok"})
+}
+
+// Readyz runs the all readiness checks and responds with the result of each
+// check.
+func (s *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	checks := map[string]string{}
+	for _, c := range s.readinessChecks {
+		if err := c.check(); err != nil {
+			s.log.Warn("readiness check failed", "check", c.name, "error", err)
+			checks[c.name] = err.Error()
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		checks[c.name] = "ok"
+	}
+
+	result := "ok"
+	if status != http.StatusOK {
+		result = "not-ready"
+	}
+
+	writeHealth(w, r, status, map[string]interface{}{"status": result, "checks": checks})
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, status int, body interface{}) {
+	jw := rest.NewJSONWriter(w, r)
+	jw.Header().Set("Content-Type", "application/json")
+	jw.Header().Set("Cache-Control", "no-cache")
+	jw.WriteHeader(status)
+	jw.WriteObject(body)
+}
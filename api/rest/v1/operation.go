@@ -1,18 +1,36 @@
 package restv1
 
 import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
 	sebakapi "boscoin.io/sebak/lib/node/runner/api"
+	sebakresource "boscoin.io/sebak/lib/node/runner/api/resource"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
 	"github.com/gorilla/mux"
 
+	"github.com/spikeekips/naru/api/rest"
+	resourcev1 "github.com/spikeekips/naru/api/rest/v1/resource"
 	"github.com/spikeekips/naru/element"
 	"github.com/spikeekips/naru/storage"
 )
 
+// OperationsByAccountStreamHandler streams the operations of account. If
+// `Last-Event-ID` is given, the operations after the event are sent first
+// instead of the page query.
 type OperationsByAccountStreamHandler struct {
 	BaseStreamHandler
-	H       *Handler
-	address string
-	query   *sebakapi.PageQuery
+	H          *Handler
+	address    string
+	query      *sebakapi.PageQuery
+	lastEvent  *EventID
+	catchupEnd uint64
+	stream     <-chan interface{}
+	closeFunc  func()
 }
 
 func (g OperationsByAccountStreamHandler) NewRequest(base BaseStreamHandler) (StreamHandler, error) {
@@ -28,10 +46,46 @@ func (g OperationsByAccountStreamHandler) NewRequest(base BaseStreamHandler) (St
 		return nil, err
 	}
 
-	return &OperationsByAccountStreamHandler{BaseStreamHandler: base, H: g.H, address: address, query: query}, nil
+	var lastEvent *EventID
+	if s := base.LastEventID(); len(s) > 0 {
+		e, err := ParseEventID(s)
+		if err != nil {
+			return nil, err
+		}
+		lastEvent = &e
+	}
+
+	return &OperationsByAccountStreamHandler{
+		BaseStreamHandler: base,
+		H:                 g.H,
+		address:           address,
+		query:             query,
+		lastEvent:         lastEvent,
+	}, nil
 }
 
+// Init subscribes the new operations before reading the stored operations.
+// When resuming, the operations until the last block are sent by Init and the
+// newer operations are sent by Stream, so the operations saved during resuming
+// are not missed nor duplicated.
 func (g *OperationsByAccountStreamHandler) Init() <-chan interface{} {
+	ids := newOperationEventIDs(g.H.potion)
+	g.stream, g.closeFunc = subscribeStream(
+		element.GetOperationAccountRelatedEventKey(g.address),
+		func(v interface{}) interface{} {
+			op, ok := v.(element.Operation)
+			if !ok || op.Block <= atomic.LoadUint64(&g.catchupEnd) {
+				return nil
+			}
+
+			return newOperationStreamEvent(ids, op)
+		},
+	)
+
+	if g.lastEvent != nil {
+		return g.resume()
+	}
+
 	lo := g.query.ListOptions()
 	iterFunc, closeFunc := g.H.potion.OperationsByAccount(
 		g.address,
@@ -44,13 +98,66 @@ func (g *OperationsByAccountStreamHandler) Init() <-chan interface{} {
 		defer closeFunc()
 		defer close(ch)
 
+		ids := newOperationEventIDs(g.H.potion)
 		for {
 			op, next, _ := iterFunc()
 			if !next {
 				break
 			}
 
-			ch <- op
+			ch <- newOperationStreamEvent(ids, op)
+		}
+		ch <- true
+	}()
+
+	return ch
+}
+
+// resume sends the operations after the last event until the last block; the
+// operations are selected by the block height, so the former operations of
+// account are not scanned.
+func (g *OperationsByAccountStreamHandler) resume() <-chan interface{} {
+	ch := make(chan interface{})
+
+	last, err := g.H.potion.LastBlock()
+	if err != nil {
+		go func() {
+			defer close(ch)
+			ch <- err
+		}()
+
+		return ch
+	}
+	end := last.Header.Height
+	atomic.StoreUint64(&g.catchupEnd, end)
+
+	iterFunc, closeFunc := g.H.potion.OperationsByAccountFilter(
+		g.address,
+		element.OperationFilter{FromHeight: g.lastEvent.Height, ToHeight: end},
+		storage.NewDefaultListOptions(false, nil, 0),
+	)
+
+	go func() {
+		defer closeFunc()
+		defer close(ch)
+
+		ids := newOperationEventIDs(g.H.potion)
+		for {
+			op, next, _ := iterFunc()
+			if !next {
+				break
+			}
+
+			id, err := ids.ID(op)
+			if err != nil {
+				ch <- err
+				return
+			}
+			if !id.After(*g.lastEvent) {
+				continue
+			}
+
+			ch <- StreamEvent{ID: id.String(), Item: op}
 		}
 		ch <- true
 	}()
@@ -59,19 +166,133 @@ func (g *OperationsByAccountStreamHandler) Init() <-chan interface{} {
 }
 
 func (g *OperationsByAccountStreamHandler) Stream() (<-chan interface{}, func()) {
-	event := element.GetOperationAccountRelatedEventKey(g.address)
+	return g.stream, g.closeFunc
+}
 
-	ch := make(chan interface{})
-	callback := func(items ...interface{}) {
-		for _, v := range items {
-			ch <- v
+func newOperationStreamEvent(ids *operationEventIDs, op element.Operation) interface{} {
+	id, err := ids.ID(op)
+	if err != nil {
+		log.Error("failed to make event id", "operation", op.Hash, "error", err)
+		return op
+	}
+
+	return StreamEvent{ID: id.String(), Item: op}
+}
+
+// GetAccountOperations lists the operations of account with the page query;
+// it is the plain version of OperationsByAccountStreamHandler.
+func (h *Handler) GetAccountOperations(w http.ResponseWriter, r *http.Request) {
+	jw := rest.NewJSONWriter(w, r)
+
+	query, err := sebakapi.NewPageQuery(r)
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	address := mux.Vars(r)["id"]
+	if _, err := h.potion.Account(address); err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	filter, found, err := newOperationFilter(h.potion, r)
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	lo := query.ListOptions()
+	if !found {
+		jw.WriteObject(newPageResourceList(r, nil, lo.Reverse(), "", ""))
+		return
+	}
+
+	options := storage.NewDefaultListOptions(lo.Reverse(), lo.Cursor(), lo.Limit())
+
+	var iterFunc func() (element.Operation, bool, []byte)
+	var closeFunc func()
+	if filter.IsEmpty() {
+		iterFunc, closeFunc = h.potion.OperationsByAccount(address, options)
+	} else {
+		iterFunc, closeFunc = h.potion.OperationsByAccountFilter(address, filter, options)
+	}
+	defer closeFunc()
+
+	jw.WriteObject(operationsResourceList(r, lo.Reverse(), iterFunc))
+}
+
+// newOperationFilter parses the filter of operations from the query string:
+// `type` can be repeated or separated by comma, `min_amount` and `max_amount`
+// are the amount range, `counterparty` is the other side of operation and
+// `from_height`, `to_height`, `from` and `to` limit the blocks; `from` and
+// `to` are the confirmed time of block in RFC3339 format. If no block is in
+// the time range, `found` is false.
+func newOperationFilter(potion element.Potion, r *http.Request) (filter element.OperationFilter, found bool, err error) {
+	q := r.URL.Query()
+
+	for _, v := range q["type"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); len(t) < 1 {
+				continue
+			}
+			filter.Types = append(filter.Types, sebakoperation.OperationType(t))
+		}
+	}
+
+	for k, a := range map[string]*sebakcommon.Amount{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount} {
+		s := q.Get(k)
+		if len(s) < 1 {
+			continue
+		}
+		if *a, err = sebakcommon.AmountFromString(s); err != nil {
+			return element.OperationFilter{}, false, BadRequestParameter.New().SetData(k, s)
+		}
+	}
+
+	filter.Counterparty = q.Get("counterparty")
+
+	for k, h := range map[string]*uint64{"from_height": &filter.FromHeight, "to_height": &filter.ToHeight} {
+		s := q.Get(k)
+		if len(s) < 1 {
+			continue
+		}
+		if *h, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return element.OperationFilter{}, false, BadRequestParameter.New().SetData(k, s)
+		}
+	}
+
+	var from, to time.Time
+	for k, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		s := q.Get(k)
+		if len(s) < 1 {
+			continue
+		}
+		if *t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return element.OperationFilter{}, false, BadRequestParameter.New().SetData(k, s)
 		}
 	}
 
-	storage.Observer.On(event, callback)
+	return filter.WithTime(potion, from, to)
+}
+
+// operationsResourceList reads all the operations of iterator; the iterator is
+// already limited by the page query.
+func operationsResourceList(r *http.Request, reverse bool, iterFunc func() (element.Operation, bool, []byte)) *sebakresource.ResourceList {
+	var rs []sebakresource.Resource
+	var first, last string
+	for {
+		op, next, cursor := iterFunc()
+		if !next {
+			break
+		}
 
-	return ch, func() {
-		storage.Observer.Off(event, callback)
-		close(ch)
+		if len(rs) < 1 {
+			first = string(cursor)
+		}
+		last = string(cursor)
+		rs = append(rs, resourcev1.NewOperation(op))
 	}
+
+	return newPageResourceList(r, rs, reverse, first, last)
 }
@@ -0,0 +1,184 @@
+package restv1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	leveldbitem "github.com/spikeekips/naru/element/leveldb"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type testTransactionOperations struct {
+	suite.Suite
+	s *leveldbstorage.Storage
+	h *Handler
+}
+
+func (t *testTransactionOperations) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+	t.h = &Handler{potion: leveldbitem.NewPotion(s)}
+
+	for _, txHash := range []string{"tx0", "tx1"} {
+		t.NoError(s.Insert(element.GetTransactionKey(txHash), element.Transaction{Hash: txHash}))
+		for i := uint64(0); i < 5; i++ {
+			op := element.Operation{Hash: element.GetOperationHash(txHash, i), TxHash: txHash, OpIndex: i}
+			t.NoError(s.Insert(element.GetOperationKey(op.Hash), op))
+		}
+	}
+}
+
+func (t *testTransactionOperations) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testTransactionOperations) get(hash, query string) (hashes []string, next string) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/v1/transactions/"+hash+"/operations?"+query, nil)
+	r = mux.SetURLVars(r, map[string]string{"id": hash})
+
+	t.h.GetTransactionOperations(w, r)
+	t.Equal(200, w.Code, w.Body.String())
+
+	var body struct {
+		Links struct {
+			Next struct {
+				Href string `json:"href"`
+			} `json:"next"`
+		} `json:"_links"`
+		Embedded struct {
+			Records []struct {
+				Hash string `json:"hash"`
+			} `json:"records"`
+		} `json:"_embedded"`
+	}
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &body))
+
+	for _, r := range body.Embedded.Records {
+		hashes = append(hashes, r.Hash)
+	}
+
+	return hashes, body.Links.Next.Href
+}
+
+func (t *testTransactionOperations) TestPage() {
+	hashes, next := t.get("tx1", "limit=3")
+	t.Equal([]string{
+		element.GetOperationHash("tx1", 0),
+		element.GetOperationHash("tx1", 1),
+		element.GetOperationHash("tx1", 2),
+	}, hashes)
+
+	u, err := url.Parse(next)
+	t.NoError(err)
+
+	hashes, _ = t.get("tx1", u.RawQuery)
+	t.Equal([]string{
+		element.GetOperationHash("tx1", 3),
+		element.GetOperationHash("tx1", 4),
+	}, hashes)
+}
+
+func (t *testTransactionOperations) TestNotFound() {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/v1/transactions/unknown/operations", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "unknown"})
+
+	t.h.GetTransactionOperations(w, r)
+	t.Equal(404, w.Code)
+}
+
+func TestTransactionOperations(t *testing.T) {
+	suite.Run(t, new(testTransactionOperations))
+}
+
+type testOperationsStreamResume struct {
+	suite.Suite
+	s *leveldbstorage.Storage
+	h *Handler
+}
+
+func (t *testOperationsStreamResume) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+	t.h = &Handler{potion: leveldbitem.NewPotion(s)}
+}
+
+func (t *testOperationsStreamResume) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testOperationsStreamResume) resume(lastEvent EventID) []interface{} {
+	g := &OperationsByAccountStreamHandler{H: t.h, address: "GABC", lastEvent: &lastEvent}
+
+	var items []interface{}
+	for v := range g.resume() {
+		items = append(items, v)
+	}
+
+	return items
+}
+
+func (t *testOperationsStreamResume) TestResume() {
+	for height := uint64(1); height <= 4; height++ {
+		txHash := fmt.Sprintf("tx%d", height)
+		block := element.Block{
+			Header:       element.BlockHeader{Height: height},
+			Hash:         fmt.Sprintf("block%d", height),
+			Transactions: []string{txHash},
+		}
+		t.NoError(t.s.Insert(element.GetBlockKey(block.Hash), block))
+		t.NoError(t.s.Insert(leveldbitem.GetBlockHeightKey(height), block.Hash))
+
+		for i := uint64(0); i < 2; i++ {
+			op := element.Operation{
+				Hash:    element.GetOperationHash(txHash, i),
+				TxHash:  txHash,
+				OpIndex: i,
+				Source:  "GABC",
+				Target:  "GDEF",
+				Block:   height,
+			}
+			t.NoError(t.s.Insert(element.GetOperationKey(op.Hash), op))
+			leveldbitem.OnAfterSaveOperation(t.s, op)
+		}
+	}
+
+	var ids []string
+	items := t.resume(EventID{Height: 2, TxIndex: 1, OpIndex: 0})
+	for _, v := range items[:len(items)-1] {
+		ids = append(ids, v.(StreamEvent).ID)
+	}
+	t.Equal([]string{"2-1-1", "3-1-0", "3-1-1", "4-1-0", "4-1-1"}, ids)
+	t.Equal(true, items[len(items)-1])
+}
+
+func (t *testOperationsStreamResume) TestLastBlockFailed() {
+	// NOTE without blocks, LastBlock fails
+	items := t.resume(EventID{Height: 2})
+	t.Equal(1, len(items))
+	_, ok := items[0].(error)
+	t.True(ok)
+}
+
+func TestOperationsStreamResume(t *testing.T) {
+	suite.Run(t, new(testOperationsStreamResume))
+}
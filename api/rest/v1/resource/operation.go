@@ -0,0 +1,50 @@
+package resourcev1
+
+import (
+	"strconv"
+	"strings"
+
+	sebakresource "boscoin.io/sebak/lib/node/runner/api/resource"
+	"github.com/nvellon/hal"
+
+	"github.com/spikeekips/naru/element"
+)
+
+type Operation struct {
+	op element.Operation
+}
+
+func NewOperation(op element.Operation) *Operation {
+	return &Operation{op: op}
+}
+
+func (o Operation) GetMap() hal.Entry {
+	e := hal.Entry{
+		"hash":         o.op.Hash,
+		"op_hash":      o.op.OpHash,
+		"source":       o.op.Source,
+		"target":       o.op.Target,
+		"type":         o.op.Type,
+		"tx_hash":      o.op.TxHash,
+		"block_height": o.op.Block,
+		"amount":       o.op.Amount.String(),
+	}
+
+	if op, err := o.op.Operation(); err == nil {
+		e["body"] = op.B
+	}
+
+	return e
+}
+
+func (o Operation) Resource() *hal.Resource {
+	r := hal.NewResource(o, o.LinkSelf())
+	r.AddLink("transaction", hal.NewLink(strings.Replace(sebakresource.URLTransactions, "{id}", o.op.TxHash, -1)))
+
+	return r
+}
+
+func (o Operation) LinkSelf() string {
+	return strings.Replace(sebakresource.URLTransactionOperations, "{id}", o.op.TxHash, -1) +
+		"/" + strconv.FormatUint(o.op.OpIndex, 10)
+}
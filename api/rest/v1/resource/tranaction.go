@@ -57,3 +57,38 @@ func (t Transaction) Resource() *hal.Resource {
 func (t Transaction) LinkSelf() string {
 	return strings.Replace(sebakresource.URLTransactions, "{id}", t.tx.Hash, -1)
 }
+
+// TransactionStatus is the status of transaction in the status stream; the
+// block height is set only when the transaction is confirmed.
+type TransactionStatus struct {
+	hash   string
+	status string
+	block  uint64
+}
+
+func NewTransactionStatus(hash, status string, block uint64) *TransactionStatus {
+	return &TransactionStatus{hash: hash, status: status, block: block}
+}
+
+func (t TransactionStatus) GetMap() hal.Entry {
+	e := hal.Entry{
+		"hash":   t.hash,
+		"status": t.status,
+	}
+	if t.block > 0 {
+		e["block"] = t.block
+	}
+
+	return e
+}
+
+func (t TransactionStatus) Resource() *hal.Resource {
+	r := hal.NewResource(t, t.LinkSelf())
+	r.AddLink("transaction", hal.NewLink(strings.Replace(sebakresource.URLTransactions, "{id}", t.hash, -1)))
+
+	return r
+}
+
+func (t TransactionStatus) LinkSelf() string {
+	return strings.Replace(sebakresource.URLTransactions, "{id}", t.hash, -1) + "/status"
+}
@@ -0,0 +1,151 @@
+package restv1
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	sebakapi "boscoin.io/sebak/lib/node/runner/api"
+	sebakresource "boscoin.io/sebak/lib/node/runner/api/resource"
+	"github.com/gorilla/mux"
+	"github.com/nvellon/hal"
+
+	"github.com/spikeekips/naru/api/rest"
+	resourcev1 "github.com/spikeekips/naru/api/rest/v1/resource"
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/query"
+	"github.com/spikeekips/naru/storage"
+)
+
+// SearchMaxBodySize limits the size of query in the search request.
+var SearchMaxBodySize int64 = 1024 * 100
+
+// Search finds the elements of collection, "blocks", "transactions",
+// "accounts" or "operations", by the JSON form of `query.Query` in the request
+// body; the empty body matches with all. The page query and `sort` are given
+// by the query string, and the cursor is the hash or the address of element.
+// Search is POST only, so the page has `next_cursor` and `prev_cursor` instead
+// of the `next` and `prev` links; to get the next page, the client should post
+// the same query again with `cursor=<next_cursor>`, and for the previous page,
+// `cursor=<prev_cursor>` with the opposite `reverse`.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	jw := rest.NewJSONWriter(w, r)
+
+	pq, err := sebakapi.NewPageQuery(r)
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, SearchMaxBodySize))
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	var q query.Query
+	if len(body) > 0 {
+		if q, err = query.UnmarshalQuery(body); err != nil {
+			jw.WriteObject(
+				BadRequestParameter.New().
+					SetData("error", err.Error()).
+					SetData("status", http.StatusBadRequest),
+			)
+			return
+		}
+	}
+
+	lo := pq.ListOptions()
+	iterFunc, closeFunc, err := h.potion.Find(
+		mux.Vars(r)["collection"],
+		q,
+		r.URL.Query().Get("sort"),
+		storage.NewDefaultListOptions(lo.Reverse(), lo.Cursor(), lo.Limit()),
+	)
+	if err != nil {
+		jw.WriteObject(searchError(err))
+		return
+	}
+	defer closeFunc()
+
+	var rs []sebakresource.Resource
+	var first, last string
+	for {
+		v, next, cursor := iterFunc()
+		if !next {
+			break
+		}
+
+		var resource sebakresource.Resource
+		switch t := v.(type) {
+		case element.Block:
+			resource = resourcev1.NewBlock(&t)
+		case element.Transaction:
+			resource = resourcev1.NewTransaction(t)
+		case element.Account:
+			resource = sebakresource.NewAccount(t.BlockAccount())
+		case element.Operation:
+			resource = resourcev1.NewOperation(t)
+		default:
+			continue
+		}
+
+		if len(rs) < 1 {
+			first = string(cursor)
+		}
+		last = string(cursor)
+		rs = append(rs, resource)
+	}
+
+	jw.WriteObject(searchResourceList{
+		ResourceList: sebakresource.NewResourceList(rs, r.URL.String(), "", ""),
+		next:         last,
+		prev:         first,
+	})
+}
+
+type searchResourceList struct {
+	*sebakresource.ResourceList
+	next string
+	prev string
+}
+
+func (l searchResourceList) Resource() *hal.Resource {
+	r := l.ResourceList.Resource()
+	delete(r.Links, "next")
+	delete(r.Links, "prev")
+	r.Payload = l
+
+	return r
+}
+
+func (l searchResourceList) GetMap() hal.Entry {
+	return hal.Entry{
+		"next_cursor": l.next,
+		"prev_cursor": l.prev,
+	}
+}
+
+func searchError(err error) error {
+	e, ok := err.(*common.Error)
+	if !ok {
+		return err
+	}
+
+	switch {
+	case e.Equal(element.UnknownSearchCollection):
+		e.SetData("status", http.StatusNotFound)
+	case e.Equal(element.InvalidSearchQuery),
+		e.Equal(element.TooManySearchRecords),
+		e.Equal(query.InvaludValue),
+		e.Equal(query.NotSupportedOperator),
+		e.Equal(query.NotSupportedConjunction),
+		e.Equal(query.InvalidQueryType):
+		e.SetData("status", http.StatusBadRequest)
+	}
+
+	return e
+}
@@ -0,0 +1,142 @@
+package restv1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	leveldbitem "github.com/spikeekips/naru/element/leveldb"
+	"github.com/spikeekips/naru/query"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type testSearch struct {
+	suite.Suite
+	s *leveldbstorage.Storage
+	h *Handler
+}
+
+func (t *testSearch) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+	t.h = &Handler{potion: leveldbitem.NewPotion(s)}
+
+	for height := uint64(1); height <= 10; height++ {
+		block := element.Block{
+			Header: element.BlockHeader{Height: height},
+			Hash:   fmt.Sprintf("block%02d", height),
+		}
+		t.NoError(s.Insert(element.GetBlockKey(block.Hash), block))
+	}
+}
+
+func (t *testSearch) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testSearch) search(collection, rawQuery string, q query.Query) (code int, heights []uint64, next string) {
+	var body string
+	if q != nil {
+		b, err := json.Marshal(q)
+		t.NoError(err)
+		body = string(b)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/api/v1/search/"+collection+"?"+rawQuery, strings.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"collection": collection})
+
+	t.h.Search(w, r)
+	if w.Code != 200 {
+		return w.Code, nil, ""
+	}
+
+	var result struct {
+		Links      map[string]interface{} `json:"_links"`
+		NextCursor string                 `json:"next_cursor"`
+		Embedded   struct {
+			Records []struct {
+				Height uint64 `json:"height"`
+			} `json:"records"`
+		} `json:"_embedded"`
+	}
+	t.NoError(json.Unmarshal(w.Body.Bytes(), &result))
+
+	for _, r := range result.Embedded.Records {
+		heights = append(heights, r.Height)
+	}
+
+	// NOTE Search is POST only, so the page links are not given
+	t.NotContains(result.Links, "next")
+	t.NotContains(result.Links, "prev")
+
+	return w.Code, heights, result.NextCursor
+}
+
+func (t *testSearch) TestPage() {
+	a, _ := query.NewTerm("height", 3)
+	b, _ := query.NewTerm("height", 8)
+	q := query.NewTermQuery(query.GTE, a).Conjunct(query.AND, query.NewTermQuery(query.LT, b))
+
+	code, heights, next := t.search("blocks", "limit=3", q)
+	t.Equal(200, code)
+	t.Equal([]uint64{3, 4, 5}, heights)
+
+	t.Equal("block05", next)
+
+	_, heights, _ = t.search("blocks", "limit=3&cursor="+next, q)
+	t.Equal([]uint64{6, 7}, heights)
+
+	_, heights, _ = t.search("blocks", "limit=2&reverse=true", q)
+	t.Equal([]uint64{7, 6}, heights)
+}
+
+func (t *testSearch) TestAll() {
+	code, heights, _ := t.search("blocks", "sort=hash&reverse=true&limit=2", nil)
+	t.Equal(200, code)
+	t.Equal([]uint64{10, 9}, heights)
+}
+
+func (t *testSearch) TestInvalid() {
+	tm, _ := query.NewTerm("proposer", "GABC")
+	q := query.NewTermQuery(query.IS, tm)
+
+	code, _, _ := t.search("blocks", "", q)
+	t.Equal(400, code)
+
+	code, _, _ = t.search("blocks", "sort=proposer", nil)
+	t.Equal(400, code)
+
+	code, _, _ = t.search("webhooks", "", nil)
+	t.Equal(404, code)
+}
+
+func (t *testSearch) TestTooManyRecords() {
+	defer func(max int) { leveldbitem.FindMaxRecords = max }(leveldbitem.FindMaxRecords)
+	leveldbitem.FindMaxRecords = 5
+
+	code, _, _ := t.search("blocks", "limit=2", nil)
+	t.Equal(400, code)
+
+	// NOTE the narrow query is still allowed
+	tm, _ := query.NewTerm("height", 8)
+	code, heights, _ := t.search("blocks", "", query.NewTermQuery(query.GTE, tm))
+	t.Equal(200, code)
+	t.Equal([]uint64{8, 9, 10}, heights)
+}
+
+func TestSearch(t *testing.T) {
+	suite.Run(t, new(testSearch))
+}
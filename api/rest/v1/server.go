@@ -1,8 +1,10 @@
 package restv1
 
 import (
+	"context"
 	goLog "log"
 	"net/http"
+	"sync"
 	"time"
 
 	sebakcommon "boscoin.io/sebak/lib/common"
@@ -28,6 +30,14 @@ type Server struct {
 	core      *http.Server
 	log       logging.Logger
 	router    *mux.Router
+
+	readinessChecks []readinessCheck
+	stopFuncs       []func() error
+	shutdownTimeout time.Duration
+	webSocket       *config.WebSocketConfig
+	shutdown        chan struct{}
+	stopOnce        sync.Once
+	stopErr         error
 }
 
 func NewServer(nc *config.Network, sst *sebak.Storage, potion element.Potion, cb cachebackend.Backend, sebakInfo sebaknode.NodeInfo) *Server {
@@ -63,6 +73,10 @@ func NewServer(nc *config.Network, sst *sebak.Storage, potion element.Potion, cb
 		core:      core,
 		log:       httpLog,
 		router:    mux.NewRouter(),
+
+		shutdownTimeout: nc.ShutdownTimeout,
+		webSocket:       nc.WebSocket,
+		shutdown:        make(chan struct{}),
 	}
 
 	// TODO ratelimit
@@ -87,6 +101,8 @@ func (s *Server) addDefaultHandlers() {
 	restHandler := NewHandler(s.sst, s.potion, s.cch, s.sebakInfo)
 
 	s.AddHandleFunc("/", restHandler.Index)
+	s.AddHandleFunc("/healthz", s.Healthz).Methods("GET")
+	s.AddHandleFunc("/readyz", s.Readyz).Methods("GET")
 	s.AddHandleFunc("/api/v1/accounts", restHandler.GetAccounts).
 		Methods("POST").
 		Headers("Content-Type", "application/json")
@@ -97,6 +113,12 @@ func (s *Server) addDefaultHandlers() {
 			Status(0, http.StatusOK).                   // for 1 year
 			Status(-1).                                 // no-cache for other status
 			SetCacheKey(func(r *http.Request) string {
+				// NOTE the account at height is not cached; the height can be
+				// above the last block and the state can be changed by
+				// rollback.
+				if len(r.URL.Query().Get("height")) > 0 {
+					return ""
+				}
 				return r.URL.Path
 			}).
 			Handler(),
@@ -117,6 +139,17 @@ func (s *Server) addDefaultHandlers() {
 	s.AddHandleFunc("/api/v1/transactions", restHandler.PostTransaction).
 		Methods("POST").
 		Headers("Content-Type", "application/json")
+	// NOTE the stream route should be added before the route of status; the
+	// same path is matched by the order.
+	s.AddHandleFunc(
+		"/api/v1/transactions/{id}/status",
+		s.NewStreamer(
+			TransactionStatusStreamHandler{H: restHandler, Expire: DefaultTransactionStatusExpire},
+			DefaultTransactionStatusExpire+time.Second*10,
+		).Handler,
+	).
+		Headers("Accept", "text/event-stream").
+		Methods("GET")
 	s.AddHandleFunc(
 		"/api/v1/transactions/{id}/status",
 		NewCacheHandler(s.cch, time.Second*3, restHandler.GetTransactionStatus).
@@ -141,12 +174,32 @@ func (s *Server) addDefaultHandlers() {
 			}).
 			Handler(),
 	).Methods("Get")
+	s.AddHandleFunc(
+		"/api/v1/blocks",
+		s.NewStreamer(BlocksStreamHandler{H: restHandler}, time.Minute*10).Handler,
+	).
+		Headers("Accept", "text/event-stream").
+		Methods("GET")
+	s.AddHandleFunc("/api/v1/blocks", restHandler.GetBlocks).
+		Methods("GET")
+	s.AddHandler("/api/v1/websocket", s.NewWebSocketHandler(restHandler).Handler()).
+		Methods("GET")
 	s.AddHandleFunc(
 		"/api/v1/accounts/{id}/operations",
-		NewStreamer(OperationsByAccountStreamHandler{H: restHandler}, time.Second*10).Handler,
+		s.NewStreamer(OperationsByAccountStreamHandler{H: restHandler}, time.Second*10).Handler,
 	).
 		Headers("Accept", "text/event-stream").
 		Methods("GET")
+	s.AddHandleFunc("/api/v1/accounts/{id}/operations", restHandler.GetAccountOperations).
+		Methods("GET")
+	s.AddHandleFunc("/api/v1/accounts/{id}/transactions", restHandler.GetAccountTransactions).
+		Methods("GET")
+	s.AddHandleFunc("/api/v1/transactions/{id}/operations", restHandler.GetTransactionOperations).
+		Methods("GET")
+	s.AddHandleFunc("/api/v1/blocks/{id}/transactions", restHandler.GetBlockTransactions).
+		Methods("GET")
+	s.AddHandleFunc("/api/v1/search/{collection}", restHandler.Search).
+		Methods("POST")
 }
 
 func (s *Server) Start() error {
@@ -175,6 +228,62 @@ func (s *Server) Start() error {
 	return err
 }
 
+// AddStopFunc adds the function, which is called after the server is stopped;
+// the background jobs like digest watcher are stopped together.
+func (s *Server) AddStopFunc(f func() error) {
+	s.stopFuncs = append(s.stopFuncs, f)
+}
+
+// NewStreamer makes Streamer, which is closed with the final event when the
+// server is shutting down.
+func (s *Server) NewStreamer(newHandler NewStreamHandler, timeout time.Duration) Streamer {
+	streamer := NewStreamer(newHandler, timeout)
+	streamer.shutdown = s.shutdown
+
+	return streamer
+}
+
+// Stop stops the server gracefully. Stop stops accepting new connections,
+// closes the open streams and waits the in-flight requests until
+// `ShutdownTimeout`; after that, the remaining connections are closed. And then
+// the functions of AddStopFunc are called in order. Stop can be called
+// multiple times; only the first call stops the server.
 func (s *Server) Stop() error {
-	return s.core.Close()
+	s.stopOnce.Do(func() {
+		s.stopErr = s.stop()
+	})
+
+	return s.stopErr
+}
+
+func (s *Server) stop() error {
+	s.log.Debug("shutting down server", "timeout", s.shutdownTimeout)
+
+	close(s.shutdown)
+
+	ctx := context.Background()
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.shutdownTimeout)
+		defer cancel()
+	}
+
+	err := s.core.Shutdown(ctx)
+	if err != nil {
+		s.log.Warn("failed to shutdown gracefully; connections will be closed", "error", err)
+		s.core.Close()
+	}
+
+	for _, f := range s.stopFuncs {
+		if e := f(); e != nil {
+			s.log.Error("failed to stop", "error", e)
+			if err == nil {
+				err = e
+			}
+		}
+	}
+
+	s.log.Debug("server stopped")
+
+	return err
 }
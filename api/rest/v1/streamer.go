@@ -3,9 +3,13 @@ package restv1
 import (
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/spikeekips/naru/api/rest"
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/metrics"
+	"github.com/spikeekips/naru/storage"
 )
 
 type StreamHandler interface {
@@ -26,13 +30,97 @@ func (b BaseStreamHandler) ResponseWriter() http.ResponseWriter {
 	return b.w
 }
 
+// LastEventID returns the `Last-Event-ID` header; the reconnecting client
+// sends the id of the last received event.
+func (b BaseStreamHandler) LastEventID() string {
+	return b.r.Header.Get("Last-Event-ID")
+}
+
+// StreamEvent is the streamed item with the event id. The stream handlers
+// send StreamEvent instead of the item itself, so client can resume the stream
+// from the last received event.
+type StreamEvent struct {
+	ID   string
+	Item interface{}
+}
+
+func writeStreamEvent(jw *rest.JSONWriter, v interface{}) {
+	if e, ok := v.(StreamEvent); ok {
+		jw.WriteEvent(e.ID, e.Item)
+		return
+	}
+
+	jw.WriteEvent("", v)
+}
+
 type NewStreamHandler interface {
 	NewRequest(BaseStreamHandler) (StreamHandler, error)
 }
 
+// DefaultStreamBufferSize is the number of events, which are buffered for each
+// stream. If client can not follow the events, the stream is closed with
+// StreamTooSlow, and client can resume it with `Last-Event-ID`.
+var DefaultStreamBufferSize = 100
+
+// subscribeStream subscribes the event of storage for stream. The events are
+// converted by `f`; if `f` returns nil, the event is ignored. The returned
+// function closes the subscription.
+func subscribeStream(event string, f func(interface{}) interface{}) (<-chan interface{}, func()) {
+	sub := storage.Observer.Subscribe(event, DefaultStreamBufferSize, common.SubscriberDisconnect)
+
+	ch := make(chan interface{})
+	done := make(chan struct{})
+	var once sync.Once
+	closeFunc := func() {
+		once.Do(func() {
+			close(done)
+			sub.Close()
+		})
+	}
+
+	go func() {
+		defer close(ch)
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-sub.Closed():
+				if sub.Disconnected() {
+					select {
+					case <-done:
+					case ch <- StreamTooSlow:
+					}
+				}
+				return
+			case items := <-sub.Events():
+				for _, v := range items {
+					o := f(v)
+					if o == nil {
+						continue
+					}
+
+					select {
+					case <-done:
+						return
+					case ch <- o:
+					}
+				}
+			}
+		}
+	}()
+
+	return ch, closeFunc
+}
+
+// streamShutdownEvent is the final event of stream, which is sent when the
+// server is shutting down; client can reconnect to the other server.
+var streamShutdownEvent = map[string]string{"event": "shutdown"}
+
 type Streamer struct {
 	newHandler NewStreamHandler
 	timeout    time.Duration
+	shutdown   <-chan struct{}
 }
 
 func NewStreamer(newHandler NewStreamHandler, timeout time.Duration) Streamer {
@@ -58,39 +146,22 @@ func (s Streamer) Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	jw.Header().Set("Content-Type", "text/event-stream")
+	jw.Header().Set("Cache-Control", "no-cache")
+
+	metrics.StreamsOpen.Inc()
+	defer metrics.StreamsOpen.Dec()
+
 	initChan := streamer.Init()
 	streamChan, closeStreamFunc := streamer.Stream()
 	timeoutChan := time.After(s.timeout)
-	streamReadyChan := make(chan bool)
-	stopChan := make(chan bool)
-	defer close(streamReadyChan)
-	defer close(stopChan)
 	defer closeStreamFunc()
 
-	go func() {
-	initEnd:
-		for {
-			select {
-			case v, ok := <-initChan:
-				if !ok {
-					break initEnd
-				}
-				switch v.(type) {
-				case error:
-					jw.WriteObject(v)
-					stopChan <- true
-					break initEnd
-				case bool:
-					streamReadyChan <- true
-				default:
-					jw.WriteObject(v)
-				}
-			}
-		}
-	}()
-
+	// NOTE all the events are written in this loop; the ResponseWriter can
+	// not be written from the multiple goroutines and it should not be
+	// written after Handler returns.
 	var streamBuffer []interface{}
-	var streamReady bool
+	var streamReady, streamClosed bool
 streamEnd:
 	for {
 		select {
@@ -100,24 +171,48 @@ streamEnd:
 		case <-connCloseNotify:
 			log.Debug("HTTP connection just closed from client-side")
 			break streamEnd
-		case <-stopChan:
+		case <-s.shutdown:
+			log.Debug("server is shutting down")
+			writeStreamEvent(jw, streamShutdownEvent)
 			break streamEnd
-		case <-streamReadyChan:
-			if len(streamBuffer) > 0 {
+		case v, ok := <-initChan:
+			if !ok {
+				initChan = nil
+				continue
+			}
+
+			switch v.(type) {
+			case error:
+				writeStreamEvent(jw, v)
+				break streamEnd
+			case bool:
 				for _, i := range streamBuffer {
-					jw.WriteObject(i)
+					writeStreamEvent(jw, i)
 				}
 				streamBuffer = nil
+				streamReady = true
+
+				if streamClosed {
+					break streamEnd
+				}
+			default:
+				writeStreamEvent(jw, v)
 			}
-			streamReady = true
 		case v, ok := <-streamChan:
 			if !ok {
-				break streamEnd
+				if streamReady {
+					break streamEnd
+				}
+
+				// NOTE the stream is finished before Init; the buffered
+				// events are written after Init.
+				streamChan, streamClosed = nil, true
+				continue
 			}
 
 			switch v.(type) {
 			case error:
-				jw.WriteObject(v)
+				writeStreamEvent(jw, v)
 				break streamEnd
 			}
 
@@ -126,10 +221,7 @@ streamEnd:
 				continue
 			}
 
-			jw.WriteObject(v)
-			if _, ok := v.(error); ok {
-				break streamEnd
-			}
+			writeStreamEvent(jw, v)
 		}
 	}
 }
@@ -0,0 +1,198 @@
+package restv1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testCloseNotifyRecorder struct {
+	*httptest.ResponseRecorder
+	closed chan bool
+}
+
+func (r testCloseNotifyRecorder) CloseNotify() <-chan bool {
+	return r.closed
+}
+
+type testStreamHandler struct {
+	init   chan interface{}
+	stream chan interface{}
+}
+
+func (t testStreamHandler) NewRequest(BaseStreamHandler) (StreamHandler, error) {
+	return t, nil
+}
+
+func (t testStreamHandler) Init() <-chan interface{} {
+	return t.init
+}
+
+func (t testStreamHandler) Stream() (<-chan interface{}, func()) {
+	return t.stream, func() {}
+}
+
+type testStreamer struct {
+	suite.Suite
+}
+
+func (t *testStreamer) TestShutdown() {
+	handler := testStreamHandler{
+		init:   make(chan interface{}),
+		stream: make(chan interface{}),
+	}
+
+	shutdown := make(chan struct{})
+	streamer := NewStreamer(handler, time.Second*10)
+	streamer.shutdown = shutdown
+
+	w := testCloseNotifyRecorder{ResponseRecorder: httptest.NewRecorder(), closed: make(chan bool)}
+	r := httptest.NewRequest("GET", "/", nil)
+
+	done := make(chan struct{})
+	go func() {
+		streamer.Handler(w, r)
+		close(done)
+	}()
+
+	close(shutdown)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fail("streamer was not closed by shutdown")
+		return
+	}
+
+	t.Equal(http.StatusOK, w.Code)
+
+	t.Equal("text/event-stream", w.Header().Get("Content-Type"))
+	t.Equal(`data: {"event":"shutdown"}`, strings.TrimSpace(w.Body.String()))
+}
+
+// TestInitAfterShutdown checks the init events are not written after the
+// shutdown event; the ResponseWriter is written only by Handler.
+func (t *testStreamer) TestInitAfterShutdown() {
+	handler := testStreamHandler{
+		init:   make(chan interface{}),
+		stream: make(chan interface{}),
+	}
+
+	shutdown := make(chan struct{})
+	streamer := NewStreamer(handler, time.Second*10)
+	streamer.shutdown = shutdown
+
+	w := testCloseNotifyRecorder{ResponseRecorder: httptest.NewRecorder(), closed: make(chan bool)}
+	r := httptest.NewRequest("GET", "/", nil)
+
+	done := make(chan struct{})
+	go func() {
+		streamer.Handler(w, r)
+		close(done)
+	}()
+
+	handler.init <- "submitted"
+	close(shutdown)
+	<-done
+
+	select {
+	case handler.init <- "late":
+		t.Fail("init event was received after Handler returned")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	t.Equal("data: \"submitted\"\n\ndata: {\"event\":\"shutdown\"}", strings.TrimSpace(w.Body.String()))
+}
+
+func (t *testStreamer) TestStreamFinishedBeforeInit() {
+	handler := testStreamHandler{
+		init:   make(chan interface{}),
+		stream: make(chan interface{}, 1),
+	}
+
+	// NOTE the stream is finished before Init is ready
+	handler.stream <- "confirmed"
+	close(handler.stream)
+
+	streamer := NewStreamer(handler, time.Second*10)
+
+	w := testCloseNotifyRecorder{ResponseRecorder: httptest.NewRecorder(), closed: make(chan bool)}
+	r := httptest.NewRequest("GET", "/", nil)
+
+	done := make(chan struct{})
+	go func() {
+		streamer.Handler(w, r)
+		close(done)
+	}()
+
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		handler.init <- "submitted"
+		handler.init <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fail("streamer was not closed after the stream finished")
+		return
+	}
+
+	t.Equal("data: \"submitted\"\n\ndata: \"confirmed\"", strings.TrimSpace(w.Body.String()))
+}
+
+func (t *testStreamer) TestEventID() {
+	handler := testStreamHandler{
+		init:   make(chan interface{}),
+		stream: make(chan interface{}),
+	}
+	defer close(handler.init)
+
+	streamer := NewStreamer(handler, time.Millisecond*500)
+
+	w := testCloseNotifyRecorder{ResponseRecorder: httptest.NewRecorder(), closed: make(chan bool)}
+	r := httptest.NewRequest("GET", "/", nil)
+
+	done := make(chan struct{})
+	go func() {
+		streamer.Handler(w, r)
+		close(done)
+	}()
+
+	id := EventID{Height: 3, TxIndex: 1, OpIndex: 0}
+	handler.init <- StreamEvent{ID: id.String(), Item: map[string]int{"a": 1}}
+	handler.init <- map[string]int{"b": 2}
+	handler.init <- true
+
+	<-done
+
+	t.Equal(
+		"id: 3-1-0\ndata: {\"a\":1}\n\ndata: {\"b\":2}\n\n",
+		w.Body.String(),
+	)
+}
+
+func (t *testStreamer) TestParseEventID() {
+	id, err := ParseEventID("10-2-1")
+	t.NoError(err)
+	t.Equal(EventID{Height: 10, TxIndex: 2, OpIndex: 1}, id)
+	t.Equal("10-2-1", id.String())
+
+	t.True(id.After(EventID{Height: 10, TxIndex: 2, OpIndex: 0}))
+	t.True(id.After(EventID{Height: 9, TxIndex: 5, OpIndex: 5}))
+	t.False(id.After(EventID{Height: 10, TxIndex: 3, OpIndex: 0}))
+	t.False(id.After(id))
+
+	for _, s := range []string{"", "10", "10-2", "10-a-1", "1-2-3-4"} {
+		_, err := ParseEventID(s)
+		t.True(InvalidEventID.Equal(err), "id=%q", s)
+	}
+}
+
+func TestStreamer(t *testing.T) {
+	suite.Run(t, new(testStreamer))
+}
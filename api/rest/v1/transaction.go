@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	sebakblock "boscoin.io/sebak/lib/block"
 	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakapi "boscoin.io/sebak/lib/node/runner/api"
 	sebakresource "boscoin.io/sebak/lib/node/runner/api/resource"
 	sebaktransaction "boscoin.io/sebak/lib/transaction"
 	"github.com/gorilla/mux"
@@ -16,6 +18,8 @@ import (
 	"github.com/spikeekips/naru/api/rest"
 	resourcev1 "github.com/spikeekips/naru/api/rest/v1/resource"
 	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/storage"
 )
 
 func (h *Handler) PostTransaction(w http.ResponseWriter, r *http.Request) {
@@ -96,16 +100,10 @@ func (h *Handler) GetTransactionStatus(w http.ResponseWriter, r *http.Request) {
 
 	jw := rest.NewJSONWriter(w, r)
 
-	status := "notfound"
-	if found, err := h.potion.ExistsTransaction(hash); err != nil {
+	status, err := h.transactionStatus(hash)
+	if err != nil {
 		jw.WriteObject(err)
 		return
-	} else if found {
-		status = "confirmed"
-	} else {
-		if found, _ := h.sst.Has(sebakblock.GetTransactionPoolKey(hash)); found {
-			status = "submitted"
-		}
 	}
 
 	if status == "notfound" {
@@ -121,3 +119,203 @@ func (h *Handler) GetTransactionStatus(w http.ResponseWriter, r *http.Request) {
 	payload := sebakresource.NewTransactionStatus(hash, status)
 	jw.WriteObject(payload)
 }
+
+// transactionStatus returns the status of transaction; "confirmed" if it is
+// stored, "submitted" if it is in the transaction pool of SEBAK, or else
+// "notfound".
+func (h *Handler) transactionStatus(hash string) (string, error) {
+	if found, err := h.potion.ExistsTransaction(hash); err != nil {
+		return "", err
+	} else if found {
+		return "confirmed", nil
+	}
+
+	if found, _ := h.sst.Has(sebakblock.GetTransactionPoolKey(hash)); found {
+		return "submitted", nil
+	}
+
+	return "notfound", nil
+}
+
+// GetTransactionOperations lists the operations of transaction with the page
+// query.
+func (h *Handler) GetTransactionOperations(w http.ResponseWriter, r *http.Request) {
+	jw := rest.NewJSONWriter(w, r)
+
+	query, err := sebakapi.NewPageQuery(r)
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	hash := mux.Vars(r)["id"]
+	if _, err := h.potion.Transaction(hash); err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	lo := query.ListOptions()
+	iterFunc, closeFunc := h.potion.OperationsByTransaction(
+		hash,
+		storage.NewDefaultListOptions(lo.Reverse(), lo.Cursor(), lo.Limit()),
+	)
+	defer closeFunc()
+
+	jw.WriteObject(operationsResourceList(r, lo.Reverse(), iterFunc))
+}
+
+// transactionsResourceList reads all the transactions of iterator; the
+// iterator is already limited by the page query.
+func transactionsResourceList(r *http.Request, reverse bool, iterFunc func() (element.Transaction, bool, []byte)) *sebakresource.ResourceList {
+	var rs []sebakresource.Resource
+	var first, last string
+	for {
+		tx, next, cursor := iterFunc()
+		if !next {
+			break
+		}
+
+		if len(rs) < 1 {
+			first = string(cursor)
+		}
+		last = string(cursor)
+		rs = append(rs, resourcev1.NewTransaction(tx))
+	}
+
+	return newPageResourceList(r, rs, reverse, first, last)
+}
+
+// DefaultTransactionStatusExpire is the time to wait the confirmation of
+// transaction in the status stream.
+var DefaultTransactionStatusExpire = time.Minute * 2
+
+// TransactionStatusStreamHandler streams the status of transaction; the
+// current status, "submitted" or "confirmed", is sent first and then
+// "confirmed" is sent when the transaction is stored. If it is not confirmed
+// until `Expire`, "expired" is sent. The stream is finished after "confirmed"
+// or "expired".
+type TransactionStatusStreamHandler struct {
+	BaseStreamHandler
+	H         *Handler
+	Expire    time.Duration
+	hash      string
+	stream    <-chan interface{}
+	closeFunc func()
+}
+
+func (g TransactionStatusStreamHandler) NewRequest(base BaseStreamHandler) (StreamHandler, error) {
+	hash := mux.Vars(base.Request())["id"]
+
+	status, err := g.H.transactionStatus(hash)
+	if err != nil {
+		return nil, err
+	} else if status == "notfound" {
+		return nil, TransactionNotFound.New().SetData("status", http.StatusNotFound)
+	}
+
+	expire := g.Expire
+	if expire < 1 {
+		expire = DefaultTransactionStatusExpire
+	}
+
+	return &TransactionStatusStreamHandler{
+		BaseStreamHandler: base,
+		H:                 g.H,
+		Expire:            expire,
+		hash:              hash,
+	}, nil
+}
+
+// Init subscribes the transaction before checking the status again, so the
+// transaction stored during checking is not missed.
+func (g *TransactionStatusStreamHandler) Init() <-chan interface{} {
+	sub, closeSub := subscribeStream(element.GetTransactionEventKey(g.hash), func(v interface{}) interface{} {
+		tx, ok := v.(element.Transaction)
+		if !ok {
+			return nil
+		}
+
+		return resourcev1.NewTransactionStatus(tx.Hash, "confirmed", tx.Block)
+	})
+
+	stream := make(chan interface{})
+	done := make(chan struct{})
+	var once sync.Once
+	g.stream = stream
+	g.closeFunc = func() {
+		once.Do(func() {
+			close(done)
+			closeSub()
+		})
+	}
+
+	ch := make(chan interface{})
+
+	status, err := g.H.transactionStatus(g.hash)
+	if err != nil {
+		close(stream)
+		go func() {
+			defer close(ch)
+			ch <- err
+		}()
+
+		return ch
+	}
+
+	var current interface{}
+	switch status {
+	case "confirmed":
+		close(stream)
+
+		tx, err := g.H.potion.Transaction(g.hash)
+		if err != nil {
+			current = err
+		} else {
+			current = resourcev1.NewTransactionStatus(g.hash, status, tx.Block)
+		}
+	case "submitted":
+		current = resourcev1.NewTransactionStatus(g.hash, status, 0)
+		fallthrough
+	default:
+		go g.wait(sub, stream, done)
+	}
+
+	go func() {
+		defer close(ch)
+
+		if current != nil {
+			ch <- current
+		}
+		ch <- true
+	}()
+
+	return ch
+}
+
+// wait sends the first confirmed status or the expired status and then
+// finishes the stream.
+func (g *TransactionStatusStreamHandler) wait(sub <-chan interface{}, stream chan<- interface{}, done <-chan struct{}) {
+	defer close(stream)
+
+	var v interface{}
+	select {
+	case <-done:
+		return
+	case <-time.After(g.Expire):
+		v = resourcev1.NewTransactionStatus(g.hash, "expired", 0)
+	case i, ok := <-sub:
+		if !ok {
+			return
+		}
+		v = i
+	}
+
+	select {
+	case <-done:
+	case stream <- v:
+	}
+}
+
+func (g *TransactionStatusStreamHandler) Stream() (<-chan interface{}, func()) {
+	return g.stream, g.closeFunc
+}
@@ -0,0 +1,120 @@
+package restv1
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/spikeekips/naru/api/rest"
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/webhook"
+)
+
+// WebhookHandler is the admin api of webhooks; every request should have
+// `Authorization: Bearer <token>` header.
+type WebhookHandler struct {
+	D     *webhook.Dispatcher
+	token string
+}
+
+// AddWebhookHandlers adds the admin api of webhooks; if `token` is empty, the
+// admin api is not added.
+func (s *Server) AddWebhookHandlers(d *webhook.Dispatcher, token string) {
+	if len(token) < 1 {
+		s.log.Debug("webhook admin api disabled; empty token")
+		return
+	}
+
+	h := WebhookHandler{D: d, token: token}
+
+	s.AddHandleFunc("/api/v1/admin/webhooks", h.auth(h.GetWebhooks)).
+		Methods("GET")
+	s.AddHandleFunc("/api/v1/admin/webhooks", h.auth(h.PostWebhook)).
+		Methods("POST").
+		Headers("Content-Type", "application/json")
+	s.AddHandleFunc("/api/v1/admin/webhooks/{id}", h.auth(h.DeleteWebhook)).
+		Methods("DELETE")
+}
+
+func (h WebhookHandler) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			rest.NewJSONWriter(w, r).WriteObject(
+				Unauthorized.New().SetData("status", http.StatusUnauthorized),
+			)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (h WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks := []webhook.Hook{}
+	for _, hook := range h.D.Hooks() {
+		hooks = append(hooks, hook.WithoutSecret())
+	}
+
+	rest.NewJSONWriter(w, r).WriteObject(hooks)
+}
+
+func (h WebhookHandler) PostWebhook(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	jw := rest.NewJSONWriter(w, r)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		jw.WriteObject(err)
+		return
+	}
+
+	var hook webhook.Hook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		jw.WriteObject(InvalidMessage.New().SetData("status", http.StatusBadRequest))
+		return
+	}
+
+	if err := h.D.AddHook(hook); err != nil {
+		jw.WriteObject(webhookError(err))
+		return
+	}
+
+	jw.WriteObject(hook.WithoutSecret())
+}
+
+func (h WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.D.RemoveHook(id); err != nil {
+		rest.NewJSONWriter(w, r).WriteObject(webhookError(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func webhookError(err error) error {
+	e, ok := err.(*common.Error)
+	if !ok {
+		return err
+	}
+
+	switch {
+	case e.Equal(webhook.InvalidHook):
+		e.SetData("status", http.StatusBadRequest)
+	case e.Equal(webhook.HookNotFound):
+		e.SetData("status", http.StatusNotFound)
+	case e.Equal(webhook.HookAlreadyExists):
+		e.SetData("status", http.StatusConflict)
+	case e.Equal(webhook.StaticHook):
+		e.SetData("status", http.StatusForbidden)
+	}
+
+	return e
+}
@@ -0,0 +1,365 @@
+package restv1
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	sebakresource "boscoin.io/sebak/lib/node/runner/api/resource"
+	"golang.org/x/net/websocket"
+
+	"github.com/spikeekips/naru/api/rest"
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/metrics"
+	"github.com/spikeekips/naru/storage"
+)
+
+const (
+	WebSocketTopicOperations  string = "operations"
+	WebSocketTopicBlocks      string = "blocks"
+	WebSocketTopicTransaction string = "transaction"
+)
+
+// WebSocketRequest is the message from client. The subscription id is chosen
+// by client and the messages of the subscription come with this id.
+//
+//	{"type": "subscribe", "id": "a", "topic": "operations", "address": "GB..."}
+//	{"type": "subscribe", "id": "b", "topic": "blocks"}
+//	{"type": "subscribe", "id": "c", "topic": "transaction", "hash": "..."}
+//	{"type": "unsubscribe", "id": "a"}
+type WebSocketRequest struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Topic   string `json:"topic"`
+	Address string `json:"address,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+}
+
+// WebSocketMessage is the message to client. `Type` is one of "subscribed",
+// "unsubscribed", "event", "error" and "shutdown".
+type WebSocketMessage struct {
+	Type    string      `json:"type"`
+	ID      string      `json:"id,omitempty"`
+	EventID string      `json:"event_id,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// WebSocketHandler multiplexes the subscriptions over one websocket
+// connection. The events are queued up to `MaxPending` per connection; if
+// client can not follow the events, the connection is closed instead of
+// blocking the digest.
+type WebSocketHandler struct {
+	H                *Handler
+	MaxSubscriptions int
+	MaxPending       int
+	shutdown         <-chan struct{}
+}
+
+func (s *Server) NewWebSocketHandler(h *Handler) WebSocketHandler {
+	return WebSocketHandler{
+		H:                h,
+		MaxSubscriptions: s.webSocket.MaxSubscriptions,
+		MaxPending:       s.webSocket.MaxPending,
+		shutdown:         s.shutdown,
+	}
+}
+
+func (ws WebSocketHandler) Handler() http.Handler {
+	return websocket.Server{
+		// NOTE origin is not checked; the api is open to any client
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: ws.serve,
+	}
+}
+
+func (ws WebSocketHandler) serve(conn *websocket.Conn) {
+	// NOTE the timeouts of http server are still set to the hijacked connection
+	conn.SetDeadline(time.Time{})
+
+	metrics.StreamsOpen.Inc()
+	defer metrics.StreamsOpen.Dec()
+
+	newWebSocketConn(ws, conn).run()
+}
+
+type webSocketConn struct {
+	h             WebSocketHandler
+	conn          *websocket.Conn
+	subscriptions map[string]func()
+	out           chan WebSocketMessage
+	finished      chan string
+	closed        chan struct{}
+	closeOnce     sync.Once
+}
+
+func newWebSocketConn(h WebSocketHandler, conn *websocket.Conn) *webSocketConn {
+	return &webSocketConn{
+		h:             h,
+		conn:          conn,
+		subscriptions: map[string]func(){},
+		out:           make(chan WebSocketMessage, h.MaxPending),
+		finished:      make(chan string),
+		closed:        make(chan struct{}),
+	}
+}
+
+func (c *webSocketConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+}
+
+// run handles the requests of client; the subscriptions are managed only in
+// run, so they do not need lock.
+func (c *webSocketConn) run() {
+	defer c.conn.Close()
+	defer c.unsubscribeAll()
+
+	requests := make(chan WebSocketRequest)
+	go c.receive(requests)
+	go c.write()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-c.h.shutdown:
+			c.unsubscribeAll()
+			websocket.JSON.Send(c.conn, WebSocketMessage{Type: "shutdown"})
+			c.close()
+			return
+		case id := <-c.finished:
+			if _, found := c.subscriptions[id]; found {
+				delete(c.subscriptions, id)
+				c.send(WebSocketMessage{Type: "unsubscribed", ID: id})
+			}
+		case r := <-requests:
+			c.handle(r)
+		}
+	}
+}
+
+func (c *webSocketConn) receive(requests chan<- WebSocketRequest) {
+	defer c.close()
+
+	for {
+		var b []byte
+		if err := websocket.Message.Receive(c.conn, &b); err != nil {
+			if err != io.EOF {
+				log.Debug("failed to receive websocket message", "error", err)
+			}
+			return
+		}
+
+		var r WebSocketRequest
+		if err := json.Unmarshal(b, &r); err != nil {
+			c.send(newWebSocketError("", InvalidSubscription.New().SetData("error", err.Error())))
+			continue
+		}
+
+		select {
+		case <-c.closed:
+			return
+		case requests <- r:
+		}
+	}
+}
+
+func (c *webSocketConn) write() {
+	for {
+		select {
+		case <-c.closed:
+			return
+		case m := <-c.out:
+			if err := websocket.JSON.Send(c.conn, m); err != nil {
+				log.Debug("failed to send websocket message", "error", err)
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// send queues the message; send does not block, because it is called in the
+// event callbacks.
+func (c *webSocketConn) send(m WebSocketMessage) {
+	select {
+	case <-c.closed:
+	case c.out <- m:
+	default:
+		log.Warn("websocket client is too slow; connection will be closed", "pending", cap(c.out))
+		metrics.SubscriberDisconnectedTotal.WithLabelValues("websocket").Inc()
+		c.close()
+	}
+}
+
+func (c *webSocketConn) sendEvent(id string, v interface{}) {
+	var eventID string
+	if e, ok := v.(StreamEvent); ok {
+		eventID, v = e.ID, e.Item
+	}
+
+	c.send(WebSocketMessage{Type: "event", ID: id, EventID: eventID, Data: rest.EventObject(v)})
+}
+
+func newWebSocketError(id string, err error) WebSocketMessage {
+	return WebSocketMessage{Type: "error", ID: id, Data: rest.EventObject(err)}
+}
+
+func (c *webSocketConn) handle(r WebSocketRequest) {
+	switch r.Type {
+	case "subscribe":
+		if err := c.subscribe(r); err != nil {
+			c.send(newWebSocketError(r.ID, err))
+		}
+	case "unsubscribe":
+		off, found := c.subscriptions[r.ID]
+		if !found {
+			c.send(newWebSocketError(r.ID, SubscriptionNotFound))
+			return
+		}
+
+		off()
+		delete(c.subscriptions, r.ID)
+		c.send(WebSocketMessage{Type: "unsubscribed", ID: r.ID})
+	default:
+		c.send(newWebSocketError(r.ID, InvalidSubscription.New().SetData("type", r.Type)))
+	}
+}
+
+func (c *webSocketConn) subscribe(r WebSocketRequest) error {
+	if len(r.ID) < 1 {
+		return InvalidSubscription.New().SetData("error", "empty id")
+	} else if _, found := c.subscriptions[r.ID]; found {
+		return InvalidSubscription.New().SetData("error", "id already subscribed")
+	} else if len(c.subscriptions) >= c.h.MaxSubscriptions {
+		return TooManySubscriptions
+	}
+
+	switch r.Topic {
+	case WebSocketTopicOperations:
+		if _, err := c.h.H.potion.Account(r.Address); err != nil {
+			return err
+		}
+
+		// NOTE the event id needs the block, so the operations are handled in
+		// the goroutine of subscription instead of the observer callback.
+		ids := newOperationEventIDs(c.h.H.potion)
+		c.subscribeEvents(r.ID, element.GetOperationAccountRelatedEventKey(r.Address), func(v interface{}) {
+			if op, ok := v.(element.Operation); ok {
+				c.sendEvent(r.ID, newOperationStreamEvent(ids, op))
+			}
+		})
+	case WebSocketTopicBlocks:
+		c.on(r.ID, element.EventNewBlock, func(items ...interface{}) {
+			for _, v := range items {
+				if block, ok := v.(element.Block); ok {
+					c.sendEvent(r.ID, newBlockStreamEvent(block))
+				}
+			}
+		})
+	case WebSocketTopicTransaction:
+		return c.subscribeTransaction(r)
+	default:
+		return InvalidSubscription.New().SetData("topic", r.Topic)
+	}
+
+	return nil
+}
+
+func (c *webSocketConn) on(id, event string, callback func(...interface{})) {
+	c.send(WebSocketMessage{Type: "subscribed", ID: id})
+
+	storage.Observer.On(event, callback)
+	c.subscriptions[id] = func() {
+		storage.Observer.Off(event, callback)
+	}
+}
+
+// subscribeEvents is like `on`, but the events are buffered by
+// common.Subscriber and handled in it's own goroutine, so the slow callback
+// does not block the trigger, like the digest. If the events are more than
+// `MaxPending`, the connection is closed like `send`.
+func (c *webSocketConn) subscribeEvents(id, event string, callback func(interface{})) {
+	c.send(WebSocketMessage{Type: "subscribed", ID: id})
+
+	sub := storage.Observer.Subscribe(event, c.h.MaxPending, common.SubscriberDisconnect)
+	c.subscriptions[id] = sub.Close
+
+	go func() {
+		for {
+			select {
+			case <-c.closed:
+				sub.Close()
+				return
+			case <-sub.Closed():
+				if sub.Disconnected() {
+					log.Warn("websocket client is too slow; connection will be closed", "pending", c.h.MaxPending)
+					c.close()
+				}
+				return
+			case items := <-sub.Events():
+				for _, v := range items {
+					callback(v)
+				}
+			}
+		}
+	}()
+}
+
+// subscribeTransaction sends the current status of transaction and the
+// "confirmed" status when the transaction is stored; after "confirmed", the
+// subscription is finished.
+func (c *webSocketConn) subscribeTransaction(r WebSocketRequest) error {
+	if len(r.Hash) < 1 {
+		return InvalidSubscription.New().SetData("error", "empty hash")
+	}
+
+	var once sync.Once
+	confirmed := func() {
+		once.Do(func() {
+			c.sendEvent(r.ID, sebakresource.NewTransactionStatus(r.Hash, "confirmed"))
+
+			go func() {
+				select {
+				case <-c.closed:
+				case c.finished <- r.ID:
+				}
+			}()
+		})
+	}
+
+	// NOTE subscribe before checking the status not to miss the transaction,
+	// which is stored during checking.
+	c.on(r.ID, element.GetTransactionEventKey(r.Hash), func(...interface{}) {
+		confirmed()
+	})
+
+	status, err := c.h.H.transactionStatus(r.Hash)
+	if err != nil {
+		c.subscriptions[r.ID]()
+		delete(c.subscriptions, r.ID)
+		return err
+	}
+
+	if status == "confirmed" {
+		confirmed()
+		return nil
+	}
+
+	c.sendEvent(r.ID, sebakresource.NewTransactionStatus(r.Hash, status))
+
+	return nil
+}
+
+func (c *webSocketConn) unsubscribeAll() {
+	for id, off := range c.subscriptions {
+		off()
+		delete(c.subscriptions, id)
+	}
+}
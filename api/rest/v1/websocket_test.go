@@ -0,0 +1,157 @@
+package restv1
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/websocket"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	leveldbitem "github.com/spikeekips/naru/element/leveldb"
+	"github.com/spikeekips/naru/storage"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type testWebSocket struct {
+	suite.Suite
+	s        *leveldbstorage.Storage
+	handler  WebSocketHandler
+	server   *httptest.Server
+	shutdown chan struct{}
+}
+
+func (t *testWebSocket) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+	t.s = s
+
+	t.shutdown = make(chan struct{})
+	t.handler = WebSocketHandler{
+		H:                &Handler{potion: leveldbitem.NewPotion(s)},
+		MaxSubscriptions: 2,
+		MaxPending:       10,
+		shutdown:         t.shutdown,
+	}
+	t.server = httptest.NewServer(t.handler.Handler())
+}
+
+func (t *testWebSocket) TearDownTest() {
+	t.server.Close()
+	t.s.Close()
+}
+
+func (t *testWebSocket) dial() *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(t.server.URL, "http")
+	conn, err := websocket.Dial(url, "", t.server.URL)
+	t.NoError(err)
+
+	return conn
+}
+
+func (t *testWebSocket) request(conn *websocket.Conn, r WebSocketRequest) {
+	t.NoError(websocket.JSON.Send(conn, r))
+}
+
+func (t *testWebSocket) receive(conn *websocket.Conn) map[string]interface{} {
+	conn.SetReadDeadline(time.Now().Add(time.Second * 3))
+
+	var m map[string]interface{}
+	t.NoError(websocket.JSON.Receive(conn, &m))
+
+	return m
+}
+
+func (t *testWebSocket) TestBlocks() {
+	conn := t.dial()
+	defer conn.Close()
+
+	t.request(conn, WebSocketRequest{Type: "subscribe", ID: "b", Topic: WebSocketTopicBlocks})
+	m := t.receive(conn)
+	t.Equal("subscribed", m["type"])
+	t.Equal("b", m["id"])
+
+	block := element.Block{Header: element.BlockHeader{Height: 33}, Hash: "findme"}
+	storage.Observer.Trigger(element.EventNewBlock, block)
+
+	m = t.receive(conn)
+	t.Equal("event", m["type"])
+	t.Equal("b", m["id"])
+	t.Equal("33", m["event_id"])
+
+	t.request(conn, WebSocketRequest{Type: "unsubscribe", ID: "b"})
+	m = t.receive(conn)
+	t.Equal("unsubscribed", m["type"])
+
+	t.request(conn, WebSocketRequest{Type: "unsubscribe", ID: "b"})
+	m = t.receive(conn)
+	t.Equal("error", m["type"])
+}
+
+func (t *testWebSocket) TestOperations() {
+	address := "GABC"
+	t.NoError(t.s.Insert(element.GetAccountKey(address), element.Account{Address: address}))
+
+	block := element.Block{
+		Header:       element.BlockHeader{Height: 3},
+		Hash:         "block3",
+		Transactions: []string{"tx0"},
+	}
+	t.NoError(t.s.Insert(element.GetBlockKey(block.Hash), block))
+	t.NoError(t.s.Insert(leveldbitem.GetBlockHeightKey(3), block.Hash))
+
+	conn := t.dial()
+	defer conn.Close()
+
+	t.request(conn, WebSocketRequest{Type: "subscribe", ID: "o", Topic: WebSocketTopicOperations, Address: address})
+	t.Equal("subscribed", t.receive(conn)["type"])
+
+	op := element.Operation{Hash: "op0", TxHash: "tx0", OpIndex: 1, Source: address, Block: 3}
+	storage.Observer.Trigger(element.GetOperationAccountRelatedEventKey(address), op)
+
+	m := t.receive(conn)
+	t.Equal("event", m["type"])
+	t.Equal("o", m["id"])
+	t.Equal("3-1-1", m["event_id"])
+}
+
+func (t *testWebSocket) TestInvalidRequests() {
+	conn := t.dial()
+	defer conn.Close()
+
+	t.request(conn, WebSocketRequest{Type: "subscribe", ID: "a", Topic: "unknown"})
+	t.Equal("error", t.receive(conn)["type"])
+
+	t.request(conn, WebSocketRequest{Type: "subscribe", Topic: WebSocketTopicBlocks})
+	t.Equal("error", t.receive(conn)["type"])
+
+	for _, id := range []string{"a", "b"} {
+		t.request(conn, WebSocketRequest{Type: "subscribe", ID: id, Topic: WebSocketTopicBlocks})
+		t.Equal("subscribed", t.receive(conn)["type"])
+	}
+
+	// exceeds MaxSubscriptions
+	t.request(conn, WebSocketRequest{Type: "subscribe", ID: "c", Topic: WebSocketTopicBlocks})
+	t.Equal("error", t.receive(conn)["type"])
+}
+
+func (t *testWebSocket) TestShutdown() {
+	conn := t.dial()
+	defer conn.Close()
+
+	t.request(conn, WebSocketRequest{Type: "subscribe", ID: "b", Topic: WebSocketTopicBlocks})
+	t.Equal("subscribed", t.receive(conn)["type"])
+
+	close(t.shutdown)
+	t.Equal("shutdown", t.receive(conn)["type"])
+}
+
+func TestWebSocket(t *testing.T) {
+	suite.Run(t, new(testWebSocket))
+}
@@ -1,13 +1,19 @@
 package rest
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 
 	sebakerrors "boscoin.io/sebak/lib/errors"
 	sebakhttputils "boscoin.io/sebak/lib/network/httputils"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/common/log"
+
+	"github.com/spikeekips/naru/common"
 )
 
 type FakeCloseNotifier struct {
@@ -59,6 +65,20 @@ func (fw FlushWriter) CloseNotify() <-chan bool {
 	return f.CloseNotify()
 }
 
+func (fw FlushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(fw.ResponseWriter)
+}
+
+// hijack hijacks the connection for the protocols like websocket.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("http.Hijacker not found")
+	}
+
+	return h.Hijack()
+}
+
 func FlushWriterMiddleware() mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -105,7 +125,13 @@ var ErrorsToStatus = map[uint]int{
 	sebakerrors.TransactionNotFound.Code:       http.StatusNotFound,
 }
 
-func (j *JSONWriter) statusByError(err error) int {
+func statusByError(err error) int {
+	if ce, ok := err.(*common.Error); ok {
+		if c, ok := ce.Data()["status"].(int); ok {
+			return c
+		}
+	}
+
 	var se *sebakerrors.Error
 	var ok bool
 	if se, ok = err.(*sebakerrors.Error); ok {
@@ -128,7 +154,7 @@ func (j *JSONWriter) WriteObject(v interface{}) (int, error) {
 	} else if e, ok := v.(error); ok {
 		j.Header().Set("Content-Type", "application/problem+json")
 
-		status := j.statusByError(e)
+		status := statusByError(e)
 		j.WriteHeader(status)
 		v = sebakhttputils.NewErrorProblem(e, status)
 	} else {
@@ -137,3 +163,32 @@ func (j *JSONWriter) WriteObject(v interface{}) (int, error) {
 
 	return j.writeObject(v)
 }
+
+// EventObject returns the object, which is written in the event streams; the
+// HAL resource is converted to its resource and the error to the problem.
+func EventObject(v interface{}) interface{} {
+	if h, ok := v.(sebakhttputils.HALResource); ok {
+		return h.Resource()
+	} else if e, ok := v.(error); ok {
+		return sebakhttputils.NewErrorProblem(e, statusByError(e))
+	}
+
+	return v
+}
+
+// WriteEvent writes the object as the server-sent event; if `id` is empty,
+// the `id` field is omitted.
+func (j *JSONWriter) WriteEvent(id string, v interface{}) (int, error) {
+	bs, err := json.Marshal(EventObject(v))
+	if err != nil {
+		return 0, err
+	}
+
+	var b bytes.Buffer
+	if len(id) > 0 {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	fmt.Fprintf(&b, "data: %s\n\n", bs)
+
+	return j.ResponseWriter.Write(b.Bytes())
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	cachebackend "github.com/spikeekips/naru/cache/backend"
+	"github.com/spikeekips/naru/metrics"
 )
 
 type Cache struct {
@@ -25,7 +26,14 @@ func (c *Cache) Has(key string) (bool, error) {
 }
 
 func (c *Cache) Get(key string) (interface{}, error) {
-	return c.backend.Get(c.key(key))
+	v, err := c.backend.Get(c.key(key))
+	if err == nil {
+		metrics.ObserveCache(c.prefix, true)
+	} else if err == cachebackend.CacheItemNotFound {
+		metrics.ObserveCache(c.prefix, false)
+	}
+
+	return v, err
 }
 
 func (c *Cache) Set(key string, v interface{}, expire time.Duration) error {
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -9,6 +12,7 @@ import (
 
 	"github.com/spikeekips/naru/config"
 	"github.com/spikeekips/naru/digest"
+	"github.com/spikeekips/naru/element"
 	"github.com/spikeekips/naru/sebak"
 	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
 )
@@ -72,6 +76,15 @@ func init() {
 }
 
 func runDigest(dc *digestConfig) error {
+	if dc.Digest.Status {
+		st, err := NewStorageByConfig(dc.Storage)
+		if err != nil {
+			return err
+		}
+
+		return printDigestStatus(NewPotionByStorage(st))
+	}
+
 	nodeInfo, err := getNodeInfo(dc.SEBAK.Endpoint)
 	if err != nil {
 		return err
@@ -123,10 +136,61 @@ func runDigest(dc *digestConfig) error {
 		log.Debug("start watching")
 		watchRunner := digest.NewWatchDigestRunner(sst, potion, nodeInfo, runner.StoredRemoteBlock().Height+1, dc.Digest.MaxWorkers, dc.Digest.Blocks)
 		watchRunner.SetInterval(dc.Digest.WatchInterval)
-		if err = watchRunner.Run(true); err != nil {
+		if err = watchRunner.Run(context.Background(), true); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+func printDigestStatus(potion element.Potion) error {
+	progresses, err := element.GetDigestProgresses(potion.Storage())
+	if err != nil {
+		return err
+	} else if len(progresses) < 1 {
+		fmt.Println("no unfinished digest")
+		return nil
+	}
+
+	for _, progress := range progresses {
+		if err := printDigestProgress(potion, progress); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printDigestProgress(potion element.Potion, progress element.DigestProgress) error {
+	completed, err := progress.Completed(potion.Storage())
+	if err != nil {
+		return err
+	}
+
+	var incomplete int
+	for _, r := range progress.Ranges() {
+		if !completed[r] {
+			incomplete += 1
+		}
+	}
+
+	fmt.Printf(
+		"unfinished digest: (%d, %d], blocks=%d, initialize=%v, created=%s\n",
+		progress.Start,
+		progress.End,
+		progress.Blocks,
+		progress.Initialize,
+		progress.Created,
+	)
+	fmt.Printf("ranges: %d, incomplete: %d\n", len(completed), incomplete)
+
+	for _, r := range progress.Ranges() {
+		if completed[r] {
+			continue
+		}
+		fmt.Printf("- (%d, %d]\n", r[0], r[1])
+	}
+
+	return nil
+}
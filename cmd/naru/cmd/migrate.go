@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+
+	cmdcommon "boscoin.io/sebak/cmd/sebak/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/spikeekips/cvc"
+
+	"github.com/spikeekips/naru/config"
+)
+
+var (
+	migrateConfigManager *cvc.Manager
+)
+
+type migrateConfig struct {
+	cvc.BaseGroup
+	Migrate *config.Migrate
+	Storage *config.Storage
+	Log     *config.Logs
+
+	Verbose bool `flag-help:"verbose"`
+}
+
+func init() {
+	var mc *migrateConfig
+	migrateCmd := &cobra.Command{
+		Use:  "migrate",
+		Long: "migrate the local storage to the latest schema version",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) > 0 {
+				migrateConfigManager.SetViperConfigFile(args...)
+			}
+
+			if _, err := migrateConfigManager.Merge(); err != nil {
+				cmdcommon.PrintError(c, err)
+			}
+
+			cs := migrateConfigManager.ConfigPprint()
+			cs = append(cs, "\n\tstorage-backend", mc.Storage.Backend().Type())
+			log.Debug("config merged", cs...)
+
+			SetAllLogging(mc.Log)
+
+			log.Info("start naru migrate")
+
+			if err := runMigrate(mc); err != nil {
+				log.Error("exited with error", "error", err)
+			} else {
+				log.Info("finished")
+			}
+		},
+	}
+	rootCmd.AddCommand(migrateCmd)
+
+	mc = &migrateConfig{
+		Migrate: config.NewMigrate(),
+		Storage: config.NewStorage(),
+		Log:     config.NewLogs(),
+	}
+	migrateConfigManager = cvc.NewManager("naru", mc, migrateCmd, viper.New())
+}
+
+func runMigrate(mc *migrateConfig) error {
+	st, err := NewStorageByConfig(mc.Storage)
+	if err != nil {
+		return err
+	}
+
+	potion := NewPotionByStorage(st)
+	migrations := potion.Migrations()
+
+	version, err := migrations.Version(st)
+	if err != nil {
+		return err
+	}
+	log.Debug("schema version", "version", version, "latest", migrations.Latest())
+
+	migrated, err := migrations.Migrate(potion, mc.Migrate.DryRun)
+	for _, m := range migrated {
+		if mc.Migrate.DryRun {
+			fmt.Printf("pending migration, %s\n", m)
+		} else {
+			log.Info("migrated", "version", m.Version, "description", m.Description)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(migrated) < 1 {
+		log.Info("already migrated", "version", version)
+		return nil
+	}
+
+	if mc.Migrate.DryRun {
+		return nil
+	}
+
+	// NOTE check indices and the others
+	return potion.Check()
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+
+	cmdcommon "boscoin.io/sebak/cmd/sebak/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/spikeekips/cvc"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+)
+
+var (
+	rollbackConfigManager *cvc.Manager
+)
+
+type rollbackConfig struct {
+	cvc.BaseGroup
+	Rollback *config.Rollback
+	Storage  *config.Storage
+	Log      *config.Logs
+
+	Verbose bool `flag-help:"verbose"`
+}
+
+func init() {
+	var rc *rollbackConfig
+	rollbackCmd := &cobra.Command{
+		Use:  "rollback",
+		Long: "remove the blocks above the given height from the local storage",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) > 0 {
+				rollbackConfigManager.SetViperConfigFile(args...)
+			}
+
+			if _, err := rollbackConfigManager.Merge(); err != nil {
+				cmdcommon.PrintError(c, err)
+			}
+
+			cs := rollbackConfigManager.ConfigPprint()
+			cs = append(cs, "\n\tstorage-backend", rc.Storage.Backend().Type())
+			log.Debug("config merged", cs...)
+
+			SetAllLogging(rc.Log)
+
+			log.Info("start naru rollback")
+
+			if err := runRollback(rc); err != nil {
+				log.Error("exited with error", "error", err)
+			} else {
+				log.Info("finished")
+			}
+		},
+	}
+	rootCmd.AddCommand(rollbackCmd)
+
+	rc = &rollbackConfig{
+		Rollback: config.NewRollback(),
+		Storage:  config.NewStorage(),
+		Log:      config.NewLogs(),
+	}
+	rollbackConfigManager = cvc.NewManager("naru", rc, rollbackCmd, viper.New())
+}
+
+func runRollback(rc *rollbackConfig) error {
+	st, err := NewStorageByConfig(rc.Storage)
+	if err != nil {
+		return err
+	}
+
+	potion := NewPotionByStorage(st)
+	if err := potion.Check(); err != nil {
+		log.Crit("failed to check storage", "storage", rc.Storage, "error", err)
+		return err
+	}
+
+	if progresses, err := element.GetDigestProgresses(st); err != nil {
+		return err
+	} else if len(progresses) > 0 {
+		return fmt.Errorf(
+			"unfinished digest found, (%d, %d]; run 'digest' to resume it first",
+			progresses[0].Start,
+			progresses[0].End,
+		)
+	}
+
+	last, err := potion.LastBlock()
+	if err != nil {
+		log.Error("failed to get last block", "error", err)
+		return err
+	}
+
+	height := rc.Rollback.ToHeight
+	if last.Header.Height <= height {
+		log.Info("nothing to rollback", "last", last.Header.Height, "to-height", height)
+		return nil
+	}
+
+	log.Debug("start rollback", "last", last.Header.Height, "to-height", height)
+	if err := potion.Rollback(height); err != nil {
+		return err
+	}
+
+	log.Info("rolled back", "from", last.Header.Height, "to-height", height)
+
+	return nil
+}
@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"net/http/pprof"
+	"os"
+	"os/signal"
+	"syscall"
 
 	cmdcommon "boscoin.io/sebak/cmd/sebak/common"
 	"github.com/spf13/cobra"
@@ -13,7 +17,10 @@ import (
 	cachebackend "github.com/spikeekips/naru/cache/backend"
 	"github.com/spikeekips/naru/config"
 	"github.com/spikeekips/naru/digest"
+	"github.com/spikeekips/naru/metrics"
 	"github.com/spikeekips/naru/sebak"
+	"github.com/spikeekips/naru/storage"
+	"github.com/spikeekips/naru/webhook"
 )
 
 var (
@@ -28,6 +35,7 @@ type ServerConfig struct {
 	Network *config.Network
 	Storage *config.Storage
 	Log     *config.Logs
+	Webhook *config.Webhook
 
 	Verbose bool `flag-help:"verbose"`
 }
@@ -68,6 +76,7 @@ func init() {
 		Network: config.NewNetwork(),
 		Storage: config.NewStorage(),
 		Log:     config.NewLogs(),
+		Webhook: config.NewWebhook(),
 	}
 	serverConfigManager = cvc.NewManager("naru", sc, serverCmd, viper.New())
 }
@@ -111,13 +120,19 @@ func runServer(sc *ServerConfig) error {
 		return err
 	}
 
+	// NOTE the dispatcher starts after the initial digest, so the operations
+	// of the initial digest are not delivered.
+	dispatcher, err := newWebhookDispatcher(st, sc.Webhook)
+	if err != nil {
+		return err
+	}
+	dispatcher.Start(context.Background())
+
 	watchRunner := digest.NewWatchDigestRunner(sst, potion, nodeInfo, runner.StoredRemoteBlock().Height+1, sc.Digest.MaxWorkers, sc.Digest.Blocks)
 	watchRunner.SetInterval(sc.Digest.WatchInterval)
-	go func() {
-		if err := watchRunner.Run(false); err != nil {
-			log.Crit("failed watchRunner", "error", err)
-		}
-	}()
+
+	supervisor := digest.NewWatchSupervisor(watchRunner, false)
+	supervisor.Start(context.Background())
 
 	// start network layers
 	cb := cachebackend.NewGoCache()
@@ -130,14 +145,78 @@ func runServer(sc *ServerConfig) error {
 		restServer.AddHandleFunc("/debug/pprof/symbol", pprof.Symbol)
 		restServer.AddHandleFunc("/debug/pprof/trace", pprof.Trace)
 	}
+	if sc.System.Metrics {
+		restServer.AddHandler("/metrics", metrics.Handler())
+	}
+
+	restServer.AddStopFunc(supervisor.Stop)
+	restServer.AddStopFunc(dispatcher.Stop)
+	restServer.AddWebhookHandlers(dispatcher, sc.Webhook.AdminToken)
+
+	restServer.AddReadinessCheck("digest", func() error {
+		return watchRunner.Check(sc.Digest.MaxLag)
+	})
+	restServer.AddReadinessCheck("sebak", sst.Ping)
+	restServer.AddReadinessCheck("storage", potion.Check)
 
 	// graphql
 	restServer.AddHandler("/graphql/v1", graphqlapiv1.Handler(potion))
 
+	chanStopped := make(chan error, 1)
+	go func() {
+		chanSignal := make(chan os.Signal, 1)
+		signal.Notify(chanSignal, syscall.SIGTERM, os.Interrupt)
+
+		sig := <-chanSignal
+		log.Info("received signal; shutting down", "signal", sig)
+		signal.Stop(chanSignal)
+
+		chanStopped <- restServer.Stop()
+	}()
+
 	if err := restServer.Start(); err != nil {
 		log.Crit("failed to run restServer", "error", err)
+		restServer.Stop()
+		CloseStorage(st)
 		return err
 	}
 
+	// NOTE Start returns as soon as Stop is called; wait until Stop finishes
+	// to close the storage after the digest watcher is stopped.
+	if err := <-chanStopped; err != nil {
+		log.Error("failed to stop server", "error", err)
+	}
+
+	if err := CloseStorage(st); err != nil {
+		log.Error("failed to close storage", "error", err)
+		return err
+	}
+
+	log.Info("naru server stopped")
+
 	return nil
 }
+
+func newWebhookDispatcher(st storage.Storage, c *config.Webhook) (*webhook.Dispatcher, error) {
+	var static []webhook.Hook
+	if len(c.File) > 0 {
+		var err error
+		if static, err = webhook.LoadHooksFile(c.File); err != nil {
+			log.Crit("failed to load webhooks", "file", c.File, "error", err)
+			return nil, err
+		}
+	}
+
+	dispatcher := webhook.NewDispatcher(st, c.Timeout)
+	if c.MaxAttempts > 0 {
+		dispatcher.MaxAttempts = c.MaxAttempts
+	}
+	if err := dispatcher.Load(static); err != nil {
+		log.Crit("failed to load stored webhooks", "error", err)
+		return nil, err
+	}
+
+	log.Debug("webhooks loaded", "hooks", len(dispatcher.Hooks()))
+
+	return dispatcher, nil
+}
@@ -17,11 +17,15 @@ import (
 	"github.com/spikeekips/naru/element"
 	leveldbitem "github.com/spikeekips/naru/element/leveldb"
 	mongoitem "github.com/spikeekips/naru/element/mongo"
+	sqliteitem "github.com/spikeekips/naru/element/sqlite"
 	"github.com/spikeekips/naru/sebak"
 	"github.com/spikeekips/naru/storage"
 	storagebackend "github.com/spikeekips/naru/storage/backend"
 	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
 	mongostorage "github.com/spikeekips/naru/storage/backend/mongo"
+	sqlitestorage "github.com/spikeekips/naru/storage/backend/sqlite"
+	"github.com/spikeekips/naru/verify"
+	"github.com/spikeekips/naru/webhook"
 )
 
 func getNodeInfo(endpoint *sebakcommon.Endpoint) (sebaknode.NodeInfo, error) {
@@ -63,6 +67,8 @@ func SetAllLogging(c *config.Logs) {
 	c.Package.Storage.SetLogger(storage.Log())
 	c.Package.StorageBackend.SetLogger(storagebackend.Log())
 	c.Package.Query.SetLogger(storage.Log())
+	c.Package.Verify.SetLogger(verify.Log())
+	c.Package.Webhook.SetLogger(webhook.Log())
 }
 
 func NewStorageByConfig(c *config.Storage) (storage.Storage, error) {
@@ -82,17 +88,34 @@ func NewStorageByConfig(c *config.Storage) (storage.Storage, error) {
 			return nil, err
 		}
 		leveldbitem.EventSync()
+	case "sqlite":
+		if st, err = sqlitestorage.NewStorage(c.SQLite); err != nil {
+			log.Crit("failed to load storage", "config", c, "error", err)
+			return nil, err
+		}
+		sqliteitem.EventSync()
 	}
 
 	return st, nil
 }
 
+// CloseStorage closes the storage, if the storage backend can be closed.
+func CloseStorage(st storage.Storage) error {
+	if c, ok := st.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
 func NewPotionByStorage(st storage.Storage) element.Potion {
 	switch st.(type) {
 	case *mongostorage.Storage:
 		return mongoitem.NewPotion(st.(*mongostorage.Storage))
 	case *leveldbstorage.Storage:
 		return leveldbitem.NewPotion(st.(*leveldbstorage.Storage))
+	case *sqlitestorage.Storage:
+		return sqliteitem.NewPotion(st.(*sqlitestorage.Storage))
 	default:
 		panic(errors.New("invalid storage type found"))
 	}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"os"
+
+	cmdcommon "boscoin.io/sebak/cmd/sebak/common"
+	sebakkeypair "boscoin.io/sebak/lib/common/keypair"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/spikeekips/cvc"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/sebak"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+	"github.com/spikeekips/naru/verify"
+)
+
+var (
+	verifyConfigManager *cvc.Manager
+)
+
+type verifyConfig struct {
+	cvc.BaseGroup
+	SEBAK   *config.SEBAK
+	Verify  *config.Verify
+	Storage *config.Storage
+	Log     *config.Logs
+
+	Verbose bool `flag-help:"verbose"`
+}
+
+func init() {
+	var vc *verifyConfig
+	verifyCmd := &cobra.Command{
+		Use:  "verify",
+		Long: "verify the local storage against SEBAK",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) > 0 {
+				verifyConfigManager.SetViperConfigFile(args...)
+			}
+
+			if _, err := verifyConfigManager.Merge(); err != nil {
+				cmdcommon.PrintError(c, err)
+			}
+
+			cs := verifyConfigManager.ConfigPprint()
+			cs = append(cs, "\n\tstorage-backend", vc.Storage.Backend().Type())
+			log.Debug("config merged", cs...)
+
+			SetAllLogging(vc.Log)
+
+			log.Info("start naru verify")
+
+			report, err := runVerify(vc)
+			if err != nil {
+				log.Error("exited with error", "error", err)
+				os.Exit(1)
+			}
+
+			if vc.Verify.Format == "json" {
+				err = report.WriteJSON(os.Stdout)
+			} else {
+				err = report.WriteText(os.Stdout)
+			}
+			if err != nil {
+				log.Error("failed to print report", "error", err)
+				os.Exit(1)
+			}
+
+			if !report.OK() {
+				os.Exit(2)
+			}
+		},
+	}
+	rootCmd.AddCommand(verifyCmd)
+
+	vc = &verifyConfig{
+		SEBAK:   config.NewSEBAK(),
+		Verify:  config.NewVerify(),
+		Storage: config.NewStorage(),
+		Log:     config.NewLogs(),
+	}
+	verifyConfigManager = cvc.NewManager("naru", vc, verifyCmd, viper.New())
+}
+
+func runVerify(vc *verifyConfig) (verify.Report, error) {
+	nodeInfo, err := getNodeInfo(vc.SEBAK.Endpoint)
+	if err != nil {
+		return verify.Report{}, err
+	}
+
+	st, err := NewStorageByConfig(vc.Storage)
+	if err != nil {
+		return verify.Report{}, err
+	}
+
+	potion := NewPotionByStorage(st)
+	if err := potion.Check(); err != nil {
+		log.Crit("failed to check storage", "storage", vc.Storage, "error", err)
+		return verify.Report{}, err
+	}
+
+	end := vc.Verify.End
+	if end < 1 {
+		block, err := potion.LastBlock()
+		if err != nil {
+			log.Error("failed to get last local block", "error", err)
+			return verify.Report{}, err
+		}
+		end = block.Header.Height
+	}
+
+	var provider sebak.StorageProvider
+	if len(vc.Verify.ImportFrom.Path) < 1 {
+		provider = sebak.NewJSONRPCStorageProvider(vc.SEBAK.JSONRpc)
+	} else {
+		lst, err := leveldbstorage.NewStorage(vc.Verify.ImportFrom)
+		if err != nil {
+			log.Crit("failed to load storage", "config", vc.Verify.ImportFrom, "error", err)
+			return verify.Report{}, err
+		}
+		provider = sebak.NewLocalStorageProvider(lst)
+	}
+
+	genesisSource := sebakkeypair.Master(string(nodeInfo.Policy.NetworkID)).Address()
+
+	return verify.NewVerifier(sebak.NewStorage(provider), potion, vc.Verify.Start, end).
+		SkipAccounts(genesisSource).
+		Verify()
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -76,6 +77,47 @@ func (t *testObservable) TestAsyncOff() {
 	}())
 }
 
+func (t *testObservable) TestSubscriberDrop() {
+	o := NewObservable(RandomUUID())
+	event := newRandomEvent()
+
+	s := o.Subscribe(event, 2, SubscriberDrop)
+	defer s.Close()
+
+	// the trigger is not blocked by the full buffer
+	for i := 0; i < 5; i++ {
+		o.Trigger(event, i)
+	}
+
+	t.Equal(uint64(3), s.Dropped())
+	t.False(s.Disconnected())
+	t.Equal([]interface{}{0}, <-s.Events())
+	t.Equal([]interface{}{1}, <-s.Events())
+
+	o.Trigger(event, 5)
+	t.Equal([]interface{}{5}, <-s.Events())
+}
+
+func (t *testObservable) TestSubscriberDisconnect() {
+	o := NewObservable(RandomUUID())
+	event := newRandomEvent()
+
+	s := o.Subscribe(event, 1, SubscriberDisconnect)
+
+	o.Trigger(event, 0)
+	o.Trigger(event, 1)
+
+	select {
+	case <-s.Closed():
+	case <-time.After(time.Second * 3):
+		t.Fail("subscriber was not disconnected")
+		return
+	}
+
+	t.True(s.Disconnected())
+	t.Equal(uint64(1), s.Dropped())
+}
+
 func TestObservable(t *testing.T) {
 	suite.Run(t, new(testObservable))
 }
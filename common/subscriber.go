@@ -0,0 +1,117 @@
+package common
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/spikeekips/naru/metrics"
+)
+
+type SubscriberPolicy uint
+
+const (
+	// SubscriberDrop drops the new events when the buffer is full.
+	SubscriberDrop SubscriberPolicy = iota
+	// SubscriberDisconnect closes the subscriber when the buffer is full.
+	SubscriberDisconnect
+)
+
+func (p SubscriberPolicy) String() string {
+	switch p {
+	case SubscriberDrop:
+		return "drop"
+	case SubscriberDisconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// Subscriber receives the events through the bounded buffer. The events are
+// triggered synchronously, so the slow subscriber can block the trigger, like
+// the digest; with Subscriber, the trigger is never blocked and the events,
+// which can not be buffered, are handled by the policy.
+type Subscriber struct {
+	o         *Observable
+	events    string
+	policy    SubscriberPolicy
+	ch        chan []interface{}
+	callback  func(...interface{})
+	dropped   uint64
+	closed    chan struct{}
+	closeOnce sync.Once
+	kicked    uint32
+}
+
+// Subscribe subscribes the events with the buffer of `size`.
+func (o *Observable) Subscribe(events string, size int, policy SubscriberPolicy) *Subscriber {
+	s := &Subscriber{
+		o:      o,
+		events: events,
+		policy: policy,
+		ch:     make(chan []interface{}, size),
+		closed: make(chan struct{}),
+	}
+	s.callback = func(items ...interface{}) {
+		s.receive(items...)
+	}
+	o.on(events, s.callback)
+
+	return s
+}
+
+func (s *Subscriber) receive(items ...interface{}) {
+	select {
+	case <-s.closed:
+		return
+	case s.ch <- items:
+		return
+	default:
+	}
+
+	atomic.AddUint64(&s.dropped, 1)
+	metrics.SubscriberDroppedEventsTotal.WithLabelValues(s.o.name, s.policy.String()).Inc()
+
+	if s.policy != SubscriberDisconnect {
+		return
+	}
+
+	if atomic.CompareAndSwapUint32(&s.kicked, 0, 1) {
+		s.o.log.Warn("subscriber is too slow; disconnected", "events", s.events, "buffer", cap(s.ch))
+		metrics.SubscriberDisconnectedTotal.WithLabelValues(s.o.name).Inc()
+
+		// NOTE Observable is locked during triggering, so Close, which
+		// calls Off, should be called in another goroutine.
+		go s.Close()
+	}
+}
+
+// Events returns the channel of events; the channel is not closed, so the
+// receiver should also wait Closed().
+func (s *Subscriber) Events() <-chan []interface{} {
+	return s.ch
+}
+
+// Closed is closed when the subscriber is closed by Close or by the
+// SubscriberDisconnect policy.
+func (s *Subscriber) Closed() <-chan struct{} {
+	return s.closed
+}
+
+// Dropped returns the number of dropped events.
+func (s *Subscriber) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Disconnected returns true when the subscriber was closed by the
+// SubscriberDisconnect policy.
+func (s *Subscriber) Disconnected() bool {
+	return atomic.LoadUint64(&s.dropped) > 0 && s.policy == SubscriberDisconnect
+}
+
+func (s *Subscriber) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.o.Off(s.events, s.callback)
+	})
+}
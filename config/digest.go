@@ -17,6 +17,8 @@ type Digest struct {
 	ImportFrom    *LevelDBStorage `flag:"import-from" flag-help:"import from local leveldb of SEBAK"`
 	MaxWorkers    int             `flag-help:"maximum number of digest workers"`
 	Blocks        uint64          `flag-help:"number of blocks per worker"`
+	Status        bool            `flag-help:"show the incomplete ranges of unfinished digest and exit"`
+	MaxLag        uint64          `flag-help:"readiness fails when digest is behind the remote block more than this"`
 }
 
 func NewDigest() *Digest {
@@ -24,5 +26,6 @@ func NewDigest() *Digest {
 		WatchInterval: common.DefaultDigestWatchInterval,
 		MaxWorkers:    100,
 		Blocks:        50,
+		MaxLag:        10,
 	}
 }
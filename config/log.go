@@ -187,6 +187,8 @@ type PackageLog struct {
 	StorageBackend *LogConfig
 	SEBAK          *LogConfig
 	Query          *LogConfig
+	Verify         *LogConfig
+	Webhook        *LogConfig
 }
 
 func NewLogs() *Logs {
@@ -217,6 +219,8 @@ func (l *Logs) Merge() error {
 	l.Package.StorageBackend.Combine(l.Global)
 	l.Package.SEBAK.Combine(l.Global)
 	l.Package.Query.Combine(l.Global)
+	l.Package.Verify.Combine(l.Global)
+	l.Package.Webhook.Combine(l.Global)
 
 	return nil
 }
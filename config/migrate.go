@@ -0,0 +1,14 @@
+package config
+
+import (
+	"github.com/spikeekips/cvc"
+)
+
+type Migrate struct {
+	cvc.BaseGroup
+	DryRun bool `flag:"dry-run" flag-help:"show the pending migrations without running them"`
+}
+
+func NewMigrate() *Migrate {
+	return &Migrate{}
+}
@@ -22,6 +22,14 @@ type Network struct {
 	ReadTimeout       time.Duration
 	ReadHeaderTimeout time.Duration
 	WriteTimeout      time.Duration
+	ShutdownTimeout   time.Duration `flag-help:"time to wait the in-flight requests when shutting down"`
+	WebSocket         *WebSocketConfig
+}
+
+type WebSocketConfig struct {
+	cvc.BaseGroup
+	MaxSubscriptions int `flag-help:"maximum number of subscriptions per websocket connection"`
+	MaxPending       int `flag-help:"maximum number of pending messages per websocket connection"`
 }
 
 type TLSConfig struct {
@@ -38,6 +46,11 @@ func NewNetwork() *Network {
 		ReadTimeout:       time.Second * 10,
 		ReadHeaderTimeout: time.Second * 10,
 		WriteTimeout:      time.Second * 10,
+		ShutdownTimeout:   time.Second * 10,
+		WebSocket: &WebSocketConfig{
+			MaxSubscriptions: 10000,
+			MaxPending:       1000,
+		},
 	}
 }
 
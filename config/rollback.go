@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spikeekips/cvc"
+)
+
+type Rollback struct {
+	cvc.BaseGroup
+	ToHeight uint64 `flag:"to-height" flag-help:"remove the blocks above this height"`
+}
+
+func NewRollback() *Rollback {
+	return &Rollback{}
+}
+
+func (r *Rollback) Validate() error {
+	if r.ToHeight < 1 {
+		return fmt.Errorf("--to-height should be greater than 0; to remove all, use 'digest --init'")
+	}
+
+	return nil
+}
@@ -17,6 +17,7 @@ type Storage struct {
 	cvc.BaseGroup
 	LevelDB *LevelDBStorage `flag:"leveldb"`
 	Mongo   *MongoStorage
+	SQLite  *SQLiteStorage `flag:"sqlite"`
 }
 
 func NewStorage() *Storage {
@@ -31,6 +32,10 @@ func (s *Storage) Backend() Backend {
 		return s.Mongo
 	}
 
+	if s.SQLite != nil && s.SQLite.Path != "" {
+		return s.SQLite
+	}
+
 	return s.LevelDB
 }
 
@@ -152,3 +157,75 @@ func (m MongoStorage) ParseURI(s string) (*mongooptions.ClientOptions, error) {
 
 	return options, nil
 }
+
+// SQLiteStorage is the single file storage. Path should start with
+// 'sqlite://', like 'sqlite:///var/naru/naru.db'; 'sqlite://:memory:' is for
+// in-memory database.
+type SQLiteStorage struct {
+	cvc.BaseGroup
+	Path     string `flag-help:"storage path, sqlite://<file path>"`
+	RealPath string `flag:"-"`
+}
+
+func NewSQLiteStorage() *SQLiteStorage {
+	return &SQLiteStorage{}
+}
+
+func (l SQLiteStorage) Type() string {
+	return "sqlite"
+}
+
+func (l SQLiteStorage) ParsePath(s string) (string, error) {
+	if len(s) < 1 {
+		return s, nil
+	}
+
+	path, err := parseSQLitePath(s)
+	if err != nil {
+		return "", err
+	}
+
+	if path == ":memory:" {
+		return s, nil
+	}
+
+	if fi, err := os.Stat(path); err == nil {
+		if fi.IsDir() {
+			return "", fmt.Errorf("storage path is directory")
+		}
+	}
+
+	return s, nil
+}
+
+func (l *SQLiteStorage) Validate() error {
+	if len(l.Path) < 1 {
+		return nil
+	}
+
+	path, err := parseSQLitePath(l.Path)
+	if err != nil {
+		return err
+	}
+	l.RealPath = path
+
+	return nil
+}
+
+func parseSQLitePath(s string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(s), "sqlite://") {
+		return "", fmt.Errorf("unknown storage type")
+	}
+
+	path := s[len("sqlite://"):]
+	switch {
+	case len(path) < 1:
+		return "", fmt.Errorf("empty sqlite path")
+	case path == ":memory:":
+		return path, nil
+	case !strings.HasPrefix(path, "/"):
+		path = filepath.Join(common.CurrentDirectory, path)
+	}
+
+	return path, nil
+}
@@ -5,8 +5,9 @@ import "github.com/spikeekips/cvc"
 type System struct {
 	cvc.BaseGroup
 	Profile bool `flag-help:"enable profiling"`
+	Metrics bool `flag-help:"enable prometheus metrics at /metrics"`
 }
 
 func NewSystem() *System {
-	return &System{}
+	return &System{Metrics: true}
 }
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spikeekips/cvc"
+)
+
+type Verify struct {
+	cvc.BaseGroup
+	Start      uint64          `flag-help:"verify from this block"`
+	End        uint64          `flag-help:"verify until this block; default is the last local block"`
+	Format     string          `flag-help:"report format {text json}"`
+	ImportFrom *LevelDBStorage `flag:"import-from" flag-help:"verify with local leveldb of SEBAK"`
+}
+
+func NewVerify() *Verify {
+	return &Verify{
+		Start:  1,
+		Format: "text",
+	}
+}
+
+func (v Verify) ParseFormat(i string) (string, error) {
+	switch i {
+	case "text", "json":
+	default:
+		return "", fmt.Errorf("invalid report format, '%s'", i)
+	}
+
+	return i, nil
+}
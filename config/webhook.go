@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spikeekips/cvc"
+
+	"github.com/spikeekips/naru/common"
+)
+
+type Webhook struct {
+	cvc.BaseGroup
+	File        string        `flag-help:"yaml file of static webhooks"`
+	AdminToken  string        `flag-help:"bearer token of webhook admin api; admin api is disabled if empty"`
+	MaxAttempts int           `flag-help:"maximum number of delivery attempts"`
+	Timeout     time.Duration `flag-help:"timeout of delivery request"`
+}
+
+func NewWebhook() *Webhook {
+	return &Webhook{
+		MaxAttempts: 10,
+		Timeout:     time.Second * 10,
+	}
+}
+
+func (w Webhook) ParseFile(i string) (string, error) {
+	if len(i) < 1 {
+		return "", nil
+	}
+
+	path := i
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(common.CurrentDirectory, i)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", err
+	}
+
+	return path, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	fakesebak "github.com/spikeekips/naru/sebak/fake"
+)
+
+var (
+	chain         *fakesebak.Chain
+	options       fakesebak.ChainOptions = fakesebak.NewChainOptions()
+	networkID     string                 = fakesebak.DefaultNetworkID
+	seed          int64                  = 1
+	blocks        int                    = 100
+	txs           int                    = 10
+	printInterval int                    = 1000
+)
+
+func printFlagsError(s string, err error) {
+	var errString string
+	if err != nil {
+		errString = err.Error()
+	}
+
+	if len(s) > 0 {
+		fmt.Println("error:", s, "", errString)
+	}
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] <sebak storage>\n", os.Args[0])
+
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func init() {
+	flag.StringVar(&networkID, "network-id", networkID, "network id")
+	flag.Int64Var(&seed, "seed", seed, "random seed; same seed and flags generate same blocks")
+	flag.IntVar(&blocks, "blocks", blocks, "number of blocks")
+	flag.IntVar(&txs, "txs", txs, "maximum number of transactions in block")
+	flag.IntVar(&options.Accounts, "accounts", options.Accounts, "number of accounts created before the random transactions")
+	flag.IntVar(&options.MaxOperations, "ops", options.MaxOperations, "maximum number of operations in transaction")
+	flag.IntVar(&options.PaymentWeight, "payment", options.PaymentWeight, "weight of payment operation")
+	flag.IntVar(&options.CreateAccountWeight, "create-account", options.CreateAccountWeight, "weight of create-account operation")
+	flag.IntVar(&options.InflationWeight, "inflation", options.InflationWeight, "weight of inflation operation")
+	flag.IntVar(&options.CollectTxFeeWeight, "collect-tx-fee", options.CollectTxFeeWeight, "weight of collect-tx-fee operation")
+	flag.IntVar(&options.CongressVotingWeight, "congress-voting", options.CongressVotingWeight, "weight of congress-voting operation")
+	flag.IntVar(&options.UnfreezingWeight, "unfreezing", options.UnfreezingWeight, "weight of unfreezing-request operation")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		printFlagsError("missing arguments", nil)
+	}
+	options.Path = flag.Arg(0)
+
+	if blocks < 0 || txs < 0 {
+		printFlagsError("<blocks> and <txs> should not be negative", nil)
+	}
+	if err := options.Validate(); err != nil {
+		printFlagsError("invalid flags", err)
+	}
+
+	{
+		var err error
+		if chain, err = fakesebak.NewChainWithOptions(networkID, seed, options); err != nil {
+			printFlagsError("failed to initialize storage", err)
+		}
+	}
+}
+
+func main() {
+	defer chain.Close()
+
+	started := time.Now()
+	for i := 0; i < blocks; i++ {
+		block, err := chain.NewBlock(txs)
+		if err != nil {
+			fmt.Println("error: failed to generate block", err)
+			os.Exit(1)
+		}
+
+		if (i+1)%printInterval == 0 {
+			fmt.Println(
+				"> generated:", block.Header.Height,
+				"transactions:", block.Header.TotalTxs,
+				"operations:", block.Header.TotalOps,
+				"elapsed:", time.Since(started),
+			)
+		}
+	}
+
+	last := chain.LastBlock()
+	fmt.Println("> network id:", chain.NetworkID())
+	fmt.Println("> last block:", last.Header.Height, last.Hash)
+	fmt.Println("> transactions:", last.Header.TotalTxs)
+	fmt.Println("> operations:", last.Header.TotalOps)
+	fmt.Println("> accounts:", chain.NumberOfAccounts())
+	fmt.Println("> elapsed:", time.Since(started))
+}
@@ -9,6 +9,7 @@ import (
 	logging "github.com/inconshreveable/log15"
 
 	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/metrics"
 	"github.com/spikeekips/naru/sebak"
 	"github.com/spikeekips/naru/storage"
 )
@@ -22,6 +23,9 @@ type Digest struct {
 	end           uint64
 	initialize    bool
 	maxWorkers    int
+	progress      element.DigestProgress
+	ranges        [][2]uint64
+	resumed       bool
 }
 
 func NewDigest(sst *sebak.Storage, potion element.Potion, genesisSource string, start, end uint64, initialize bool, maxWorkers int, blocksLimit uint64) (*Digest, error) {
@@ -38,6 +42,31 @@ func NewDigest(sst *sebak.Storage, potion element.Potion, genesisSource string,
 		initialize:    initialize,
 		maxWorkers:    maxWorkers,
 		blocksLimit:   blocksLimit,
+		progress:      element.NewDigestProgress(start, end, blocksLimit, initialize),
+		ranges:        element.SplitDigestRanges(start, end, blocksLimit),
+	}, nil
+}
+
+// NewDigestByProgress resumes the unfinished digest; only the incomplete
+// ranges of DigestProgress will be digested.
+func NewDigestByProgress(sst *sebak.Storage, potion element.Potion, genesisSource string, progress element.DigestProgress, maxWorkers int) (*Digest, error) {
+	ranges, err := progress.Incomplete(potion.Storage())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Digest{
+		sst:           sst.New(),
+		potion:        potion,
+		genesisSource: genesisSource,
+		start:         progress.Start,
+		end:           progress.End,
+		initialize:    progress.Initialize,
+		maxWorkers:    maxWorkers,
+		blocksLimit:   progress.Blocks,
+		progress:      progress,
+		ranges:        ranges,
+		resumed:       true,
 	}, nil
 }
 
@@ -70,11 +99,14 @@ func (d *Digest) Close() error {
 }
 
 func (d *Digest) Digest() error {
-	if d.end == d.start {
+	if len(d.ranges) < 1 {
+		if d.resumed {
+			return d.finish()
+		}
 		return nil
 	}
 
-	numberOfWorkers := int((d.end - d.start) / d.blocksLimit)
+	numberOfWorkers := len(d.ranges)
 	if numberOfWorkers < 1 {
 		numberOfWorkers = 1
 	} else if numberOfWorkers > d.maxWorkers {
@@ -88,10 +120,19 @@ func (d *Digest) Digest() error {
 		"blocksLimit":     d.blocksLimit,
 		"numberOfWorkers": numberOfWorkers,
 		"maxWorkers":      d.maxWorkers,
+		"ranges":          len(d.ranges),
+		"resumed":         d.resumed,
 	})
 
 	log_.Debug("start digest")
 
+	if !d.resumed {
+		if err := d.progress.Save(d.potion.Storage()); err != nil {
+			log_.Error("failed to save digest progress", "error", err)
+			return err
+		}
+	}
+
 	chanWorker := make(chan [2]uint64, 1000)
 	chanError := make(chan error, 1000)
 
@@ -99,25 +140,10 @@ func (d *Digest) Digest() error {
 		go d.digestBlocks(wid, chanWorker, chanError)
 	}
 
-	var countCursors int
-	var cursorSet [][2]uint64
-	var start uint64 = d.start
-	for {
-		if start >= d.end {
-			break
-		}
-		end := start + d.blocksLimit
-		if end > d.end {
-			end = d.end
-		}
-
-		cursorSet = append(cursorSet, [2]uint64{start, end})
-		start += d.blocksLimit
-		countCursors += 1
-	}
+	countCursors := len(d.ranges)
 
 	go func() {
-		for _, cursors := range cursorSet {
+		for _, cursors := range d.ranges {
 			chanWorker <- cursors
 		}
 		close(chanWorker)
@@ -145,13 +171,28 @@ end:
 		return err
 	}
 
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	ended := time.Now()
+	log_.Debug("digest done", "end", ended, "elapsed", ended.Sub(started))
+
+	return nil
+}
+
+// finish stores the accounts for initializing and removes the DigestProgress.
+func (d *Digest) finish() error {
 	if d.initialize {
 		batch, err := d.newBatch()
 		if err != nil {
 			return err
 		}
 
-		if err := d.saveAccounts(batch); err != nil {
+		if err := d.saveAccounts(batch, d.end); err != nil {
+			return err
+		}
+		if err := element.SaveAccountStateSince(batch, d.end); err != nil {
 			return err
 		}
 		if err := batch.Write(); err != nil {
@@ -159,10 +200,12 @@ end:
 		}
 	}
 
-	d.logInsertedData()
+	if err := d.progress.Remove(d.potion.Storage()); err != nil {
+		log.Error("failed to remove digest progress", "error", err)
+		return err
+	}
 
-	ended := time.Now()
-	log_.Debug("digest done", "end", ended, "elapsed", ended.Sub(started))
+	d.logInsertedData()
 
 	return nil
 }
@@ -214,6 +257,12 @@ func (d *Digest) digestBlocksByHeight(start, end uint64) error {
 
 		blocks = append(blocks, element.NewBlock(blk))
 	}
+
+	if err := d.checkContinuity(start, blocks); err != nil {
+		log_.Error("failed to check continuity of blocks", "error", err)
+		return err
+	}
+
 	log_.Debug(
 		"block fetched",
 		"blocks", len(blocks),
@@ -229,13 +278,21 @@ func (d *Digest) digestBlocksByHeight(start, end uint64) error {
 	defer batch.Close()
 
 	var block element.Block
+	var txs, ops int
 	for _, block = range blocks {
-		if err := d.digestBlock(batch, block); err != nil {
+		t, o, err := d.digestBlock(batch, block)
+		if err != nil {
 			return err
 		}
+		txs += t
+		ops += o
 		time.Sleep(time.Millisecond * 300)
 	}
 
+	if err := d.progress.SaveRange(batch, start, end); err != nil {
+		return err
+	}
+
 	log_.Debug(
 		"block digested",
 		"last-block", block.Header.Height,
@@ -249,6 +306,10 @@ func (d *Digest) digestBlocksByHeight(start, end uint64) error {
 		return err
 	}
 
+	metrics.DigestBlocksTotal.Add(float64(len(blocks)))
+	metrics.DigestTransactionsTotal.Add(float64(txs))
+	metrics.DigestOperationsTotal.Add(float64(ops))
+
 	log_.Debug(
 		"blocks saved",
 		"last-block", block.Header.Height,
@@ -260,7 +321,54 @@ func (d *Digest) digestBlocksByHeight(start, end uint64) error {
 	return nil
 }
 
-func (d *Digest) digestBlock(st storage.Storage, block element.Block) error {
+// checkContinuity checks whether `PrevBlockHash` of each block is same with
+// the hash of the previous block. The first block is checked with the local
+// block of `start`, so the diverged local store is detected. The local block
+// of the range boundary inside this digest can be not yet stored by the other
+// worker; it is checked with the remote block instead.
+func (d *Digest) checkContinuity(start uint64, blocks []element.Block) error {
+	if len(blocks) < 1 {
+		return nil
+	}
+
+	var prev string
+	if start >= sebakcommon.GenesisBlockHeight {
+		local, err := d.potion.BlockByHeight(start)
+		switch {
+		case err == nil:
+			prev = local.Hash
+		case storage.NotFound.Equal(err) && start > d.start:
+			blk, err := sebak.GetBlockByHeight(d.sst, start)
+			if err != nil {
+				return err
+			}
+			prev = blk.Hash
+		default:
+			return err
+		}
+	}
+
+	for _, block := range blocks {
+		if block.Header.Height <= start {
+			prev = block.Hash
+			continue
+		}
+
+		if len(prev) > 0 && block.Header.PrevBlockHash != prev {
+			return BlockNotContinuous.New().
+				SetData("height", block.Header.Height).
+				SetData("prev_block_hash", block.Header.PrevBlockHash).
+				SetData("expected", prev)
+		}
+		prev = block.Hash
+	}
+
+	return nil
+}
+
+// digestBlock saves the block and it's transactions. digestBlock returns the
+// number of saved transactions and operations.
+func (d *Digest) digestBlock(st storage.Storage, block element.Block) (int, int, error) {
 	var txHashes []string
 	txHashes = append(txHashes, block.Transactions...)
 	txHashes = append(txHashes, block.ProposerTransaction)
@@ -268,21 +376,26 @@ func (d *Digest) digestBlock(st storage.Storage, block element.Block) error {
 	txs, err := sebak.GetTransactions(d.sst, txHashes...)
 	if err != nil {
 		log.Error("failed to get transactions from block", "block", block.Header.Height, "error", err)
-		return err
+		return 0, 0, err
 	}
 
 	err = d.saveBlock(st, block, txs)
 	if err != nil {
 		if err == sebakerrors.BlockAlreadyExists {
 			log.Warn("block already exists", "block", block.Header.Height)
-			return nil
+			return 0, 0, nil
 		} else {
 			log.Error("failed to save block and transactions", "block", block.Header.Height, "error", err)
-			return err
+			return 0, 0, err
 		}
 	}
 
-	return nil
+	var ops int
+	for _, tx := range txs {
+		ops += len(tx.B.Operations)
+	}
+
+	return len(txs), ops, nil
 }
 
 func (d *Digest) logInsertedData() {
@@ -375,7 +488,11 @@ func (d *Digest) logInsertedData() {
 	)
 }
 
-func (d *Digest) saveAccounts(st storage.Storage, addresses ...string) error {
+// saveAccounts saves the accounts and their AccountState at the height. At
+// initializing, all the accounts are saved at the last height of digest, so
+// the account states before that height is not available; see
+// `element.GetAccountStateSince()`.
+func (d *Digest) saveAccounts(st storage.Storage, height uint64, addresses ...string) error {
 	var count int
 	if len(addresses) < 1 {
 		options := storage.NewDefaultListOptions(false, nil, 0)
@@ -390,6 +507,9 @@ func (d *Digest) saveAccounts(st storage.Storage, addresses ...string) error {
 			if err := ac.Save(st); err != nil {
 				return err
 			}
+			if err := element.NewAccountState(ac, height).Save(st); err != nil {
+				return err
+			}
 
 			count += 1
 		}
@@ -405,6 +525,9 @@ func (d *Digest) saveAccounts(st storage.Storage, addresses ...string) error {
 			} else if err := ac.Save(st); err != nil {
 				log.Error("failed to save account from sebak", "address", address)
 				return err
+			} else if err := element.NewAccountState(ac, height).Save(st); err != nil {
+				log.Error("failed to save account state", "address", address, "height", height)
+				return err
 			}
 			count += 1
 		}
@@ -433,7 +556,7 @@ func (d *Digest) saveBlock(st storage.Storage, block element.Block, txs []elemen
 	}
 
 	if !d.initialize {
-		if err := d.saveAccounts(st, addresses...); err != nil {
+		if err := d.saveAccounts(st, block.Header.Height, addresses...); err != nil {
 			log.Error("failed to save accounts", "block", block.Header.Height, "error", err, "txs", txs)
 			return err
 		}
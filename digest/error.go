@@ -0,0 +1,17 @@
+package digest
+
+import (
+	"github.com/spikeekips/naru/common"
+)
+
+const (
+	BlockNotContinuousCode = iota + 100
+	WatcherNotRunningCode
+	DigestTooFarBehindCode
+)
+
+var (
+	BlockNotContinuous = common.NewError(BlockNotContinuousCode, "block is not continuous with the previous block")
+	WatcherNotRunning  = common.NewError(WatcherNotRunningCode, "digest watcher is not running")
+	DigestTooFarBehind = common.NewError(DigestTooFarBehindCode, "digest is too far behind the remote block")
+)
@@ -1,6 +1,7 @@
 package digest
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -12,11 +13,18 @@ import (
 
 	"github.com/spikeekips/naru/common"
 	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/metrics"
 	"github.com/spikeekips/naru/sebak"
+	"github.com/spikeekips/naru/storage"
 )
 
 var farBlockHeight uint64 = 1000
 
+// watcherStaleIntervals is the number of watch intervals; when the watcher
+// does not watch successfully during these intervals, the watcher is regarded
+// as not running.
+var watcherStaleIntervals time.Duration = 10
+
 type BaseDigestRunner struct {
 	sync.RWMutex
 	sst                 *sebak.Storage
@@ -24,6 +32,7 @@ type BaseDigestRunner struct {
 	sebakInfo           sebaknode.NodeInfo
 	storedRemoteBlock   sebakblock.Block
 	lastLocalBlock      element.Block
+	remoteHeight        uint64
 	TestLastRemoteBlock uint64
 	MaxWorkers          int
 	Blocks              uint64
@@ -46,6 +55,17 @@ func (d *BaseDigestRunner) setLastLocalBlock(block element.Block) {
 	defer d.Unlock()
 
 	d.lastLocalBlock = block
+	metrics.SetDigestHeight(block.Header.Height, d.remoteHeight)
+}
+
+// setRemoteHeight keeps the last known height of remote block for the digest
+// lag metric.
+func (d *BaseDigestRunner) setRemoteHeight(height uint64) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.remoteHeight = height
+	metrics.SetDigestHeight(d.lastLocalBlock.Header.Height, height)
 }
 
 func (d *BaseDigestRunner) StoredRemoteBlock() sebakblock.Block {
@@ -62,6 +82,107 @@ func (d *BaseDigestRunner) setStoredRemoteBlock(block sebakblock.Block) {
 	d.storedRemoteBlock = block
 }
 
+// reorganize checks the last local block is same with the remote block of
+// same height. If not, or the local block is higher than the remote, the local
+// blocks are rolled back to the highest block, which is same in both side.
+// reorganize returns true when rolled back.
+func (d *BaseDigestRunner) reorganize(sst *sebak.Storage, remote sebakblock.Block) (bool, error) {
+	local := d.LastLocalBlock()
+	if local.Header.Height < sebakcommon.GenesisBlockHeight {
+		return false, nil
+	}
+
+	top := local.Header.Height
+	if remote.Header.Height < top {
+		top = remote.Header.Height
+	}
+
+	same, err := d.isSameBlock(sst, top)
+	if err != nil {
+		return false, err
+	} else if same && top == local.Header.Height {
+		return false, nil
+	}
+
+	fork := top
+	if !same {
+		if fork, err = d.findForkPoint(sst, top); err != nil {
+			return false, err
+		}
+	}
+
+	log.Warn(
+		"local blocks diverged from the remote",
+		"local", local.Header.Height,
+		"remote", remote.Header.Height,
+		"fork", fork,
+	)
+
+	if err := d.potion.Rollback(fork); err != nil {
+		log.Error("failed to rollback", "height", fork, "error", err)
+		return false, err
+	}
+
+	var block element.Block
+	if fork >= sebakcommon.GenesisBlockHeight {
+		if block, err = d.potion.BlockByHeight(fork); err != nil {
+			return false, err
+		}
+	}
+	d.setLastLocalBlock(block)
+
+	log.Debug("local blocks rolled back", "height", fork)
+
+	return true, nil
+}
+
+// findForkPoint finds the highest height, which the local and the remote have
+// same block, under `top`. The block of `top` is already known to be
+// different.
+func (d *BaseDigestRunner) findForkPoint(sst *sebak.Storage, top uint64) (uint64, error) {
+	low := sebakcommon.GenesisBlockHeight
+	if same, err := d.isSameBlock(sst, low); err != nil {
+		return 0, err
+	} else if !same {
+		return 0, nil
+	}
+
+	high := top
+	for high-low > 1 {
+		mid := low + (high-low)/2
+
+		same, err := d.isSameBlock(sst, mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if same {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return low, nil
+}
+
+func (d *BaseDigestRunner) isSameBlock(sst *sebak.Storage, height uint64) (bool, error) {
+	remote, err := sebak.GetBlockByHeight(sst, height)
+	if err != nil {
+		return false, err
+	}
+
+	local, err := d.potion.BlockByHeight(height)
+	if storage.NotFound.Equal(err) {
+		log.Debug("local block not found", "height", height)
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return local.Hash == remote.Hash && local.Header.PrevBlockHash == remote.Header.PrevBlockHash, nil
+}
+
 type InitializeDigestRunner struct {
 	*BaseDigestRunner
 	initialize bool
@@ -103,6 +224,7 @@ func (d *InitializeDigestRunner) Run() (err error) {
 		if err != nil {
 			return
 		}
+		d.setRemoteHeight(lastRemoteBlock.Header.Height)
 	}
 
 	defer func() {
@@ -126,6 +248,15 @@ func (d *InitializeDigestRunner) Run() (err error) {
 		}
 	}
 
+	if err = d.resume(); err != nil {
+		log.Error("failed to resume digest", "error", err)
+		return
+	}
+
+	if _, err = d.reorganize(sst, lastRemoteBlock); err != nil {
+		return
+	}
+
 	if d.LastLocalBlock().Header.Height < 1 {
 		d.initialize = true
 	}
@@ -175,10 +306,78 @@ func (d *InitializeDigestRunner) Run() (err error) {
 	return nil
 }
 
+// resume digests the incomplete ranges of the unfinished digests, which were
+// stopped by crash.
+func (d *InitializeDigestRunner) resume() error {
+	progresses, err := element.GetDigestProgresses(d.potion.Storage())
+	if err != nil {
+		return err
+	}
+
+	for _, progress := range progresses {
+		if err := d.resumeProgress(progress); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *InitializeDigestRunner) resumeProgress(progress element.DigestProgress) error {
+	dg, err := NewDigestByProgress(d.sst, d.potion, d.GenesisSource(), progress, d.MaxWorkers)
+	if err != nil {
+		return err
+	}
+
+	log.Warn(
+		"found unfinished digest",
+		"start", progress.Start,
+		"end", progress.End,
+		"incomplete", len(dg.ranges),
+		"created", progress.Created,
+	)
+
+	if err := dg.Open(); err != nil {
+		return err
+	}
+	defer dg.Close()
+
+	if err := dg.Digest(); err != nil {
+		return err
+	}
+
+	if d.LastLocalBlock().Header.Height < progress.End {
+		block, err := d.potion.BlockByHeight(progress.End)
+		if err != nil {
+			return err
+		}
+		d.setLastLocalBlock(block)
+	}
+
+	d.potion.Storage().Event("OnAfterDigest", d.potion, progress.Start, progress.End)
+
+	log.Debug("unfinished digest resumed", "start", progress.Start, "end", progress.End)
+
+	return nil
+}
+
+// WatcherState is the state of WatchDigestRunner.
+type WatcherState string
+
+const (
+	WatcherStopped     WatcherState = "stopped"
+	WatcherRunning     WatcherState = "running"
+	WatcherFollowingUp WatcherState = "following-up"
+	WatcherStalled     WatcherState = "stalled"
+)
+
 type WatchDigestRunner struct {
 	*BaseDigestRunner
-	start    uint64
-	interval time.Duration
+	start       uint64
+	interval    time.Duration
+	lastWatched time.Time
+	state       WatcherState
+	lastError   error
 }
 
 func NewWatchDigestRunner(sst *sebak.Storage, potion element.Potion, sebakInfo sebaknode.NodeInfo, start uint64, maxWorkers int, blocks uint64) *WatchDigestRunner {
@@ -190,7 +389,9 @@ func NewWatchDigestRunner(sst *sebak.Storage, potion element.Potion, sebakInfo s
 			MaxWorkers: maxWorkers,
 			Blocks:     blocks,
 		},
-		start: start,
+		start:    start,
+		interval: common.DefaultDigestWatchInterval,
+		state:    WatcherStopped,
 	}
 }
 
@@ -202,10 +403,98 @@ func (w *WatchDigestRunner) SetInterval(i time.Duration) {
 	w.interval = i
 }
 
-// Run runs to watch and follow up the last remote block from sebak. By default,
-// Run does not run if the local block is far behind from the remote
-// block(`farBlockHeight`).
-func (w *WatchDigestRunner) Run(force bool) error {
+func (w *WatchDigestRunner) setLastWatched(t time.Time) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.lastWatched = t
+}
+
+func (w *WatchDigestRunner) State() WatcherState {
+	w.RLock()
+	defer w.RUnlock()
+
+	return w.state
+}
+
+// LastError returns the last error of watching; it is reset when the watcher
+// watches successfully.
+func (w *WatchDigestRunner) LastError() error {
+	w.RLock()
+	defer w.RUnlock()
+
+	return w.lastError
+}
+
+func (w *WatchDigestRunner) setState(state WatcherState, err error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.state != state {
+		log.Debug("watcher state changed", "from", w.state, "to", state, "error", err)
+	}
+
+	w.state = state
+	w.lastError = err
+}
+
+// Check returns error when the watcher is stopped or has not watched the
+// remote block successfully for a while, or the local block is behind the
+// last known remote block more than `maxLag` blocks.
+func (w *WatchDigestRunner) Check(maxLag uint64) error {
+	w.RLock()
+	defer w.RUnlock()
+
+	if w.state == WatcherStopped {
+		return WatcherNotRunning.New().SetData("state", w.state).SetData("error", w.lastError)
+	}
+
+	if w.lastWatched.IsZero() || time.Since(w.lastWatched) > w.interval*watcherStaleIntervals {
+		return WatcherNotRunning.New().
+			SetData("state", w.state).
+			SetData("last-watched", w.lastWatched).
+			SetData("error", w.lastError)
+	}
+
+	local := w.lastLocalBlock.Header.Height
+	if w.remoteHeight > local && w.remoteHeight-local > maxLag {
+		return DigestTooFarBehind.New().
+			SetData("local", local).
+			SetData("remote", w.remoteHeight).
+			SetData("max-lag", maxLag)
+	}
+
+	return nil
+}
+
+// Run runs to watch and follow up the last remote block from sebak until the
+// context is canceled. The blocks between the last local block and the last
+// remote block are digested by follow-up; if it fails, Run stops with the
+// error. The failure of watching is not fatal; the watcher becomes
+// `WatcherStalled` and tries again. By default, Run warns if the local block is
+// far behind from the remote block(`farBlockHeight`).
+func (w *WatchDigestRunner) Run(ctx context.Context, force bool) error {
+	w.setState(WatcherRunning, nil)
+
+	err := w.run(ctx, force)
+	if err != nil {
+		log.Error("watcher stopped by error", "error", err)
+	}
+	w.setState(WatcherStopped, err)
+
+	return err
+}
+
+func (w *WatchDigestRunner) run(ctx context.Context, force bool) error {
+	{ // get last local block
+		block, err := w.potion.LastBlock()
+		if err != nil {
+			log.Error("failed to get last local block", "error", err)
+			return err
+		}
+		w.setLastLocalBlock(block)
+	}
+
 	sst := w.sst.New()
 	if err := sst.Provider().Open(); err != nil {
 		if err != sebak.ProviderNotClosedError {
@@ -218,62 +507,52 @@ func (w *WatchDigestRunner) Run(force bool) error {
 	{ // get last remote block
 		var err error
 		lastRemoteBlock, err = sebak.GetLastBlock(sst)
+		sst.Provider().Close()
 		if err != nil {
-			sst.Provider().Close()
 			log.Error("failed to get remote block", "error", err)
 			return err
 		}
+		w.setRemoteHeight(lastRemoteBlock.Header.Height)
 	}
 
-	var startRemoteBlock sebakblock.Block
-	if w.start > sebakcommon.GenesisBlockHeight { // get start block
-		var err error
-		startRemoteBlock, err = sebak.GetBlockByHeight(sst, w.start)
-		if err != nil {
-			if e, ok := err.(*sebakerrors.Error); ok {
-				if e.Code != sebakerrors.StorageRecordDoesNotExist.Code {
-					sst.Provider().Close()
-					log.Error("failed to get block by height", "error", err)
-					return err
-				}
-			}
-			startRemoteBlock = lastRemoteBlock
-		}
+	// NOTE the blocks after `start` will be digested; by default, it is the
+	// last local block. The blocks before `w.start` are skipped.
+	start := w.LastLocalBlock().Header.Height
+	if w.start > start+1 {
+		start = w.start - 1
 	}
-	sst.Provider().Close()
-
-	{ // get last local block
-		block, err := w.potion.LastBlock()
-		if err != nil {
-			log.Error("failed to get last local block", "error", err)
-			return err
-		}
-		w.setLastLocalBlock(block)
+	if start > lastRemoteBlock.Header.Height {
+		start = lastRemoteBlock.Header.Height
 	}
 
 	log.Debug(
 		"start WatchDigestRunner",
-		"start", startRemoteBlock.Header.Height,
+		"start", start,
 		"remote", lastRemoteBlock.Header.Height,
 	)
 
-	if startRemoteBlock.Header.Height < lastRemoteBlock.Header.Height { // follow up
-		if !force && lastRemoteBlock.Header.Height-startRemoteBlock.Header.Height >= farBlockHeight {
+	var chanFollowup chan error
+	if start < lastRemoteBlock.Header.Height { // follow up
+		if !force && lastRemoteBlock.Header.Height-start >= farBlockHeight {
 			log.Error(
 				"local block is too far from the remote block",
-				"local", startRemoteBlock,
+				"local", start,
 				"remote", lastRemoteBlock.Header.Height,
 			)
 		}
 
-		go w.followup(startRemoteBlock.Header.Height, lastRemoteBlock.Header.Height-1)
-	}
+		w.setState(WatcherFollowingUp, nil)
 
-	w.watchLatestBlocks(lastRemoteBlock.Header.Height)
+		chanFollowup = make(chan error, 1)
+		go func(start, end uint64) {
+			chanFollowup <- w.followup(start, end)
+		}(start, lastRemoteBlock.Header.Height)
+	}
 
-	return nil
+	return w.watchLatestBlocks(ctx, lastRemoteBlock.Header.Height, chanFollowup)
 }
 
+// followup digests the blocks of (start, end].
 func (w *WatchDigestRunner) followup(start, end uint64) error {
 	log.Debug("start to follow up", "start", start, "end", end)
 
@@ -290,22 +569,66 @@ func (w *WatchDigestRunner) followup(start, end uint64) error {
 	defer dg.Close()
 
 	if err := dg.Digest(); err != nil {
+		log.Error("failed to follow up", "error", err)
 		return err
 	}
 
+	if w.LastLocalBlock().Header.Height < end {
+		block, err := w.potion.BlockByHeight(end)
+		if err != nil {
+			return err
+		}
+		w.setLastLocalBlock(block)
+	}
+
+	w.potion.Storage().Event("OnAfterDigest", w.potion, start, end)
+
+	log.Debug("follow up finished", "start", start, "end", end)
+
 	return nil
 }
 
-func (w *WatchDigestRunner) watchLatestBlocks(lastBlock uint64) {
+// watchLatestBlocks watches the latest remote block in every interval until
+// the context is canceled or the follow-up fails. While following up, the
+// latest block is not watched, because the blocks after the follow-up can not
+// be digested before the follow-up finishes. When the context is canceled,
+// watchLatestBlocks waits the running follow-up.
+func (w *WatchDigestRunner) watchLatestBlocks(ctx context.Context, lastBlock uint64, chanFollowup chan error) error {
 	log.Debug("start watchLatestBlocks", "last-block", lastBlock)
 
+	var wait time.Duration
 	for {
+		select {
+		case <-ctx.Done():
+			if chanFollowup != nil {
+				log.Debug("waiting follow-up to be finished")
+				<-chanFollowup
+			}
+			return nil
+		case err := <-chanFollowup:
+			if err != nil {
+				return err
+			}
+			chanFollowup = nil
+			w.setState(WatcherRunning, nil)
+			wait = 0 // NOTE watch the blocks created during follow-up
+			continue
+		case <-time.After(wait):
+		}
+		wait = w.interval
+
+		if chanFollowup != nil {
+			continue
+		}
+
 		if err := w.watchLatestBlock(lastBlock); err != nil {
 			log.Error("something wrong watchLatestBlock", "error", err)
-			time.Sleep(w.interval)
+			w.setState(WatcherStalled, err)
 			continue
 		}
-		time.Sleep(w.interval)
+
+		w.setLastWatched(time.Now())
+		w.setState(WatcherRunning, nil)
 	}
 }
 
@@ -321,12 +644,21 @@ func (w *WatchDigestRunner) watchLatestBlock(lastBlock uint64) error {
 	var err error
 	var block sebakblock.Block
 	block, err = sebak.GetLastBlock(sst)
-	sst.Provider().Close()
 	if err != nil {
+		sst.Provider().Close()
 		log.Error("failed to get last remote block", "error", err)
 		return err
 	}
-	if block.Header.Height < lastBlock {
+	w.setRemoteHeight(block.Header.Height)
+
+	rolledBack, err := w.reorganize(sst, block)
+	sst.Provider().Close()
+	if err != nil {
+		log.Error("failed to reorganize", "error", err)
+		return err
+	}
+
+	if !rolledBack && block.Header.Height < lastBlock {
 		return nil
 	}
 
@@ -335,7 +667,7 @@ func (w *WatchDigestRunner) watchLatestBlock(lastBlock uint64) error {
 	}
 
 	start := w.LastLocalBlock().Header.Height
-	if start < lastBlock {
+	if !rolledBack && start < lastBlock {
 		start = lastBlock
 	}
 
@@ -0,0 +1,242 @@
+package digest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	leveldbelement "github.com/spikeekips/naru/element/leveldb"
+	"github.com/spikeekips/naru/sebak"
+	fakesebak "github.com/spikeekips/naru/sebak/fake"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type testDigestRunner struct {
+	suite.Suite
+	chain  *fakesebak.Chain
+	server *fakesebak.Server
+	st     *leveldbstorage.Storage
+	potion element.Potion
+}
+
+func (t *testDigestRunner) SetupTest() {
+	chain, err := fakesebak.NewChain(fakesebak.DefaultNetworkID, 1)
+	t.NoError(err)
+	t.NoError(chain.NewBlocks(10, 5))
+
+	server, err := fakesebak.NewServer(chain)
+	t.NoError(err)
+
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	st, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.chain = chain
+	t.server = server
+	t.st = st
+	t.potion = leveldbelement.NewPotion(st)
+}
+
+func (t *testDigestRunner) TearDownTest() {
+	t.server.Close()
+	t.chain.Close()
+	t.st.Close()
+}
+
+func (t *testDigestRunner) newRunner() *InitializeDigestRunner {
+	sst := sebak.NewStorage(sebak.NewJSONRPCStorageProvider(t.server.JSONRPCEndpoint()))
+	return NewInitializeDigestRunner(sst, t.potion, t.server.NodeInfo(), 2, 3)
+}
+
+func (t *testDigestRunner) checkChain() {
+	remote := t.chain.LastBlock()
+
+	block, err := t.potion.LastBlock()
+	t.NoError(err)
+	t.Equal(remote.Header.Height, block.Header.Height)
+	t.Equal(remote.Hash, block.Hash)
+	t.Equal(remote.Header.TotalTxs, block.Header.TotalTxs)
+
+	for _, remoteAccount := range t.chain.Accounts() {
+		ac, err := t.potion.Account(remoteAccount.Address)
+		t.NoError(err)
+		t.Equal(remoteAccount.Balance, ac.Balance, "address=%s", remoteAccount.Address)
+		t.Equal(remoteAccount.SequenceID, ac.SequenceID, "address=%s", remoteAccount.Address)
+	}
+}
+
+func (t *testDigestRunner) TestInitialize() {
+	t.NoError(t.newRunner().Run())
+	t.checkChain()
+}
+
+func (t *testDigestRunner) TestFollowNewBlocks() {
+	t.NoError(t.newRunner().Run())
+
+	t.NoError(t.chain.NewBlocks(5, 5))
+
+	t.NoError(t.newRunner().Run())
+	t.checkChain()
+}
+
+func (t *testDigestRunner) TestCheckContinuityWithLocalBlock() {
+	t.NoError(t.newRunner().Run())
+	t.NoError(t.chain.NewBlocks(1, 1))
+
+	last, err := t.potion.LastBlock()
+	t.NoError(err)
+
+	sst := sebak.NewStorage(sebak.NewJSONRPCStorageProvider(t.server.JSONRPCEndpoint()))
+	dg, err := NewDigest(sst, t.potion, "", last.Header.Height, last.Header.Height+1, false, 1, 3)
+	t.NoError(err)
+
+	blocks := []element.Block{element.NewBlock(t.chain.LastBlock())}
+	t.NoError(dg.checkContinuity(last.Header.Height, blocks))
+
+	// NOTE the local block diverges from the remote
+	diverged := last
+	diverged.Hash = "diverged"
+	t.NoError(t.st.Insert(element.GetBlockKey(diverged.Hash), diverged))
+	t.NoError(t.st.Update(leveldbelement.GetBlockHeightKey(last.Header.Height), diverged.Hash))
+
+	err = dg.checkContinuity(last.Header.Height, blocks)
+	t.True(BlockNotContinuous.Equal(err))
+}
+
+func (t *testDigestRunner) newWatcher() *WatchDigestRunner {
+	sst := sebak.NewStorage(sebak.NewJSONRPCStorageProvider(t.server.JSONRPCEndpoint()))
+	watcher := NewWatchDigestRunner(sst, t.potion, t.server.NodeInfo(), 0, 2, 3)
+	watcher.SetInterval(time.Millisecond * 100)
+
+	return watcher
+}
+
+// waitLocalBlock waits until the last local block reaches to the last block of
+// chain.
+func (t *testDigestRunner) waitLocalBlock(watcher *WatchDigestRunner) {
+	for i := 0; i < 100; i++ {
+		if watcher.LastLocalBlock().Header.Height == t.chain.LastBlock().Header.Height {
+			return
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+
+	t.Fail("local block did not reach to the remote")
+}
+
+func (t *testDigestRunner) TestWatcherCheck() {
+	t.NoError(t.newRunner().Run())
+
+	watcher := t.newWatcher()
+
+	// not yet watched
+	t.True(WatcherNotRunning.Equal(watcher.Check(3)))
+
+	block, err := t.potion.LastBlock()
+	t.NoError(err)
+	watcher.setLastLocalBlock(block)
+	watcher.setState(WatcherRunning, nil)
+
+	t.NoError(watcher.watchLatestBlock(block.Header.Height))
+	watcher.setLastWatched(time.Now())
+	t.NoError(watcher.Check(3))
+
+	t.NoError(t.chain.NewBlocks(5, 5))
+	watcher.setRemoteHeight(t.chain.LastBlock().Header.Height)
+	t.True(DigestTooFarBehind.Equal(watcher.Check(3)))
+
+	t.NoError(watcher.watchLatestBlock(block.Header.Height))
+	t.NoError(watcher.Check(3))
+	t.Equal(t.chain.LastBlock().Header.Height, watcher.LastLocalBlock().Header.Height)
+}
+
+func (t *testDigestRunner) TestWatcherFollowup() {
+	t.NoError(t.newRunner().Run())
+
+	// the remote blocks are added before watcher starts
+	t.NoError(t.chain.NewBlocks(3, 5))
+
+	watcher := t.newWatcher()
+	supervisor := NewWatchSupervisor(watcher, false)
+	supervisor.Start(context.Background())
+
+	t.waitLocalBlock(watcher)
+
+	t.NoError(t.chain.NewBlocks(3, 5))
+	t.waitLocalBlock(watcher)
+
+	t.NoError(supervisor.Stop())
+	t.Equal(WatcherStopped, watcher.State())
+	t.NoError(watcher.LastError())
+
+	t.checkChain()
+}
+
+// TestWatchWhileFollowingUp checks the watcher does not digest the new remote
+// blocks and is not stalled until the follow-up finishes.
+func (t *testDigestRunner) TestWatchWhileFollowingUp() {
+	t.NoError(t.newRunner().Run())
+
+	block, err := t.potion.LastBlock()
+	t.NoError(err)
+
+	t.NoError(t.chain.NewBlocks(3, 5))
+	end := t.chain.LastBlock().Header.Height
+
+	// the remote blocks are added during follow-up
+	t.NoError(t.chain.NewBlocks(2, 5))
+
+	watcher := t.newWatcher()
+	watcher.SetInterval(time.Millisecond * 10)
+	watcher.setLastLocalBlock(block)
+	watcher.setState(WatcherFollowingUp, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chanFollowup := make(chan error, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.watchLatestBlocks(ctx, end, chanFollowup)
+	}()
+
+	time.Sleep(time.Millisecond * 100)
+	t.Equal(WatcherFollowingUp, watcher.State())
+	t.NoError(watcher.LastError())
+	t.Equal(block.Header.Height, watcher.LastLocalBlock().Header.Height)
+
+	chanFollowup <- watcher.followup(block.Header.Height, end)
+	t.waitLocalBlock(watcher)
+
+	cancel()
+	t.NoError(<-done)
+	t.Equal(WatcherRunning, watcher.State())
+
+	t.checkChain()
+}
+
+func (t *testDigestRunner) TestSupervisorRestart() {
+	watcher := t.newWatcher()
+
+	// no local block, so the watcher fails
+	supervisor := NewWatchSupervisor(watcher, false)
+	supervisor.MinBackoff = time.Millisecond * 10
+	supervisor.Start(context.Background())
+
+	for i := 0; i < 100 && supervisor.Restarts() < 2; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.True(supervisor.Restarts() >= 2)
+
+	t.NoError(supervisor.Stop())
+	t.Equal(WatcherStopped, watcher.State())
+	t.Error(watcher.LastError())
+}
+
+func TestDigestRunner(t *testing.T) {
+	suite.Run(t, new(testDigestRunner))
+}
@@ -0,0 +1,117 @@
+package digest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultSupervisorMinBackoff = time.Second
+	DefaultSupervisorMaxBackoff = time.Minute
+)
+
+// WatchSupervisor runs WatchDigestRunner and restarts it with backoff when it
+// stops by error. The backoff is doubled on each failure up to `MaxBackoff`,
+// and is reset when the runner has run longer than `MaxBackoff`.
+type WatchSupervisor struct {
+	sync.RWMutex
+	runner     *WatchDigestRunner
+	force      bool
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	restarts   int
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+func NewWatchSupervisor(runner *WatchDigestRunner, force bool) *WatchSupervisor {
+	return &WatchSupervisor{
+		runner:     runner,
+		force:      force,
+		MinBackoff: DefaultSupervisorMinBackoff,
+		MaxBackoff: DefaultSupervisorMaxBackoff,
+	}
+}
+
+func (s *WatchSupervisor) Runner() *WatchDigestRunner {
+	return s.runner
+}
+
+// Restarts returns how many times the runner was restarted.
+func (s *WatchSupervisor) Restarts() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.restarts
+}
+
+// Start starts the runner in background.
+func (s *WatchSupervisor) Start(ctx context.Context) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.done != nil {
+		return
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+
+	go s.supervise(ctx, s.done)
+}
+
+// Stop stops the runner and waits until it is stopped.
+func (s *WatchSupervisor) Stop() error {
+	s.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	<-done
+
+	log.Debug("watch supervisor stopped")
+
+	return nil
+}
+
+func (s *WatchSupervisor) supervise(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	backoff := s.MinBackoff
+	for {
+		started := time.Now()
+		err := s.runner.Run(ctx, s.force)
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		if time.Since(started) > s.MaxBackoff {
+			backoff = s.MinBackoff
+		}
+
+		log.Error("watcher stopped; will be restarted", "error", err, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		s.Lock()
+		s.restarts++
+		s.Unlock()
+
+		if backoff *= 2; backoff > s.MaxBackoff {
+			backoff = s.MaxBackoff
+		}
+	}
+}
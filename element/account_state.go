@@ -0,0 +1,127 @@
+package element
+
+import (
+	"fmt"
+
+	sebakerrors "boscoin.io/sebak/lib/errors"
+
+	"github.com/spikeekips/naru/storage"
+)
+
+// AccountState is the snapshot of account at the block height. AccountState
+// is saved whenever the account is changed by digest.
+type AccountState struct {
+	Account Account
+	Height  uint64
+}
+
+func NewAccountState(account Account, height uint64) AccountState {
+	return AccountState{Account: account, Height: height}
+}
+
+func (a AccountState) Save(st storage.Storage) error {
+	key := GetAccountStateKey(a.Account.Address, a.Height)
+
+	var f func(string, interface{}) error
+	var created bool
+	if found, err := st.Has(key); err != nil {
+		return err
+	} else if found {
+		f = st.Update
+	} else {
+		f = st.Insert
+		created = true
+	}
+
+	if err := f(key, a); err != nil {
+		return err
+	}
+
+	st.Event("OnAfterSaveAccountState", st, a, created)
+
+	return nil
+}
+
+// GetAccountAt returns the latest account state at or before the height. If
+// the height is below `GetAccountStateSince()`, AccountStateNotAvailable is
+// returned.
+func GetAccountAt(st storage.Storage, address string, height uint64) (Account, error) {
+	if since, found, err := GetAccountStateSince(st); err != nil {
+		return Account{}, err
+	} else if found && height < since {
+		return Account{}, AccountStateNotAvailable.New().
+			SetData("height", height).
+			SetData("since", since)
+	}
+
+	iterFunc, closeFunc, err := st.Iterator(
+		GetAccountStateKeyPrefix(address),
+		AccountState{},
+		storage.NewDefaultListOptions(true, []byte(GetAccountStateKey(address, height+1)), 1),
+	)
+	if err != nil {
+		return Account{}, err
+	}
+	defer closeFunc()
+
+	record, next, err := iterFunc()
+	if err != nil {
+		return Account{}, err
+	} else if !next {
+		return Account{}, sebakerrors.StorageRecordDoesNotExist
+	}
+
+	state, ok := record.Value.(AccountState)
+	if !ok {
+		return Account{}, storage.DecodeValueFailed.New()
+	}
+
+	return state.Account, nil
+}
+
+type accountStateSince struct {
+	Height uint64
+}
+
+// GetAccountStateSince returns the height, which the account states are
+// complete from. SEBAK gives only the current state of account, so at
+// initializing, digest saves the accounts only at the last height of digest;
+// the account states below that height are not available.
+func GetAccountStateSince(st storage.Storage) (uint64, bool, error) {
+	if found, err := st.Has(GetAccountStateSinceKey()); err != nil {
+		return 0, false, err
+	} else if !found {
+		return 0, false, nil
+	}
+
+	var since accountStateSince
+	if err := st.Get(GetAccountStateSinceKey(), &since); err != nil {
+		return 0, false, err
+	}
+
+	return since.Height, true, nil
+}
+
+// SaveAccountStateSince saves the height of `GetAccountStateSince()`; the
+// height is not changed once saved.
+func SaveAccountStateSince(st storage.Storage, height uint64) error {
+	if found, err := st.Has(GetAccountStateSinceKey()); err != nil {
+		return err
+	} else if found {
+		return nil
+	}
+
+	return st.Insert(GetAccountStateSinceKey(), accountStateSince{Height: height})
+}
+
+func GetAccountStateSinceKey() string {
+	return fmt.Sprintf("%s-account-state-since", InternalPrefix)
+}
+
+func GetAccountStateKeyPrefix(address string) string {
+	return fmt.Sprintf("%s%s", AccountStatePrefix, address)
+}
+
+func GetAccountStateKey(address string, height uint64) string {
+	return fmt.Sprintf("%s%020d", GetAccountStateKeyPrefix(address), height)
+}
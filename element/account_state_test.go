@@ -0,0 +1,87 @@
+package element
+
+import (
+	"testing"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakerrors "boscoin.io/sebak/lib/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type testAccountState struct {
+	suite.Suite
+	s *leveldbstorage.Storage
+}
+
+func (t *testAccountState) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+}
+
+func (t *testAccountState) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testAccountState) TestAccountAt() {
+	address := "GDIRF4UWPACXPPI4GW7CMTACTCNDIKJEHZK44RITZB4TD3YUM6CCVNGJ"
+	other := "GBZQTU2ZFVFW6XO2WCQOY4RTLHJPUOHNMRC35WGMFAAZKZNH4F4BOHGU"
+
+	for height, balance := range map[uint64]uint64{3: 100, 7: 80, 10: 120} {
+		ac := Account{Address: address, Balance: sebakcommon.Amount(balance)}
+		t.NoError(NewAccountState(ac, height).Save(t.s))
+	}
+	t.NoError(NewAccountState(Account{Address: other, Balance: 1}, 5).Save(t.s))
+
+	cases := map[uint64]uint64{3: 100, 4: 100, 6: 100, 7: 80, 9: 80, 10: 120, 1000: 120}
+	for height, expected := range cases {
+		ac, err := GetAccountAt(t.s, address, height)
+		t.NoError(err, "height=%d", height)
+		t.Equal(sebakcommon.Amount(expected), ac.Balance, "height=%d", height)
+	}
+
+	_, err := GetAccountAt(t.s, address, 2)
+	t.Equal(sebakerrors.StorageRecordDoesNotExist, err)
+}
+
+func (t *testAccountState) TestSaveSameHeight() {
+	address := "GDIRF4UWPACXPPI4GW7CMTACTCNDIKJEHZK44RITZB4TD3YUM6CCVNGJ"
+
+	t.NoError(NewAccountState(Account{Address: address, Balance: 1}, 3).Save(t.s))
+	t.NoError(NewAccountState(Account{Address: address, Balance: 2}, 3).Save(t.s))
+
+	ac, err := GetAccountAt(t.s, address, 3)
+	t.NoError(err)
+	t.Equal(sebakcommon.Amount(2), ac.Balance)
+}
+
+func (t *testAccountState) TestSince() {
+	address := "GDIRF4UWPACXPPI4GW7CMTACTCNDIKJEHZK44RITZB4TD3YUM6CCVNGJ"
+
+	t.NoError(NewAccountState(Account{Address: address, Balance: 1}, 10).Save(t.s))
+	t.NoError(SaveAccountStateSince(t.s, 10))
+	t.NoError(SaveAccountStateSince(t.s, 20)) // NOTE not changed
+
+	since, found, err := GetAccountStateSince(t.s)
+	t.NoError(err)
+	t.True(found)
+	t.Equal(uint64(10), since)
+
+	ac, err := GetAccountAt(t.s, address, 10)
+	t.NoError(err)
+	t.Equal(sebakcommon.Amount(1), ac.Balance)
+
+	_, err = GetAccountAt(t.s, address, 9)
+	t.True(AccountStateNotAvailable.Equal(err))
+}
+
+func TestAccountState(t *testing.T) {
+	suite.Run(t, new(testAccountState))
+}
@@ -59,6 +59,7 @@ func (b Block) Save(st storage.Storage) error {
 	}
 
 	st.Event("OnAfterSaveBlock", st, b)
+	st.Event(EventNewBlock, b)
 
 	return nil
 }
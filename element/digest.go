@@ -0,0 +1,254 @@
+package element
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spikeekips/naru/storage"
+)
+
+// DigestProgress keeps the state of the running digest. The digest splits the
+// blocks into the ranges and each range is digested by the parallel workers,
+// so the last stored block does not mean the lower blocks are all stored.
+// DigestProgress is removed when the digest is finished.
+type DigestProgress struct {
+	Start      uint64
+	End        uint64
+	Blocks     uint64
+	Initialize bool
+	Created    time.Time
+}
+
+func NewDigestProgress(start, end, blocks uint64, initialize bool) DigestProgress {
+	return DigestProgress{
+		Start:      start,
+		End:        end,
+		Blocks:     blocks,
+		Initialize: initialize,
+		Created:    time.Now(),
+	}
+}
+
+// GetDigestProgress returns the DigestProgress of unfinished digest of
+// `(start, end]`. If not found, returns `false`.
+func GetDigestProgress(st storage.Storage, start, end uint64) (DigestProgress, bool, error) {
+	var progress DigestProgress
+	if found, err := st.Has(GetDigestProgressKey(start, end)); err != nil {
+		return progress, false, err
+	} else if !found {
+		return progress, false, nil
+	}
+
+	if err := st.Get(GetDigestProgressKey(start, end), &progress); err != nil {
+		return progress, false, err
+	}
+
+	return progress, true, nil
+}
+
+// GetDigestProgresses returns the DigestProgresses of all the unfinished
+// digests in order of start height. The digests can run at the same time, like
+// the follow-up and the new blocks of watcher, so each digest keeps it's own
+// DigestProgress.
+func GetDigestProgresses(st storage.Storage) ([]DigestProgress, error) {
+	iterFunc, closeFunc, err := st.Iterator(
+		GetDigestProgressKeyPrefix(),
+		DigestProgress{},
+		storage.NewDefaultListOptions(false, nil, 0),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFunc()
+
+	var progresses []DigestProgress
+	for {
+		record, next, err := iterFunc()
+		if err != nil {
+			return nil, err
+		} else if !next {
+			break
+		}
+
+		progresses = append(progresses, record.Value.(DigestProgress))
+	}
+
+	return progresses, nil
+}
+
+// Ranges returns the block ranges, which are assigned to each worker. The
+// range is `(start, end]`.
+func (p DigestProgress) Ranges() [][2]uint64 {
+	return SplitDigestRanges(p.Start, p.End, p.Blocks)
+}
+
+// Completed returns the completion map of ranges.
+func (p DigestProgress) Completed(st storage.Storage) (map[[2]uint64]bool, error) {
+	completed := map[[2]uint64]bool{}
+	for _, r := range p.Ranges() {
+		completed[r] = false
+	}
+
+	iterFunc, closeFunc, err := st.Iterator(
+		GetDigestRangeKeyPrefix(p.Start, p.End),
+		DigestRange{},
+		storage.NewDefaultListOptions(false, nil, 0),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFunc()
+
+	for {
+		record, next, err := iterFunc()
+		if err != nil {
+			return nil, err
+		} else if !next {
+			break
+		}
+
+		r := record.Value.(DigestRange)
+		if _, found := completed[[2]uint64{r.Start, r.End}]; found {
+			completed[[2]uint64{r.Start, r.End}] = true
+		}
+	}
+
+	return completed, nil
+}
+
+// Incomplete returns the ranges, which are not yet stored.
+func (p DigestProgress) Incomplete(st storage.Storage) ([][2]uint64, error) {
+	completed, err := p.Completed(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranges [][2]uint64
+	for _, r := range p.Ranges() {
+		if !completed[r] {
+			ranges = append(ranges, r)
+		}
+	}
+
+	return ranges, nil
+}
+
+// Save stores the DigestProgress; the completed ranges of the previous
+// progress of same blocks are removed. The other DigestProgresses are not
+// touched.
+func (p DigestProgress) Save(st storage.Storage) error {
+	if err := p.removeRanges(st); err != nil {
+		return err
+	}
+
+	var f func(string, interface{}) error
+	if found, err := st.Has(GetDigestProgressKey(p.Start, p.End)); err != nil {
+		return err
+	} else if found {
+		f = st.Update
+	} else {
+		f = st.Insert
+	}
+
+	return f(GetDigestProgressKey(p.Start, p.End), p)
+}
+
+// Remove removes the DigestProgress and it's completed ranges.
+func (p DigestProgress) Remove(st storage.Storage) error {
+	if err := p.removeRanges(st); err != nil {
+		return err
+	}
+
+	if found, err := st.Has(GetDigestProgressKey(p.Start, p.End)); err != nil {
+		return err
+	} else if !found {
+		return nil
+	}
+
+	return st.Delete(GetDigestProgressKey(p.Start, p.End))
+}
+
+// SaveRange marks the range of blocks is stored. SaveRange should be called
+// with the blocks in same batch.
+func (p DigestProgress) SaveRange(st storage.Storage, start, end uint64) error {
+	key := GetDigestRangeKey(p.Start, p.End, start, end)
+	if found, err := st.Has(key); err != nil {
+		return err
+	} else if found {
+		return nil
+	}
+
+	return st.Insert(key, DigestRange{Start: start, End: end})
+}
+
+func (p DigestProgress) removeRanges(st storage.Storage) error {
+	iterFunc, closeFunc, err := st.Iterator(
+		GetDigestRangeKeyPrefix(p.Start, p.End),
+		DigestRange{},
+		storage.NewDefaultListOptions(false, nil, 0),
+	)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for {
+		record, next, err := iterFunc()
+		if err != nil {
+			closeFunc()
+			return err
+		} else if !next {
+			break
+		}
+		keys = append(keys, record.Key)
+	}
+	closeFunc()
+
+	for _, k := range keys {
+		if err := st.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DigestRange is the stored range of blocks of DigestProgress.
+type DigestRange struct {
+	Start uint64
+	End   uint64
+}
+
+// SplitDigestRanges splits `(start, end]` by `blocks`.
+func SplitDigestRanges(start, end, blocks uint64) [][2]uint64 {
+	if blocks < 1 {
+		blocks = 1
+	}
+
+	var ranges [][2]uint64
+	for s := start; s < end; s += blocks {
+		e := s + blocks
+		if e > end {
+			e = end
+		}
+		ranges = append(ranges, [2]uint64{s, e})
+	}
+
+	return ranges
+}
+
+func GetDigestProgressKeyPrefix() string {
+	return fmt.Sprintf("%s-digest-progress-", InternalPrefix)
+}
+
+func GetDigestProgressKey(start, end uint64) string {
+	return fmt.Sprintf("%s%020d-%020d", GetDigestProgressKeyPrefix(), start, end)
+}
+
+func GetDigestRangeKeyPrefix(progressStart, progressEnd uint64) string {
+	return fmt.Sprintf("%s-digest-range-%020d-%020d-", InternalPrefix, progressStart, progressEnd)
+}
+
+func GetDigestRangeKey(progressStart, progressEnd, start, end uint64) string {
+	return fmt.Sprintf("%s%020d-%020d", GetDigestRangeKeyPrefix(progressStart, progressEnd), start, end)
+}
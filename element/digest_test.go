@@ -0,0 +1,126 @@
+package element
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type testDigestProgress struct {
+	suite.Suite
+	s *leveldbstorage.Storage
+}
+
+func (t *testDigestProgress) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+}
+
+func (t *testDigestProgress) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testDigestProgress) TestSplitDigestRanges() {
+	t.Equal([][2]uint64{{0, 3}, {3, 6}, {6, 7}}, SplitDigestRanges(0, 7, 3))
+	t.Equal([][2]uint64{{5, 7}}, SplitDigestRanges(5, 7, 10))
+	t.Nil(SplitDigestRanges(7, 7, 3))
+}
+
+func (t *testDigestProgress) TestNotFound() {
+	_, found, err := GetDigestProgress(t.s, 0, 10)
+	t.NoError(err)
+	t.False(found)
+
+	progresses, err := GetDigestProgresses(t.s)
+	t.NoError(err)
+	t.Empty(progresses)
+}
+
+func (t *testDigestProgress) TestIncomplete() {
+	progress := NewDigestProgress(0, 10, 3, true)
+	t.NoError(progress.Save(t.s))
+
+	{
+		saved, found, err := GetDigestProgress(t.s, 0, 10)
+		t.NoError(err)
+		t.True(found)
+		t.Equal(progress.Start, saved.Start)
+		t.Equal(progress.End, saved.End)
+		t.Equal(progress.Blocks, saved.Blocks)
+		t.Equal(progress.Initialize, saved.Initialize)
+	}
+
+	t.NoError(progress.SaveRange(t.s, 3, 6))
+	t.NoError(progress.SaveRange(t.s, 9, 10))
+
+	ranges, err := progress.Incomplete(t.s)
+	t.NoError(err)
+	t.Equal([][2]uint64{{0, 3}, {6, 9}}, ranges)
+
+	// new progress of same blocks clears the completed ranges of the previous
+	// one
+	t.NoError(NewDigestProgress(0, 10, 3, false).Save(t.s))
+	ranges, err = progress.Incomplete(t.s)
+	t.NoError(err)
+	t.Equal(progress.Ranges(), ranges)
+}
+
+func (t *testDigestProgress) TestRemove() {
+	progress := NewDigestProgress(0, 10, 3, false)
+	t.NoError(progress.Save(t.s))
+	t.NoError(progress.SaveRange(t.s, 0, 3))
+
+	t.NoError(progress.Remove(t.s))
+
+	_, found, err := GetDigestProgress(t.s, 0, 10)
+	t.NoError(err)
+	t.False(found)
+
+	found, err = t.s.Has(GetDigestRangeKey(0, 10, 0, 3))
+	t.NoError(err)
+	t.False(found)
+}
+
+// TestOverlapping runs two digests at the same time like the follow-up and the
+// new blocks of watcher; each one keeps it's own progress.
+func (t *testDigestProgress) TestOverlapping() {
+	followup := NewDigestProgress(0, 10, 3, false)
+	t.NoError(followup.Save(t.s))
+	t.NoError(followup.SaveRange(t.s, 3, 6))
+
+	latest := NewDigestProgress(10, 12, 3, false)
+	t.NoError(latest.Save(t.s))
+
+	{ // saving the new progress does not touch the running one
+		ranges, err := followup.Incomplete(t.s)
+		t.NoError(err)
+		t.Equal([][2]uint64{{0, 3}, {6, 9}, {9, 10}}, ranges)
+	}
+
+	t.NoError(latest.SaveRange(t.s, 10, 12))
+	t.NoError(latest.Remove(t.s))
+
+	{ // finishing the new one does not remove the running one
+		progresses, err := GetDigestProgresses(t.s)
+		t.NoError(err)
+		t.Equal(1, len(progresses))
+		t.Equal(followup.Start, progresses[0].Start)
+		t.Equal(followup.End, progresses[0].End)
+
+		ranges, err := followup.Incomplete(t.s)
+		t.NoError(err)
+		t.Equal([][2]uint64{{0, 3}, {6, 9}, {9, 10}}, ranges)
+	}
+}
+
+func TestDigestProgress(t *testing.T) {
+	suite.Run(t, new(testDigestProgress))
+}
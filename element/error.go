@@ -0,0 +1,27 @@
+package element
+
+import (
+	"github.com/spikeekips/naru/common"
+)
+
+const (
+	SchemaVersionNewerCode = iota + 100
+	SchemaNotMigratedCode
+	InvalidMigrationCode
+	UnknownSearchCollectionCode
+	InvalidSearchQueryCode
+	AccountStateNotAvailableCode
+	TooManySearchRecordsCode
+)
+
+var (
+	SchemaVersionNewer = common.NewError(SchemaVersionNewerCode, "schema version of storage is newer than this naru supports")
+	SchemaNotMigrated  = common.NewError(SchemaNotMigratedCode, "storage is not migrated; run 'migrate'")
+	InvalidMigration   = common.NewError(InvalidMigrationCode, "invalid migration found")
+
+	UnknownSearchCollection = common.NewError(UnknownSearchCollectionCode, "unknown search collection")
+	InvalidSearchQuery      = common.NewError(InvalidSearchQueryCode, "invalid search query")
+	TooManySearchRecords    = common.NewError(TooManySearchRecordsCode, "too many records are matched; narrow the query")
+
+	AccountStateNotAvailable = common.NewError(AccountStateNotAvailableCode, "account state is not available at the height")
+)
@@ -10,3 +10,13 @@ const (
 const (
 	EventPrefixNewOperation string = "\x00\x00"
 )
+
+// EventNewBlock is triggered with the saved block; with BatchStorage, it is
+// triggered after the batch is written.
+const EventNewBlock string = EventPrefixNewOperation + BlockPrefix
+
+// GetTransactionEventKey is the event key, which is triggered with the saved
+// transaction; with BatchStorage, it is triggered after the batch is written.
+func GetTransactionEventKey(hash string) string {
+	return EventPrefixNewOperation + TransactionPrefix + hash
+}
@@ -0,0 +1,11 @@
+package leveldbelement
+
+import (
+	logging "github.com/inconshreveable/log15"
+)
+
+var log logging.Logger = logging.New("module", "leveldbelement")
+
+func Log() logging.Logger {
+	return log
+}
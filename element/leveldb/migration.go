@@ -0,0 +1,53 @@
+package leveldbelement
+
+import (
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/storage"
+)
+
+// migrations is the ordered migrations of LevelDB storage; the new migration
+// should be appended with the next version.
+var migrations = element.NewMigrations(
+	append(
+		element.DefaultMigrations(),
+		element.Migration{
+			Version:     3,
+			Description: "save the secondary keys of operations by type and counterparty",
+			Run:         migrationOperationFilterKeys,
+		},
+	)...,
+)
+
+func (g Potion) Migrations() *element.Migrations {
+	return migrations
+}
+
+// migrationOperationFilterKeys saves the secondary keys for
+// `OperationsByAccountFilter` of the operations, which were stored before the
+// keys were added.
+func migrationOperationFilterKeys(potion element.Potion, st storage.Storage) error {
+	iterFunc, closeFunc, err := potion.Storage().Iterator(
+		element.OperationPrefix,
+		element.Operation{},
+		storage.NewDefaultListOptions(false, nil, 0),
+	)
+	if err != nil {
+		return err
+	}
+	defer closeFunc()
+
+	for {
+		record, next, err := iterFunc()
+		if err != nil {
+			return err
+		} else if !next {
+			break
+		}
+
+		if err := saveOperationFilterKeys(st, record.Value.(element.Operation)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
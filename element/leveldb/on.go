@@ -11,6 +11,7 @@ import (
 
 func EventSync() {
 	storage.Observer.Sync("OnAfterSaveAccount", OnAfterSaveAccount)
+	storage.Observer.Sync("OnAfterSaveAccountState", OnAfterSaveAccountState)
 	storage.Observer.Sync("OnAfterSaveBlock", OnAfterSaveBlock)
 	storage.Observer.Sync("OnAfterSaveTransaction", OnAfterSaveTransaction)
 	storage.Observer.Sync("OnAfterSaveOperation", OnAfterSaveOperation)
@@ -19,6 +20,17 @@ func EventSync() {
 func OnAfterSaveAccount(st storage.Storage, account element.Account, created bool) {
 }
 
+func OnAfterSaveAccountState(st storage.Storage, state element.AccountState, created bool) {
+	if !created {
+		return
+	}
+
+	key := element.GetAccountStateKey(state.Account.Address, state.Height)
+	if err := st.Insert(GetAccountStateHeightKey(state.Height, state.Account.Address), key); err != nil {
+		return
+	}
+}
+
 func OnAfterSaveBlock(st storage.Storage, block element.Block) {
 	if err := st.Insert(GetBlockHeightKey(block.Header.Height), block.Hash); err != nil {
 		return
@@ -54,5 +66,35 @@ func OnAfterSaveOperation(st storage.Storage, operation element.Operation) {
 		events = append(events, element.GetOperationAccountRelatedEventKey(operation.Target))
 	}
 
+	if err := saveOperationFilterKeys(st, operation); err != nil {
+		return
+	}
+
 	st.Event(strings.Join(events, " "), operation)
 }
+
+// saveOperationFilterKeys saves the secondary keys of operation by type and
+// counterparty for `OperationsByAccountFilter`.
+func saveOperationFilterKeys(st storage.Storage, operation element.Operation) error {
+	addresses := []string{operation.Source}
+	if len(operation.Target) > 0 && operation.Target != operation.Source {
+		addresses = append(addresses, operation.Target)
+	}
+
+	for _, address := range addresses {
+		if err := st.Insert(GetOperationAccountTypeKey(address, operation.Type, operation.Block), operation.Hash); err != nil {
+			return err
+		}
+	}
+
+	if len(addresses) > 1 {
+		if err := st.Insert(GetOperationAccountCounterpartyKey(operation.Source, operation.Target, operation.Block), operation.Hash); err != nil {
+			return err
+		}
+		if err := st.Insert(GetOperationAccountCounterpartyKey(operation.Target, operation.Source, operation.Block), operation.Hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
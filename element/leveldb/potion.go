@@ -2,11 +2,16 @@ package leveldbelement
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	sebakerrors "boscoin.io/sebak/lib/errors"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
 
 	"github.com/spikeekips/naru/common"
 	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/query"
 	"github.com/spikeekips/naru/storage"
 	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
 )
@@ -17,43 +22,103 @@ func GetBlockHeightKey(height uint64) string {
 
 func GetOperationAccountRelatedKey(address string, blockHeight uint64) string {
 	return fmt.Sprintf(
-		"%s%s%20d%s",
+		"%s%s",
+		getOperationAccountRelatedKeyPrefix(address, blockHeight),
+		common.SequentialUUID(),
+	)
+}
+
+func getOperationAccountRelatedKeyPrefix(address string, blockHeight uint64) string {
+	return fmt.Sprintf(
+		"%s%s%20d",
 		element.OperationAccountRelatedPrefix,
 		address,
 		blockHeight,
+	)
+}
+
+// GetOperationAccountTypeKey is the secondary key of the operations of account
+// by operation type. The type is followed by "/", because some type is the
+// prefix of the other type, like "congress-voting" and
+// "congress-voting-result".
+func GetOperationAccountTypeKey(address string, opType sebakoperation.OperationType, blockHeight uint64) string {
+	return fmt.Sprintf(
+		"%s%s",
+		getOperationAccountTypeKeyPrefix(address, opType, blockHeight),
+		common.SequentialUUID(),
+	)
+}
+
+func getOperationAccountTypePrefix(address string, opType sebakoperation.OperationType) string {
+	return fmt.Sprintf("%s%s%s/", OperationAccountTypePrefix, address, opType)
+}
+
+func getOperationAccountTypeKeyPrefix(address string, opType sebakoperation.OperationType, blockHeight uint64) string {
+	return fmt.Sprintf("%s%020d", getOperationAccountTypePrefix(address, opType), blockHeight)
+}
+
+// GetOperationAccountCounterpartyKey is the secondary key of the operations
+// between account and counterparty.
+func GetOperationAccountCounterpartyKey(address, counterparty string, blockHeight uint64) string {
+	return fmt.Sprintf(
+		"%s%s",
+		getOperationAccountCounterpartyKeyPrefix(address, counterparty, blockHeight),
 		common.SequentialUUID(),
 	)
 }
 
+func getOperationAccountCounterpartyPrefix(address, counterparty string) string {
+	return fmt.Sprintf("%s%s%s", OperationAccountCounterpartyPrefix, address, counterparty)
+}
+
+func getOperationAccountCounterpartyKeyPrefix(address, counterparty string, blockHeight uint64) string {
+	return fmt.Sprintf("%s%020d", getOperationAccountCounterpartyPrefix(address, counterparty), blockHeight)
+}
+
 func GetTransactionBlockKey(block uint64) string {
 	return fmt.Sprintf(
-		"%s%020d%s",
-		TransactionBlockPrefix,
-		block,
+		"%s%s",
+		getTransactionBlockKeyPrefix(block),
 		common.SequentialUUID(),
 	)
 }
 
+func getTransactionBlockKeyPrefix(block uint64) string {
+	return fmt.Sprintf("%s%020d", TransactionBlockPrefix, block)
+}
+
 func GetTransactionSourceKey(source string, block uint64) string {
 	return fmt.Sprintf(
-		"%s%s%020d%s",
-		TransactionSourcePrefix,
-		source,
-		block,
+		"%s%s",
+		getTransactionSourceKeyPrefix(source, block),
 		common.SequentialUUID(),
 	)
 }
 
+func getTransactionSourceKeyPrefix(source string, block uint64) string {
+	return fmt.Sprintf("%s%s%020d", TransactionSourcePrefix, source, block)
+}
+
 func GetTransactionAccountsKey(address string, block uint64) string {
 	return fmt.Sprintf(
-		"%s%s%020d%s",
-		TransactionAccountsPrefix,
-		address,
-		block,
+		"%s%s",
+		getTransactionAccountsKeyPrefix(address, block),
 		common.SequentialUUID(),
 	)
 }
 
+func getTransactionAccountsKeyPrefix(address string, block uint64) string {
+	return fmt.Sprintf("%s%s%020d", TransactionAccountsPrefix, address, block)
+}
+
+func GetAccountStateHeightKey(height uint64, address string) string {
+	return fmt.Sprintf("%s%s", getAccountStateHeightKeyPrefix(height), address)
+}
+
+func getAccountStateHeightKeyPrefix(height uint64) string {
+	return fmt.Sprintf("%s%020d", AccountStateHeightPrefix, height)
+}
+
 type Potion struct {
 	s *leveldbstorage.Storage
 }
@@ -63,7 +128,7 @@ func NewPotion(s *leveldbstorage.Storage) Potion {
 }
 
 func (g Potion) Check() error {
-	return nil
+	return migrations.Check(g.s)
 }
 
 func (g Potion) Storage() storage.Storage {
@@ -76,6 +141,10 @@ func (g Potion) Account(address string) (element.Account, error) {
 	return ac, err
 }
 
+func (g Potion) AccountAt(address string, height uint64) (element.Account, error) {
+	return element.GetAccountAt(g.s, address, height)
+}
+
 func (g Potion) Accounts(sort string, options storage.ListOptions) (
 	func() (element.Account, bool, []byte),
 	func(),
@@ -131,14 +200,20 @@ func (g Potion) BlocksByHeight(start, end uint64) (
 	func() (element.Block, bool, []byte),
 	func(),
 ) {
+	// NOTE the cursor is excluded, so the iterator starts after `start - 1`
+	var cursor []byte
+	if start > 0 {
+		cursor = []byte(GetBlockHeightKey(start - 1))
+	}
+
 	iterFunc, closeFunc, err := g.Storage().Iterator(
 		BlockHeightPrefix,
 		"",
-		storage.NewDefaultListOptions(false, []byte(GetBlockHeightKey(start)), 0),
+		storage.NewDefaultListOptions(false, cursor, 0),
 	)
 
 	if err != nil {
-		return nil, nil
+		return func() (element.Block, bool, []byte) { return element.Block{}, false, nil }, func() {}
 	}
 
 	return (func() (element.Block, bool, []byte) {
@@ -153,7 +228,7 @@ func (g Potion) BlocksByHeight(start, end uint64) (
 			}
 
 			b, err := g.Block(hash)
-			if err != nil {
+			if err != nil || b.Header.Height >= end {
 				return element.Block{}, false, []byte{}
 			}
 
@@ -199,6 +274,129 @@ func (g Potion) OperationsByAccount(address string, options storage.ListOptions)
 		})
 }
 
+// OperationsByAccountFilter selects the secondary keys by the filter; the
+// counterparty keys or the type keys are used if possible. The block height is
+// checked by the key, so only the operations in the height range are loaded.
+func (g Potion) OperationsByAccountFilter(address string, filter element.OperationFilter, options storage.ListOptions) (
+	func() (element.Operation, bool, []byte),
+	func(),
+) {
+	var prefix string
+	var heightKey func(uint64) string
+	switch {
+	case len(filter.Counterparty) > 0:
+		prefix = getOperationAccountCounterpartyPrefix(address, filter.Counterparty)
+		heightKey = func(h uint64) string {
+			return getOperationAccountCounterpartyKeyPrefix(address, filter.Counterparty, h)
+		}
+	case len(filter.Types) == 1:
+		prefix = getOperationAccountTypePrefix(address, filter.Types[0])
+		heightKey = func(h uint64) string {
+			return getOperationAccountTypeKeyPrefix(address, filter.Types[0], h)
+		}
+	default:
+		prefix = fmt.Sprintf("%s%s", element.OperationAccountRelatedPrefix, address)
+		heightKey = func(h uint64) string {
+			return getOperationAccountRelatedKeyPrefix(address, h)
+		}
+	}
+
+	var reverse bool
+	var cursor []byte
+	var limit uint64
+	if options != nil {
+		reverse = options.Reverse()
+		cursor = options.Cursor()
+		limit = options.Limit()
+	}
+
+	// NOTE without cursor, the iteration starts from the height of filter
+	// instead of scanning the lower heights.
+	if cursor == nil {
+		if !reverse && filter.FromHeight > 0 {
+			cursor = g.lastKeyBefore(prefix, heightKey(filter.FromHeight))
+		} else if reverse && filter.ToHeight > 0 {
+			cursor = []byte(heightKey(filter.ToHeight + 1))
+		}
+	}
+
+	// NOTE the limit is applied after filtering
+	iterFunc, closeFunc, err := g.s.Iterator(prefix, "", storage.NewDefaultListOptions(reverse, cursor, 0))
+	if err != nil {
+		return func() (element.Operation, bool, []byte) {
+				return element.Operation{}, false, nil
+			},
+			func() {}
+	}
+
+	var n uint64
+	return (func() (element.Operation, bool, []byte) {
+			for {
+				if limit > 0 && n >= limit {
+					return element.Operation{}, false, nil
+				}
+
+				it, next, err := iterFunc()
+				if err != nil || !next {
+					return element.Operation{}, false, []byte(it.Key)
+				}
+
+				// NOTE the block height follows the prefix in 20 digits
+				if len(it.Key) < len(prefix)+20 {
+					continue
+				}
+				height, err := strconv.ParseUint(strings.TrimSpace(it.Key[len(prefix):len(prefix)+20]), 10, 64)
+				if err != nil {
+					continue
+				}
+
+				if !filter.MatchHeight(height) {
+					if (!reverse && filter.ToHeight > 0 && height > filter.ToHeight) ||
+						(reverse && height < filter.FromHeight) {
+						return element.Operation{}, false, []byte(it.Key)
+					}
+					continue
+				}
+
+				hash, ok := it.Value.(string)
+				if !ok {
+					return element.Operation{}, false, []byte(it.Key)
+				}
+
+				o, err := g.Operation(hash)
+				if err != nil {
+					return element.Operation{}, false, []byte(it.Key)
+				}
+				if !filter.Match(address, o) {
+					continue
+				}
+
+				n++
+				return o, true, []byte(it.Key)
+			}
+		}), (func() {
+			closeFunc()
+		})
+}
+
+// lastKeyBefore returns the last key, which is less than `key` under
+// `prefix`; the cursor is skipped by the forward iteration, so the cursor for
+// starting at `key` should be the key before it. If not found, returns nil.
+func (g Potion) lastKeyBefore(prefix, key string) []byte {
+	iterFunc, closeFunc, err := g.s.IteratorRaw(prefix, storage.NewDefaultListOptions(true, []byte(key), 1))
+	if err != nil {
+		return nil
+	}
+	defer closeFunc()
+
+	item, next, err := iterFunc()
+	if err != nil || !next {
+		return nil
+	}
+
+	return item.Key
+}
+
 func (g Potion) ExistsTransaction(hash string) (bool, error) {
 	return g.s.Has(element.GetTransactionKey(hash))
 }
@@ -230,28 +428,397 @@ func (g Potion) TransactionsByBlock(hash string, options storage.ListOptions) (
 	func() (element.Transaction, bool, []byte),
 	func(),
 ) {
-	return func() (element.Transaction, bool, []byte) {
-			return element.Transaction{}, false, nil
-		},
-		func() {}
+	block, err := g.Block(hash)
+	if err != nil {
+		return func() (element.Transaction, bool, []byte) {
+				return element.Transaction{}, false, nil
+			},
+			func() {}
+	}
+
+	// NOTE proposer transaction is not included like the other potions.
+	return g.transactionsByPrefix(getTransactionBlockKeyPrefix(block.Header.Height), block.ProposerTransaction, options)
 }
 
 func (g Potion) TransactionsByAccount(address string, options storage.ListOptions) (
 	func() (element.Transaction, bool, []byte),
 	func(),
 ) {
-	return func() (element.Transaction, bool, []byte) {
-			return element.Transaction{}, false, nil
-		},
-		func() {}
+	return g.transactionsByPrefix(fmt.Sprintf("%s%s", TransactionAccountsPrefix, address), "", options)
+}
+
+// transactionsByPrefix iterates the transactions by the secondary keys, which
+// have the transaction hash as value; the transaction of `exclude` is skipped.
+func (g Potion) transactionsByPrefix(prefix, exclude string, options storage.ListOptions) (
+	func() (element.Transaction, bool, []byte),
+	func(),
+) {
+	iterFunc, closeFunc, err := g.s.Iterator(prefix, "", options)
+	if err != nil {
+		return func() (element.Transaction, bool, []byte) {
+				return element.Transaction{}, false, nil
+			},
+			func() {}
+	}
+
+	var f func() (element.Transaction, bool, []byte)
+	f = func() (element.Transaction, bool, []byte) {
+		it, next, err := iterFunc()
+		if err != nil || !next {
+			return element.Transaction{}, false, []byte(it.Key)
+		}
+
+		hash, ok := it.Value.(string)
+		if !ok {
+			return element.Transaction{}, false, []byte(it.Key)
+		}
+		if len(exclude) > 0 && hash == exclude {
+			return f()
+		}
+
+		tx, err := g.Transaction(hash)
+		if err != nil {
+			return element.Transaction{}, false, []byte(it.Key)
+		}
+
+		return tx, next, []byte(it.Key)
+	}
+
+	return f, func() {
+		closeFunc()
+	}
 }
 
 func (g Potion) OperationsByTransaction(hash string, options storage.ListOptions) (
 	func() (element.Operation, bool, []byte),
 	func(),
 ) {
-	return func() (element.Operation, bool, []byte) {
-			return element.Operation{}, false, nil
-		},
-		func() {}
+	// NOTE the operation keys of transaction are ordered by the operation
+	// index.
+	iterFunc, closeFunc, err := g.s.Iterator(element.GetOperationKey(hash), element.Operation{}, options)
+	if err != nil {
+		return func() (element.Operation, bool, []byte) {
+				return element.Operation{}, false, nil
+			},
+			func() {}
+	}
+
+	return (func() (element.Operation, bool, []byte) {
+			it, next, err := iterFunc()
+			if err != nil || !next {
+				return element.Operation{}, false, []byte(it.Key)
+			}
+
+			o, ok := it.Value.(element.Operation)
+			if !ok {
+				return element.Operation{}, false, []byte(it.Key)
+			}
+
+			return o, next, []byte(it.Key)
+		}), (func() {
+			closeFunc()
+		})
+}
+
+// FindMaxRecords is the maximum number of matched records, which Find keeps in
+// memory for sorting.
+var FindMaxRecords = 10000
+
+// Find evaluates the query in memory, because leveldb has no index for the
+// fields; all the elements of collection are read and the matched ones are
+// sorted, so it fits only for the small storage. If more than
+// `FindMaxRecords` are matched, Find fails with TooManySearchRecords.
+func (g Potion) Find(collection string, q query.Query, sortField string, options storage.ListOptions) (
+	func() (interface{}, bool, []byte),
+	func(),
+	error,
+) {
+	c, q, sortField, err := element.PrepareSearch(collection, q, sortField)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	iterFunc, closeFunc, err := g.s.Iterator(c.Prefix, c.Element, storage.NewDefaultListOptions(false, nil, 0))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeFunc()
+
+	var found []interface{}
+	for {
+		it, next, err := iterFunc()
+		if err != nil {
+			return nil, nil, err
+		} else if !next {
+			break
+		}
+
+		if matched, err := c.Match(q, it.Value); err != nil {
+			return nil, nil, err
+		} else if !matched {
+			continue
+		}
+
+		if len(found) >= FindMaxRecords {
+			return nil, nil, element.TooManySearchRecords.New().SetData("max", FindMaxRecords)
+		}
+		found = append(found, it.Value)
+	}
+
+	var reverse bool
+	var cursor []byte
+	var limit uint64
+	if options != nil {
+		reverse = options.Reverse()
+		cursor = options.Cursor()
+		limit = options.Limit()
+	}
+
+	sortValue := c.Fields[sortField].Value
+	less := func(a, b interface{}) bool {
+		n, _ := query.Compare(sortValue(a), sortValue(b))
+		if n == 0 {
+			return string(c.Cursor(a)) < string(c.Cursor(b))
+		}
+		return n < 0
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if reverse {
+			return less(found[j], found[i])
+		}
+		return less(found[i], found[j])
+	})
+
+	if len(cursor) > 0 {
+		var i int
+		for i = 0; i < len(found); i++ {
+			if string(c.Cursor(found[i])) == string(cursor) {
+				break
+			}
+		}
+
+		if i < len(found) {
+			found = found[i+1:]
+		} else {
+			found = nil
+		}
+	}
+
+	if limit > 0 && uint64(len(found)) > limit {
+		found = found[:limit]
+	}
+
+	var n int
+	return func() (interface{}, bool, []byte) {
+		if n >= len(found) {
+			return nil, false, nil
+		}
+
+		v := found[n]
+		n++
+
+		return v, true, c.Cursor(v)
+	}, func() {}, nil
+}
+
+// Rollback removes the blocks above the given height with their
+// transactions, operations and the secondary keys. Blocks are removed from the
+// top one by one, so the interrupted rollback still leaves the continuous
+// blocks.
+func (g Potion) Rollback(height uint64) error {
+	last, err := g.LastBlock()
+	if err != nil {
+		if err == sebakerrors.StorageRecordDoesNotExist {
+			return nil
+		}
+		return err
+	}
+
+	for h := last.Header.Height; h > height; h-- {
+		if found, err := g.s.Has(GetBlockHeightKey(h)); err != nil {
+			return err
+		} else if !found {
+			continue
+		}
+
+		block, err := g.BlockByHeight(h)
+		if err != nil {
+			return err
+		}
+
+		if err := g.rollbackBlock(block); err != nil {
+			log.Error("failed to rollback block", "block", h, "error", err)
+			return err
+		}
+		log.Debug("block rolled back", "block", h, "hash", block.Hash)
+	}
+
+	return nil
+}
+
+func (g Potion) rollbackBlock(block element.Block) error {
+	batch, err := g.s.Batch()
+	if err != nil {
+		return err
+	}
+	defer batch.Close()
+
+	height := block.Header.Height
+
+	var hashes []string
+	hashes = append(hashes, block.Transactions...)
+	hashes = append(hashes, block.ProposerTransaction)
+
+	reverter := element.NewReverter()
+	for _, hash := range hashes {
+		if len(hash) < 1 {
+			continue
+		}
+		if err := g.rollbackTransaction(batch, reverter, hash, height); err != nil {
+			return err
+		}
+	}
+
+	if err := reverter.Revert(batch); err != nil {
+		return err
+	}
+
+	if err := g.deleteByPrefix(batch, getTransactionBlockKeyPrefix(height)); err != nil {
+		return err
+	}
+	if err := g.rollbackAccountStates(batch, height); err != nil {
+		return err
+	}
+	if err := batch.Delete(GetBlockHeightKey(height)); err != nil {
+		return err
+	}
+	if err := batch.Delete(element.GetBlockKey(block.Hash)); err != nil {
+		return err
+	}
+
+	return batch.Write()
+}
+
+func (g Potion) rollbackTransaction(st storage.Storage, reverter *element.Reverter, hash string, height uint64) error {
+	if found, err := g.s.Has(element.GetTransactionKey(hash)); err != nil {
+		return err
+	} else if !found {
+		return nil
+	}
+
+	tx, err := g.Transaction(hash)
+	if err != nil {
+		return err
+	}
+
+	var operations []element.Operation
+	addresses := map[string]struct{}{tx.Source: struct{}{}}
+	for i := range tx.Operations {
+		key := element.GetOperationKey(element.GetOperationHash(hash, uint64(i)))
+		if found, err := g.s.Has(key); err != nil {
+			return err
+		} else if !found {
+			continue
+		}
+
+		var op element.Operation
+		if err := g.s.Get(key, &op); err != nil {
+			return err
+		}
+		operations = append(operations, op)
+
+		for _, address := range []string{op.Source, op.Target} {
+			if len(address) < 1 {
+				continue
+			}
+			addresses[address] = struct{}{}
+
+			if err := g.deleteByPrefix(st, getOperationAccountRelatedKeyPrefix(address, height)); err != nil {
+				return err
+			}
+			if err := g.deleteByPrefix(st, getOperationAccountTypeKeyPrefix(address, op.Type, height)); err != nil {
+				return err
+			}
+		}
+
+		if len(op.Target) > 0 && op.Target != op.Source {
+			if err := g.deleteByPrefix(st, getOperationAccountCounterpartyKeyPrefix(op.Source, op.Target, height)); err != nil {
+				return err
+			}
+			if err := g.deleteByPrefix(st, getOperationAccountCounterpartyKeyPrefix(op.Target, op.Source, height)); err != nil {
+				return err
+			}
+		}
+
+		if err := st.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	for address := range addresses {
+		if err := g.deleteByPrefix(st, getTransactionAccountsKeyPrefix(address, height)); err != nil {
+			return err
+		}
+	}
+
+	if err := g.deleteByPrefix(st, getTransactionSourceKeyPrefix(tx.Source, height)); err != nil {
+		return err
+	}
+
+	reverter.Add(tx, operations...)
+
+	return st.Delete(element.GetTransactionKey(hash))
+}
+
+func (g Potion) rollbackAccountStates(st storage.Storage, height uint64) error {
+	iterFunc, closeFunc, err := g.s.Iterator(getAccountStateHeightKeyPrefix(height), "", nil)
+	if err != nil {
+		return err
+	}
+	defer closeFunc()
+
+	for {
+		it, next, err := iterFunc()
+		if err != nil {
+			return err
+		} else if !next {
+			break
+		}
+
+		if key, ok := it.Value.(string); ok {
+			if err := st.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		if err := st.Delete(it.Key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g Potion) deleteByPrefix(st storage.Storage, prefix string) error {
+	iterFunc, closeFunc, err := g.s.IteratorRaw(prefix, nil)
+	if err != nil {
+		return err
+	}
+	defer closeFunc()
+
+	for {
+		item, next, err := iterFunc()
+		if err != nil {
+			return err
+		} else if !next {
+			break
+		}
+
+		if err := st.Delete(string(item.Key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
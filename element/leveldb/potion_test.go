@@ -0,0 +1,107 @@
+package leveldbelement
+
+import (
+	"testing"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/storage"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+const (
+	testAddressA = "GDIRF4UWPACXPPI4GW7CMTACTCNDIKJEHZK44RITZB4TD3YUM6CCVNGJ"
+	testAddressB = "GBAQPF5DEMUGMAKAGCOUOGI44O3BGANNJQVH5DCPR47NGCG7FFNPNXIU"
+	testAddressC = "GCSHV3WXWWM5LVRVQQOC3EGF6CBAW4ANHLA5TV6UYGGCVNNQQX4DXFFC"
+)
+
+type testOperationsByAccountFilter struct {
+	suite.Suite
+	s *leveldbstorage.Storage
+	p Potion
+}
+
+func (t *testOperationsByAccountFilter) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+	t.p = NewPotion(s)
+
+	for _, op := range []element.Operation{
+		{Hash: "op0", Type: sebakoperation.TypeCreateAccount, Source: testAddressA, Target: testAddressB, Amount: 100, Block: 1},
+		{Hash: "op1", Type: sebakoperation.TypePayment, Source: testAddressA, Target: testAddressB, Amount: 10, Block: 2},
+		{Hash: "op2", Type: sebakoperation.TypePayment, Source: testAddressA, Target: testAddressC, Amount: 20, Block: 3},
+		{Hash: "op3", Type: sebakoperation.TypePayment, Source: testAddressB, Target: testAddressA, Amount: 30, Block: 4},
+		{Hash: "op4", Type: sebakoperation.TypePayment, Source: testAddressC, Target: testAddressB, Amount: 40, Block: 5},
+	} {
+		t.NoError(s.Insert(element.GetOperationKey(op.Hash), op))
+		OnAfterSaveOperation(s, op)
+	}
+}
+
+func (t *testOperationsByAccountFilter) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testOperationsByAccountFilter) hashes(address string, filter element.OperationFilter, options storage.ListOptions) []string {
+	iterFunc, closeFunc := t.p.OperationsByAccountFilter(address, filter, options)
+	defer closeFunc()
+
+	var hashes []string
+	for {
+		op, next, _ := iterFunc()
+		if !next {
+			break
+		}
+		hashes = append(hashes, op.Hash)
+	}
+
+	return hashes
+}
+
+func (t *testOperationsByAccountFilter) TestType() {
+	filter := element.OperationFilter{Types: []sebakoperation.OperationType{sebakoperation.TypePayment}}
+	t.Equal([]string{"op1", "op2", "op3"}, t.hashes(testAddressA, filter, nil))
+}
+
+func (t *testOperationsByAccountFilter) TestAmount() {
+	filter := element.OperationFilter{MinAmount: sebakcommon.Amount(20), MaxAmount: sebakcommon.Amount(30)}
+	t.Equal([]string{"op2", "op3"}, t.hashes(testAddressA, filter, nil))
+}
+
+func (t *testOperationsByAccountFilter) TestCounterparty() {
+	filter := element.OperationFilter{Counterparty: testAddressB}
+	t.Equal([]string{"op0", "op1", "op3"}, t.hashes(testAddressA, filter, nil))
+	t.Equal([]string{"op3", "op1", "op0"}, t.hashes(testAddressA, filter, storage.NewDefaultListOptions(true, nil, 0)))
+}
+
+func (t *testOperationsByAccountFilter) TestHeight() {
+	filter := element.OperationFilter{FromHeight: 2, ToHeight: 4}
+	t.Equal([]string{"op1", "op3"}, t.hashes(testAddressB, filter, nil))
+	t.Equal([]string{"op3", "op1"}, t.hashes(testAddressB, filter, storage.NewDefaultListOptions(true, nil, 0)))
+
+	// NOTE the iteration starts from the height without the lower keys
+	t.Equal([]string{"op0", "op1"}, t.hashes(testAddressB, element.OperationFilter{FromHeight: 1, ToHeight: 2}, nil))
+	t.Equal([]string{"op4"}, t.hashes(testAddressB, element.OperationFilter{FromHeight: 5}, nil))
+	t.Equal(
+		[]string{"op1", "op3"},
+		t.hashes(testAddressB, element.OperationFilter{FromHeight: 2, Counterparty: testAddressA}, nil),
+	)
+}
+
+func (t *testOperationsByAccountFilter) TestLimit() {
+	filter := element.OperationFilter{Types: []sebakoperation.OperationType{sebakoperation.TypePayment}}
+	t.Equal([]string{"op1", "op2"}, t.hashes(testAddressA, filter, storage.NewDefaultListOptions(false, nil, 2)))
+}
+
+func TestOperationsByAccountFilter(t *testing.T) {
+	suite.Run(t, new(testOperationsByAccountFilter))
+}
@@ -1,9 +1,12 @@
 package leveldbelement
 
 const (
-	BlockHeightPrefix         = "1001"
-	TransactionSourcePrefix   = "2001"
-	TransactionAccountsPrefix = "2002"
-	TransactionBlockPrefix    = "2003"
-	AccountPrefix             = "3000" // account
+	BlockHeightPrefix                  = "1001"
+	TransactionSourcePrefix            = "2001"
+	TransactionAccountsPrefix          = "2002"
+	TransactionBlockPrefix             = "2003"
+	AccountPrefix                      = "3000" // account
+	AccountStateHeightPrefix           = "3101"
+	OperationAccountTypePrefix         = "4011"
+	OperationAccountCounterpartyPrefix = "4012"
 )
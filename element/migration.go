@@ -0,0 +1,270 @@
+package element
+
+import (
+	"fmt"
+	"sort"
+
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+
+	"github.com/spikeekips/naru/storage"
+)
+
+// Migration upgrades the stored elements to the `Version`. `Run` reads the
+// current elements from potion and writes the changes to `st`, which is the
+// batch of the potion storage.
+type Migration struct {
+	Version     uint64
+	Description string
+	Run         func(potion Potion, st storage.Storage) error
+}
+
+// Migrations is the ordered migrations of one storage backend. The version of
+// the last migration is the latest schema version.
+type Migrations struct {
+	l []Migration
+}
+
+func NewMigrations(migrations ...Migration) *Migrations {
+	l := make([]Migration, len(migrations))
+	copy(l, migrations)
+
+	sort.SliceStable(l, func(i, j int) bool { return l[i].Version < l[j].Version })
+
+	for i, m := range l {
+		if m.Version != uint64(i+1) {
+			panic(InvalidMigration.New().SetData("version", m.Version).SetData("expected", i+1))
+		}
+		if m.Run == nil {
+			panic(InvalidMigration.New().SetData("version", m.Version).SetData("error", "empty Run"))
+		}
+	}
+
+	return &Migrations{l: l}
+}
+
+func (m *Migrations) Latest() uint64 {
+	if len(m.l) < 1 {
+		return 0
+	}
+
+	return m.l[len(m.l)-1].Version
+}
+
+// Pending returns the migrations, which are newer than the given version.
+func (m *Migrations) Pending(version uint64) []Migration {
+	var l []Migration
+	for _, i := range m.l {
+		if i.Version <= version {
+			continue
+		}
+		l = append(l, i)
+	}
+
+	return l
+}
+
+// Version returns the schema version of storage. The empty storage without
+// Schema is regarded as the latest version.
+func (m *Migrations) Version(st storage.Storage) (uint64, error) {
+	schema, found, err := GetSchema(st)
+	if err != nil {
+		return 0, err
+	} else if found {
+		return schema.Version, nil
+	}
+
+	if empty, err := isEmptyStorage(st); err != nil {
+		return 0, err
+	} else if empty {
+		return m.Latest(), nil
+	}
+
+	return 0, nil
+}
+
+// Check refuses the storage, which is written by the newer version or is not
+// migrated yet. The new storage is marked with the latest version.
+func (m *Migrations) Check(st storage.Storage) error {
+	version, err := m.Version(st)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version > m.Latest():
+		return SchemaVersionNewer.New().SetData("version", version).SetData("latest", m.Latest())
+	case version < m.Latest():
+		return SchemaNotMigrated.New().SetData("version", version).SetData("latest", m.Latest())
+	}
+
+	if _, found, err := GetSchema(st); err != nil {
+		return err
+	} else if !found {
+		return NewSchema(version).Save(st)
+	}
+
+	return nil
+}
+
+// Migrate runs the pending migrations in order. Each migration is written with
+// the new Schema in one batch, so the interrupted migration can be run again.
+// With `dryRun`, only the pending migrations are returned.
+func (m *Migrations) Migrate(potion Potion, dryRun bool) ([]Migration, error) {
+	version, err := m.Version(potion.Storage())
+	if err != nil {
+		return nil, err
+	} else if version > m.Latest() {
+		return nil, SchemaVersionNewer.New().SetData("version", version).SetData("latest", m.Latest())
+	}
+
+	pending := m.Pending(version)
+	if dryRun {
+		return pending, nil
+	}
+
+	for i, migration := range pending {
+		if err := runMigration(potion, migration); err != nil {
+			return pending[:i], err
+		}
+	}
+
+	if _, found, err := GetSchema(potion.Storage()); err != nil {
+		return pending, err
+	} else if !found {
+		if err := NewSchema(version).Save(potion.Storage()); err != nil {
+			return pending, err
+		}
+	}
+
+	return pending, nil
+}
+
+func runMigration(potion Potion, migration Migration) error {
+	batch, err := potion.Storage().Batch()
+	if err != nil {
+		return err
+	}
+	defer batch.Close()
+
+	if err := migration.Run(potion, batch); err != nil {
+		return err
+	}
+
+	if err := NewSchema(migration.Version).Save(batch); err != nil {
+		return err
+	}
+
+	return batch.Write()
+}
+
+// MigrationAccountCreatedBlock fills the empty `Account.CreatedBlock` of the
+// accounts, which were stored before `CreatedBlock` was added, from the
+// CreateAccount operations.
+var MigrationAccountCreatedBlock = func(potion Potion, st storage.Storage) error {
+	iterFunc, closeFunc, err := potion.Storage().Iterator(OperationPrefix, Operation{}, storage.NewDefaultListOptions(false, nil, 0))
+	if err != nil {
+		return err
+	}
+
+	created := map[string]uint64{}
+	for {
+		record, next, err := iterFunc()
+		if err != nil {
+			closeFunc()
+			return err
+		} else if !next {
+			break
+		}
+
+		op := record.Value.(Operation)
+		if op.Type != sebakoperation.TypeCreateAccount || len(op.Target) < 1 {
+			continue
+		}
+		created[op.Target] = op.Block
+	}
+	closeFunc()
+
+	for address, height := range created {
+		ac, err := potion.Account(address)
+		if err != nil {
+			if storage.NotFound.Equal(err) {
+				continue
+			}
+			return err
+		}
+
+		if ac.CreatedBlock > 0 {
+			continue
+		}
+
+		ac.CreatedBlock = height
+		if err := st.Update(GetAccountKey(address), ac); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrationAccountState saves the AccountState of the all accounts at the last
+// block, so `AccountAt()` works for the storage, which was stored before
+// AccountState was added. The account states are available from the last
+// block.
+var MigrationAccountState = func(potion Potion, st storage.Storage) error {
+	last, err := potion.LastBlock()
+	if err != nil {
+		return err
+	}
+
+	if err := SaveAccountStateSince(st, last.Header.Height); err != nil {
+		return err
+	}
+
+	iterFunc, closeFunc, err := potion.Storage().Iterator(AccountPrefix, Account{}, storage.NewDefaultListOptions(false, nil, 0))
+	if err != nil {
+		return err
+	}
+	defer closeFunc()
+
+	for {
+		record, next, err := iterFunc()
+		if err != nil {
+			return err
+		} else if !next {
+			break
+		}
+
+		ac := record.Value.(Account)
+		if found, err := potion.Storage().Has(GetAccountStateKey(ac.Address, last.Header.Height)); err != nil {
+			return err
+		} else if found {
+			continue
+		}
+
+		if err := NewAccountState(ac, last.Header.Height).Save(st); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DefaultMigrations is the migrations, which all the storage backends share.
+func DefaultMigrations() []Migration {
+	return []Migration{
+		Migration{
+			Version:     1,
+			Description: "fill Account.CreatedBlock",
+			Run:         MigrationAccountCreatedBlock,
+		},
+		Migration{
+			Version:     2,
+			Description: "save AccountState of accounts at the last block",
+			Run:         MigrationAccountState,
+		},
+	}
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%d: %s", m.Version, m.Description)
+}
@@ -0,0 +1,136 @@
+package element
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/storage"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type testMigrationPotion struct {
+	Potion
+	s storage.Storage
+}
+
+func (p testMigrationPotion) Storage() storage.Storage {
+	return p.s
+}
+
+type testMigrations struct {
+	suite.Suite
+	s *leveldbstorage.Storage
+}
+
+func (t *testMigrations) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+}
+
+func (t *testMigrations) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testMigrations) newMigrations(ran *[]uint64) *Migrations {
+	run := func(version uint64) func(Potion, storage.Storage) error {
+		return func(Potion, storage.Storage) error {
+			*ran = append(*ran, version)
+			return nil
+		}
+	}
+
+	return NewMigrations(
+		Migration{Version: 2, Description: "second", Run: run(2)},
+		Migration{Version: 1, Description: "first", Run: run(1)},
+	)
+}
+
+func (t *testMigrations) TestInvalidVersion() {
+	run := func(Potion, storage.Storage) error { return nil }
+
+	t.Panics(func() {
+		NewMigrations(Migration{Version: 1, Run: run}, Migration{Version: 3, Run: run})
+	})
+	t.Panics(func() {
+		NewMigrations(Migration{Version: 1, Run: run}, Migration{Version: 1, Run: run})
+	})
+}
+
+func (t *testMigrations) TestEmptyStorage() {
+	var ran []uint64
+	migrations := t.newMigrations(&ran)
+	t.Equal(uint64(2), migrations.Latest())
+
+	t.NoError(migrations.Check(t.s))
+
+	schema, found, err := GetSchema(t.s)
+	t.NoError(err)
+	t.True(found)
+	t.Equal(uint64(2), schema.Version)
+	t.Empty(ran)
+}
+
+func (t *testMigrations) TestNotMigrated() {
+	var ran []uint64
+	migrations := t.newMigrations(&ran)
+
+	// storage without schema
+	t.NoError(Block{Hash: "showme", Header: BlockHeader{Height: 1}}.Save(t.s))
+
+	err := migrations.Check(t.s)
+	t.True(SchemaNotMigrated.Equal(err))
+
+	potion := testMigrationPotion{s: t.s}
+
+	pending, err := migrations.Migrate(potion, true)
+	t.NoError(err)
+	t.Equal(2, len(pending))
+	t.Empty(ran)
+
+	pending, err = migrations.Migrate(potion, false)
+	t.NoError(err)
+	t.Equal(2, len(pending))
+	t.Equal([]uint64{1, 2}, ran)
+
+	t.NoError(migrations.Check(t.s))
+
+	pending, err = migrations.Migrate(potion, false)
+	t.NoError(err)
+	t.Empty(pending)
+}
+
+func (t *testMigrations) TestPartiallyMigrated() {
+	var ran []uint64
+	migrations := t.newMigrations(&ran)
+
+	t.NoError(NewSchema(1).Save(t.s))
+	t.True(SchemaNotMigrated.Equal(migrations.Check(t.s)))
+
+	pending, err := migrations.Migrate(testMigrationPotion{s: t.s}, false)
+	t.NoError(err)
+	t.Equal(1, len(pending))
+	t.Equal([]uint64{2}, ran)
+}
+
+func (t *testMigrations) TestNewerVersion() {
+	var ran []uint64
+	migrations := t.newMigrations(&ran)
+
+	t.NoError(NewSchema(3).Save(t.s))
+	t.True(SchemaVersionNewer.Equal(migrations.Check(t.s)))
+
+	_, err := migrations.Migrate(testMigrationPotion{s: t.s}, false)
+	t.True(SchemaVersionNewer.Equal(err))
+	t.Empty(ran)
+}
+
+func TestMigrations(t *testing.T) {
+	suite.Run(t, new(testMigrations))
+}
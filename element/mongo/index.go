@@ -93,6 +93,24 @@ var allIndexes = map[string][]mongo.IndexModel{
 				SetName("_naru_v0_transaction_source"),
 		},
 	},
+	element.AccountStatePrefix: []mongo.IndexModel{
+		mongo.IndexModel{
+			Keys: bson.M{"_k": 1},
+			Options: mongooptions.Index().
+				SetUnique(true).
+				SetName("_naru_v0_accountstate_k"),
+		},
+		mongo.IndexModel{
+			Keys: bson.M{"_v.account.address": 1, "_v.height": -1},
+			Options: mongooptions.Index().
+				SetName("_naru_v0_accountstate_address_height"),
+		},
+		mongo.IndexModel{
+			Keys: bson.M{"_v.height": 1},
+			Options: mongooptions.Index().
+				SetName("_naru_v0_accountstate_height"),
+		},
+	},
 	element.OperationPrefix: []mongo.IndexModel{
 		mongo.IndexModel{
 			Keys: bson.M{"_k": 1},
@@ -116,5 +134,35 @@ var allIndexes = map[string][]mongo.IndexModel{
 			Options: mongooptions.Index().
 				SetName("_naru_v0_operation_txhash_hash"),
 		},
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "_v.source", Value: 1}, {Key: "_v.block", Value: 1}, {Key: "_v.hash", Value: 1}},
+			Options: mongooptions.Index().
+				SetName("_naru_v0_operation_source_block_hash"),
+		},
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "_v.target", Value: 1}, {Key: "_v.block", Value: 1}, {Key: "_v.hash", Value: 1}},
+			Options: mongooptions.Index().
+				SetName("_naru_v0_operation_target_block_hash"),
+		},
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "_v.source", Value: 1}, {Key: "_v.type", Value: 1}, {Key: "_v.block", Value: 1}},
+			Options: mongooptions.Index().
+				SetName("_naru_v0_operation_source_type_block"),
+		},
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "_v.target", Value: 1}, {Key: "_v.type", Value: 1}, {Key: "_v.block", Value: 1}},
+			Options: mongooptions.Index().
+				SetName("_naru_v0_operation_target_type_block"),
+		},
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "_v.source", Value: 1}, {Key: "_v.target", Value: 1}, {Key: "_v.block", Value: 1}},
+			Options: mongooptions.Index().
+				SetName("_naru_v0_operation_source_target_block"),
+		},
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "_v.type", Value: 1}, {Key: "_v.amount", Value: 1}},
+			Options: mongooptions.Index().
+				SetName("_naru_v0_operation_type_amount"),
+		},
 	},
 }
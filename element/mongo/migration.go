@@ -0,0 +1,15 @@
+package mongoelement
+
+import (
+	"github.com/spikeekips/naru/element"
+)
+
+// migrations is the ordered migrations of mongodb storage; the new migration
+// should be appended with the next version.
+var migrations = element.NewMigrations(
+	element.DefaultMigrations()...,
+)
+
+func (g Potion) Migrations() *element.Migrations {
+	return migrations
+}
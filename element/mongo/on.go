@@ -20,8 +20,11 @@ func EventSync() {
 func OnAfterSaveAccount(st storage.Storage, account element.Account, created bool) {
 }
 
+// OnAfterDigest updates BlockStat and Account.Created by the operations of
+// digested blocks. Like the digest, the range is `(start, end]`; `start` is
+// the already digested block, so it should not be counted again.
 func OnAfterDigest(potion element.Potion, start, end uint64) {
-	iterFunc, closeFunc := potion.OperationsByHeight(start, end+1)
+	iterFunc, closeFunc := potion.OperationsByHeight(start+1, end+1)
 	defer closeFunc()
 
 	var createdAccounts []element.Operation
@@ -0,0 +1,75 @@
+package mongoelement
+
+import (
+	"testing"
+
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+	"github.com/stretchr/testify/suite"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	mongostorage "github.com/spikeekips/naru/storage/backend/mongo"
+)
+
+type testOnAfterDigest struct {
+	suite.Suite
+	s            *mongostorage.Storage
+	p            Potion
+	databaseName string
+}
+
+func (t *testOnAfterDigest) SetupTest() {
+	t.databaseName = common.SequentialUUID()
+
+	c := &config.MongoStorage{
+		URI: mongooptions.Client().ApplyURI("mongodb://localhost:27017"),
+		DB:  t.databaseName,
+	}
+
+	s, err := mongostorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+	t.p = NewPotion(s)
+}
+
+func (t *testOnAfterDigest) TearDownTest() {
+	if t.s == nil {
+		return
+	}
+
+	if err := t.s.Core().Database(t.databaseName, nil).Drop(nil); err != nil {
+		log.Error("failed to drop db", "error", err)
+	}
+}
+
+// TestConsecutiveRanges digests `(0, 2]` and `(2, 3]`; the block 2 is counted
+// only once.
+func (t *testOnAfterDigest) TestConsecutiveRanges() {
+	for _, op := range []element.Operation{
+		{Hash: "op1", Type: sebakoperation.TypeCreateAccount, Amount: 100, Block: 1},
+		{Hash: "op2", Type: sebakoperation.TypeInflation, Amount: 10, Block: 2},
+		{Hash: "op3", Type: sebakoperation.TypeInflation, Amount: 1, Block: 3},
+		{Hash: "op4", Type: sebakoperation.TypePayment, Amount: 1000, Block: 3},
+	} {
+		t.NoError(t.s.Insert(element.GetOperationKey(op.Hash), op))
+	}
+
+	OnAfterDigest(t.p, 0, 2)
+
+	bs, err := t.p.BlockStat()
+	t.NoError(err)
+	t.Equal(uint64(110), bs.TotalSupply)
+
+	OnAfterDigest(t.p, 2, 3)
+
+	bs, err = t.p.BlockStat()
+	t.NoError(err)
+	t.Equal(uint64(111), bs.TotalSupply)
+}
+
+func TestOnAfterDigest(t *testing.T) {
+	suite.Run(t, new(testOnAfterDigest))
+}
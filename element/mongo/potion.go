@@ -2,14 +2,18 @@ package mongoelement
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"strings"
 
 	logging "github.com/inconshreveable/log15"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/query"
 	"github.com/spikeekips/naru/storage"
 	mongostorage "github.com/spikeekips/naru/storage/backend/mongo"
 )
@@ -118,11 +122,16 @@ func (g Potion) checkOne(prefix string, dryRun bool) error {
 }
 
 func (g Potion) Check() error {
+	if err := migrations.Check(g.s); err != nil {
+		return err
+	}
+
 	prefixes := []string{
 		element.InternalPrefix,
 		element.BlockPrefix,
 		element.TransactionPrefix,
 		element.AccountPrefix,
+		element.AccountStatePrefix,
 		element.OperationPrefix,
 	}
 
@@ -145,6 +154,10 @@ func (g Potion) Account(address string) (element.Account, error) {
 	return ac, err
 }
 
+func (g Potion) AccountAt(address string, height uint64) (element.Account, error) {
+	return element.GetAccountAt(g.s, address, height)
+}
+
 func (g Potion) Accounts(sort string, options storage.ListOptions) (
 	func() (element.Account, bool, []byte),
 	func(),
@@ -204,7 +217,7 @@ func (g Potion) Accounts(sort string, options storage.ListOptions) (
 			b := []byte(cur.Current)
 			var account element.Account
 			_, err = mongostorage.UnmarshalDocument(b, &account)
-			return account, true, b
+			return account, true, []byte(account.Address)
 		},
 		func() {
 			cur.Close(context.Background())
@@ -227,7 +240,9 @@ func (g Potion) BlockByHeight(height uint64) (element.Block, error) {
 	}
 
 	r := col.FindOne(context.Background(), bson.M{"_v.header.height": height})
-	if err := r.Err(); err != nil {
+	if err := r.Err(); err == mongo.ErrNoDocuments {
+		return element.Block{}, storage.NotFound.New()
+	} else if err != nil {
 		return element.Block{}, err
 	}
 
@@ -317,7 +332,7 @@ func (g Potion) BlocksByHeight(start, end uint64) (
 			b := []byte(cur.Current)
 			var block element.Block
 			_, err = mongostorage.UnmarshalDocument(b, &block)
-			return block, true, b
+			return block, true, []byte(block.Hash)
 		},
 		func() {
 			cur.Close(context.Background())
@@ -389,13 +404,150 @@ func (g Potion) OperationsByAccount(address string, options storage.ListOptions)
 			b := []byte(cur.Current)
 			var operation element.Operation
 			_, err = mongostorage.UnmarshalDocument(b, &operation)
-			return operation, true, b
+			return operation, true, []byte(operation.Hash)
 		},
 		func() {
 			cur.Close(context.Background())
 		}
 }
 
+// OperationsByAccountFilter queries the operations with the filter; the
+// operations are sorted by block height and hash, and the cursor is the hash of
+// the last operation.
+func (g Potion) OperationsByAccountFilter(address string, filter element.OperationFilter, options storage.ListOptions) (
+	func() (element.Operation, bool, []byte),
+	func(),
+) {
+	nullIterFunc := func() (element.Operation, bool, []byte) {
+		return element.Operation{}, false, nil
+	}
+	nullCloseFunc := func() {}
+
+	col, err := g.s.Collection(element.OperationPrefix)
+	if err != nil {
+		return nullIterFunc, nullCloseFunc
+	}
+
+	q, err := operationFilterQuery(address, filter)
+	if err != nil {
+		log.Error("invalid operation filter", "filter", filter, "error", err)
+		return nullIterFunc, nullCloseFunc
+	}
+
+	var reverse bool
+	var cursor []byte
+	var limit uint64
+	if options != nil {
+		reverse = options.Reverse()
+		cursor = options.Cursor()
+		limit = options.Limit()
+	}
+
+	if len(cursor) > 0 {
+		last, err := g.Operation(string(cursor))
+		if err != nil {
+			return nullIterFunc, nullCloseFunc
+		}
+
+		dir := "$gt"
+		if reverse {
+			dir = "$lt"
+		}
+
+		q = append(q, bson.M{"$or": bson.A{
+			bson.M{"_v.block": bson.M{dir: last.Block}},
+			bson.M{"_v.block": last.Block, "_v.hash": bson.M{dir: last.Hash}},
+		}})
+	}
+
+	order := 1
+	if reverse {
+		order = -1
+	}
+
+	cur, err := col.Find(
+		context.Background(),
+		bson.M{"$and": q},
+		mongooptions.Find().
+			SetSort(bson.D{{Key: "_v.block", Value: order}, {Key: "_v.hash", Value: order}}).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nullIterFunc, nullCloseFunc
+	}
+
+	return func() (element.Operation, bool, []byte) {
+			next := cur.Next(context.Background())
+			if !next {
+				defer cur.Close(context.Background())
+				return element.Operation{}, false, nil
+			}
+
+			var operation element.Operation
+			if _, err := mongostorage.UnmarshalDocument([]byte(cur.Current), &operation); err != nil {
+				return element.Operation{}, false, nil
+			}
+
+			return operation, true, []byte(operation.Hash)
+		},
+		func() {
+			cur.Close(context.Background())
+		}
+}
+
+// operationFilterQuery returns the conditions of filter, which are joined by
+// "$and".
+func operationFilterQuery(address string, filter element.OperationFilter) (bson.A, error) {
+	var q bson.A
+	if len(filter.Counterparty) > 0 {
+		q = append(q, bson.M{"$or": bson.A{
+			bson.M{"_v.source": address, "_v.target": filter.Counterparty},
+			bson.M{"_v.source": filter.Counterparty, "_v.target": address},
+		}})
+	} else {
+		q = append(q, bson.M{"$or": bson.A{
+			bson.M{"_v.source": address},
+			bson.M{"_v.target": address},
+		}})
+	}
+
+	if len(filter.Types) > 0 {
+		q = append(q, bson.M{"_v.type": bson.M{"$in": filter.Types}})
+	}
+
+	amount := bson.M{}
+	if filter.MinAmount > 0 {
+		d, err := primitive.ParseDecimal128(filter.MinAmount.String())
+		if err != nil {
+			return nil, err
+		}
+		amount["$gte"] = d
+	}
+	if filter.MaxAmount > 0 {
+		d, err := primitive.ParseDecimal128(filter.MaxAmount.String())
+		if err != nil {
+			return nil, err
+		}
+		amount["$lte"] = d
+	}
+	if len(amount) > 0 {
+		q = append(q, bson.M{"_v.amount": amount})
+	}
+
+	height := bson.M{}
+	if filter.FromHeight > 0 {
+		height["$gte"] = filter.FromHeight
+	}
+	if filter.ToHeight > 0 {
+		height["$lte"] = filter.ToHeight
+	}
+	if len(height) > 0 {
+		q = append(q, bson.M{"_v.block": height})
+	}
+
+	return q, nil
+}
+
 func (g Potion) OperationsByTransaction(hash string, options storage.ListOptions) (
 	func() (element.Operation, bool, []byte),
 	func(),
@@ -451,7 +603,7 @@ func (g Potion) OperationsByTransaction(hash string, options storage.ListOptions
 			b := []byte(cur.Current)
 			var operation element.Operation
 			_, err = mongostorage.UnmarshalDocument(b, &operation)
-			return operation, true, b
+			return operation, true, []byte(operation.Hash)
 		},
 		func() {
 			cur.Close(context.Background())
@@ -531,7 +683,7 @@ func (g Potion) TransactionsByBlock(hash string, options storage.ListOptions) (
 			b := []byte(cur.Current)
 			var transaction element.Transaction
 			_, err = mongostorage.UnmarshalDocument(b, &transaction)
-			return transaction, true, b
+			return transaction, true, []byte(transaction.Hash)
 		},
 		func() {
 			cur.Close(context.Background())
@@ -554,8 +706,8 @@ func (g Potion) OperationsByHeight(start, end uint64) (
 
 	q := bson.M{
 		"$and": bson.A{
-			bson.M{"_v.height": bson.M{"$gte": start}},
-			bson.M{"_v.height": bson.M{"$lt": end}},
+			bson.M{"_v.block": bson.M{"$gte": start}},
+			bson.M{"_v.block": bson.M{"$lt": end}},
 		},
 	}
 
@@ -563,7 +715,7 @@ func (g Potion) OperationsByHeight(start, end uint64) (
 		context.Background(),
 		q,
 		mongooptions.Find().
-			SetSort(bson.M{"_v.height": 1}),
+			SetSort(bson.M{"_v.block": 1}),
 	)
 	if err != nil {
 		return nullIterFunc, nullCloseFunc
@@ -579,13 +731,157 @@ func (g Potion) OperationsByHeight(start, end uint64) (
 			b := []byte(cur.Current)
 			var operation element.Operation
 			_, err = mongostorage.UnmarshalDocument(b, &operation)
-			return operation, true, b
+			return operation, true, []byte(operation.Hash)
 		},
 		func() {
 			cur.Close(context.Background())
 		}
 }
 
+// searchFieldPaths is the document path of the fields of
+// `element.SearchCollections`.
+var searchFieldPaths = map[string]map[string]string{
+	"blocks": {
+		"hash":            "_v.hash",
+		"height":          "_v.header.height",
+		"prev_block_hash": "_v.header.prevblockhash",
+		"confirmed":       "_v.confirmed",
+	},
+	"transactions": {
+		"hash":      "_v.hash",
+		"block":     "_v.block",
+		"source":    "_v.source",
+		"confirmed": "_v.confirmed",
+	},
+	"accounts": {
+		"address":       "_v.address",
+		"balance":       "_v.balance",
+		"linked":        "_v.linked",
+		"created_block": "_v.createdblock",
+	},
+	"operations": {
+		"hash":    "_v.hash",
+		"tx_hash": "_v.txhash",
+		"type":    "_v.type",
+		"source":  "_v.source",
+		"target":  "_v.target",
+		"block":   "_v.block",
+		"amount":  "_v.amount",
+	},
+}
+
+func (g Potion) Find(collection string, q query.Query, sort string, options storage.ListOptions) (
+	func() (interface{}, bool, []byte),
+	func(),
+	error,
+) {
+	c, q, sort, err := element.PrepareSearch(collection, q, sort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := searchFieldPaths[c.Name]
+
+	var conditions bson.A
+	if q != nil {
+		q, err = query.Transform(q, func(tq query.TermQuery) (query.Query, error) {
+			return query.NewTermQuery(
+				tq.Operator(),
+				query.NewTermWithValue(paths[tq.Term().Field()], tq.Term().Value()),
+			), nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		mq, err := query.NewMongoBuilder(q).Build()
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, mq)
+	}
+
+	col, err := g.s.Collection(c.Prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reverse bool
+	var cursor []byte
+	var limit uint64
+	if options != nil {
+		reverse = options.Reverse()
+		cursor = options.Cursor()
+		limit = options.Limit()
+	}
+
+	sortPath, idPath := paths[sort], paths[c.ID]
+
+	if len(cursor) > 0 {
+		raw, err := col.FindOne(context.Background(), bson.M{idPath: string(cursor)}).DecodeBytes()
+		if err == mongo.ErrNoDocuments { // NOTE like the other potions, nothing after unknown cursor
+			return func() (interface{}, bool, []byte) { return nil, false, nil }, func() {}, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		last, err := raw.LookupErr(strings.Split(sortPath, ".")...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		dir := "$gt"
+		if reverse {
+			dir = "$lt"
+		}
+
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{sortPath: bson.M{dir: last}},
+			bson.M{sortPath: last, idPath: bson.M{dir: string(cursor)}},
+		}})
+	}
+
+	filter := bson.M{}
+	if len(conditions) > 0 {
+		filter = bson.M{"$and": conditions}
+	}
+
+	order := 1
+	if reverse {
+		order = -1
+	}
+
+	cur, err := col.Find(
+		context.Background(),
+		filter,
+		mongooptions.Find().
+			SetSort(bson.D{{Key: sortPath, Value: order}, {Key: idPath, Value: order}}).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func() (interface{}, bool, []byte) {
+			if !cur.Next(context.Background()) {
+				return nil, false, nil
+			}
+
+			nv := reflect.New(reflect.TypeOf(c.Element)).Interface()
+			if _, err := mongostorage.UnmarshalDocument([]byte(cur.Current), nv); err != nil {
+				log.Error("failed to decode document", "collection", c.Name, "error", err)
+				return nil, false, nil
+			}
+
+			v := reflect.ValueOf(nv).Elem().Interface()
+			return v, true, c.Cursor(v)
+		},
+		func() {
+			cur.Close(context.Background())
+		},
+		nil
+}
+
 func (g Potion) BlockStat() (element.BlockStat, error) {
 	var bs element.BlockStat
 	err := g.s.Get(element.GetBlockStatKey(), &bs)
@@ -647,9 +943,231 @@ func (g Potion) TransactionsByAccount(address string, options storage.ListOption
 			b := []byte(cur.Current)
 			var transaction element.Transaction
 			_, err = mongostorage.UnmarshalDocument(b, &transaction)
-			return transaction, true, b
+			return transaction, true, []byte(transaction.Hash)
 		},
 		func() {
 			cur.Close(context.Background())
 		}
 }
+
+// Rollback removes the operations, transactions and blocks above the given
+// height. Mongo can not write the accounts and remove the blocks at once, so
+// the reverted accounts are saved as rollback progress before they are
+// applied; the interrupted rollback is resumed from the saved progress
+// instead of reverting the accounts again.
+func (g Potion) Rollback(height uint64) error {
+	if progress, found, err := g.rollbackProgress(); err != nil {
+		return err
+	} else if found {
+		log.Debug("resume the interrupted rollback", "height", progress.Height)
+		if err := g.finishRollback(progress); err != nil {
+			return err
+		}
+
+		if progress.Height <= height {
+			return nil
+		}
+	}
+
+	progress, err := g.saveRollbackProgress(height)
+	if err != nil {
+		return err
+	}
+
+	return g.finishRollback(progress)
+}
+
+// saveRollbackProgress reverts the accounts and BlockStat in memory and saves
+// them as rollback progress.
+func (g Potion) saveRollbackProgress(height uint64) (rollbackProgress, error) {
+	reverter, err := g.newReverter(height)
+	if err != nil {
+		return rollbackProgress{}, err
+	}
+
+	progress := rollbackProgress{Height: height}
+	if err := reverter.Revert(revertRecorder{Storage: g.s, progress: &progress}); err != nil {
+		log.Error("failed to revert accounts", "height", height, "error", err)
+		return rollbackProgress{}, err
+	}
+
+	if err := g.s.Insert(getRollbackProgressKey(), progress); err != nil {
+		return rollbackProgress{}, err
+	}
+
+	return progress, nil
+}
+
+// finishRollback applies the reverted accounts of progress and removes the
+// blocks above the height; every step can be done again.
+func (g Potion) finishRollback(progress rollbackProgress) error {
+	for _, key := range progress.Deleted {
+		if found, err := g.s.Has(key); err != nil {
+			return err
+		} else if !found {
+			continue
+		}
+
+		if err := g.s.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	for _, ac := range progress.Accounts {
+		if err := ac.Save(g.s); err != nil {
+			log.Error("failed to revert account", "address", ac.Address, "error", err)
+			return err
+		}
+	}
+
+	if progress.BlockStat != nil {
+		if err := progress.BlockStat.Save(g.s); err != nil {
+			return err
+		}
+	}
+
+	removes := []struct {
+		prefix string
+		field  string
+	}{
+		{prefix: element.OperationPrefix, field: "_v.block"},
+		{prefix: element.TransactionPrefix, field: "_v.block"},
+		{prefix: element.BlockPrefix, field: "_v.header.height"},
+		{prefix: element.AccountStatePrefix, field: "_v.height"},
+	}
+
+	for _, r := range removes {
+		col, err := g.s.Collection(r.prefix)
+		if err != nil {
+			return err
+		}
+
+		result, err := col.DeleteMany(
+			context.Background(),
+			bson.M{r.field: bson.M{"$gt": progress.Height}},
+		)
+		if err != nil {
+			log.Error("failed to rollback", "prefix", r.prefix, "height", progress.Height, "error", err)
+			return err
+		}
+		log.Debug("rolled back", "prefix", r.prefix, "height", progress.Height, "deleted", result.DeletedCount)
+	}
+
+	return g.s.Delete(getRollbackProgressKey())
+}
+
+func (g Potion) rollbackProgress() (rollbackProgress, bool, error) {
+	if found, err := g.s.Has(getRollbackProgressKey()); err != nil {
+		return rollbackProgress{}, false, err
+	} else if !found {
+		return rollbackProgress{}, false, nil
+	}
+
+	var progress rollbackProgress
+	if err := g.s.Get(getRollbackProgressKey(), &progress); err != nil {
+		return rollbackProgress{}, false, err
+	}
+
+	return progress, true, nil
+}
+
+// rollbackProgress keeps the accounts and BlockStat, which are reverted by
+// the rollback to the height.
+type rollbackProgress struct {
+	Height    uint64
+	Accounts  []element.Account
+	Deleted   []string
+	BlockStat *element.BlockStat
+}
+
+func getRollbackProgressKey() string {
+	return fmt.Sprintf("%s-rollback-progress", element.InternalPrefix)
+}
+
+// revertRecorder collects the writes of element.Reverter into
+// rollbackProgress instead of writing them to the storage.
+type revertRecorder struct {
+	storage.Storage
+	progress *rollbackProgress
+}
+
+func (r revertRecorder) Insert(k string, v interface{}) error {
+	return r.record(k, v)
+}
+
+func (r revertRecorder) Update(k string, v interface{}) error {
+	return r.record(k, v)
+}
+
+func (r revertRecorder) Delete(k string) error {
+	r.progress.Deleted = append(r.progress.Deleted, k)
+
+	return nil
+}
+
+func (r revertRecorder) Event(string, ...interface{}) {
+}
+
+func (r revertRecorder) record(k string, v interface{}) error {
+	switch t := v.(type) {
+	case element.Account:
+		r.progress.Accounts = append(r.progress.Accounts, t)
+	case element.BlockStat:
+		r.progress.BlockStat = &t
+	default:
+		return storage.DecodeValueFailed.New().SetData("key", k)
+	}
+
+	return nil
+}
+
+// newReverter collects the transactions and operations above the height.
+func (g Potion) newReverter(height uint64) (*element.Reverter, error) {
+	q := bson.M{"_v.block": bson.M{"$gt": height}}
+
+	operations := map[string][]element.Operation{}
+	{
+		col, err := g.s.Collection(element.OperationPrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		cur, err := col.Find(context.Background(), q)
+		if err != nil {
+			return nil, err
+		}
+		defer cur.Close(context.Background())
+
+		for cur.Next(context.Background()) {
+			var op element.Operation
+			if _, err := mongostorage.UnmarshalDocument([]byte(cur.Current), &op); err != nil {
+				return nil, err
+			}
+			operations[op.TxHash] = append(operations[op.TxHash], op)
+		}
+	}
+
+	reverter := element.NewReverter()
+	{
+		col, err := g.s.Collection(element.TransactionPrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		cur, err := col.Find(context.Background(), q)
+		if err != nil {
+			return nil, err
+		}
+		defer cur.Close(context.Background())
+
+		for cur.Next(context.Background()) {
+			var tx element.Transaction
+			if _, err := mongostorage.UnmarshalDocument([]byte(cur.Current), &tx); err != nil {
+				return nil, err
+			}
+			reverter.Add(tx, operations[tx.Hash]...)
+		}
+	}
+
+	return reverter, nil
+}
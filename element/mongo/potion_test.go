@@ -0,0 +1,144 @@
+package mongoelement
+
+import (
+	"testing"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+	"github.com/stretchr/testify/suite"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	mongostorage "github.com/spikeekips/naru/storage/backend/mongo"
+)
+
+type testRollback struct {
+	suite.Suite
+	s            *mongostorage.Storage
+	p            Potion
+	databaseName string
+}
+
+func (t *testRollback) SetupTest() {
+	t.databaseName = common.SequentialUUID()
+
+	c := &config.MongoStorage{
+		URI: mongooptions.Client().ApplyURI("mongodb://localhost:27017"),
+		DB:  t.databaseName,
+	}
+
+	s, err := mongostorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+	t.p = NewPotion(s)
+
+	// state after block 3: `a` created `c` at block 2 and paid to `b` at block
+	// 3.
+	t.NoError(element.Account{Address: "a", Balance: 70, SequenceID: 2}.Save(t.s))
+	t.NoError(element.Account{Address: "b", Balance: 120}.Save(t.s))
+	t.NoError(element.Account{Address: "c", Balance: 10}.Save(t.s))
+	t.NoError(element.BlockStat{TotalSupply: 1000}.Save(t.s))
+
+	for height := uint64(1); height <= 3; height++ {
+		block := element.Block{Hash: common.SequentialUUID(), Header: element.BlockHeader{Height: height}}
+		t.NoError(t.s.Insert(element.GetBlockKey(block.Hash), block))
+	}
+
+	for _, tx := range []element.Transaction{
+		{Hash: "tx2", Block: 2, Source: "a", SequenceID: 0, Amount: sebakcommon.Amount(15)},
+		{Hash: "tx3", Block: 3, Source: "a", SequenceID: 1, Amount: sebakcommon.Amount(20)},
+	} {
+		t.NoError(t.s.Insert(element.GetTransactionKey(tx.Hash), tx))
+	}
+
+	for _, op := range []element.Operation{
+		{Hash: "op2", TxHash: "tx2", Block: 2, Type: sebakoperation.TypeCreateAccount, Source: "a", Target: "c", Amount: 10},
+		{Hash: "op3", TxHash: "tx3", Block: 3, Type: sebakoperation.TypePayment, Source: "a", Target: "b", Amount: 15},
+	} {
+		t.NoError(t.s.Insert(element.GetOperationKey(op.Hash), op))
+	}
+}
+
+func (t *testRollback) TearDownTest() {
+	if t.s == nil {
+		return
+	}
+
+	if err := t.s.Core().Database(t.databaseName, nil).Drop(nil); err != nil {
+		log.Error("failed to drop db", "error", err)
+	}
+}
+
+func (t *testRollback) account(address string) (element.Account, bool) {
+	found, err := t.s.Has(element.GetAccountKey(address))
+	t.NoError(err)
+	if !found {
+		return element.Account{}, false
+	}
+
+	var ac element.Account
+	t.NoError(t.s.Get(element.GetAccountKey(address), &ac))
+
+	return ac, true
+}
+
+func (t *testRollback) checkRolledBack() {
+	a, found := t.account("a")
+	t.True(found)
+	t.Equal(sebakcommon.Amount(105), a.Balance)
+	t.Equal(uint64(0), a.SequenceID)
+
+	b, found := t.account("b")
+	t.True(found)
+	t.Equal(sebakcommon.Amount(105), b.Balance)
+
+	_, found = t.account("c")
+	t.False(found)
+
+	bs, err := t.p.BlockStat()
+	t.NoError(err)
+	t.Equal(uint64(990), bs.TotalSupply)
+
+	last, err := t.p.LastBlock()
+	t.NoError(err)
+	t.Equal(uint64(1), last.Header.Height)
+
+	for _, hash := range []string{"op2", "op3"} {
+		found, err := t.s.Has(element.GetOperationKey(hash))
+		t.NoError(err)
+		t.False(found)
+	}
+
+	found, err = t.s.Has(getRollbackProgressKey())
+	t.NoError(err)
+	t.False(found)
+}
+
+func (t *testRollback) TestRollback() {
+	t.NoError(t.p.Rollback(1))
+	t.checkRolledBack()
+
+	// NOTE rollback again does nothing
+	t.NoError(t.p.Rollback(1))
+	t.checkRolledBack()
+}
+
+// TestRetryAfterFailure retries the rollback, which was interrupted after the
+// part of accounts were reverted; the accounts are not reverted twice.
+func (t *testRollback) TestRetryAfterFailure() {
+	progress, err := t.p.saveRollbackProgress(1)
+	t.NoError(err)
+	t.NotEmpty(progress.Accounts)
+
+	t.NoError(progress.Accounts[0].Save(t.s))
+
+	t.NoError(t.p.Rollback(1))
+	t.checkRolledBack()
+}
+
+func TestRollback(t *testing.T) {
+	suite.Run(t, new(testRollback))
+}
@@ -1,6 +1,7 @@
 package element
 
 import (
+	"encoding/json"
 	"fmt"
 
 	sebakcommon "boscoin.io/sebak/lib/common"
@@ -92,3 +93,8 @@ func (o Operation) Save(st storage.Storage) error {
 
 	return nil
 }
+
+func (o Operation) Operation() (op sebakoperation.Operation, err error) {
+	err = json.Unmarshal(o.Raw, &op)
+	return
+}
@@ -0,0 +1,174 @@
+package element
+
+import (
+	"sort"
+	"time"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+)
+
+// OperationFilter filters the operations of account; the empty field matches
+// with any operation. The amount and height ranges include both ends, and zero
+// `MaxAmount` or `ToHeight` means no upper limit.
+type OperationFilter struct {
+	Types        []sebakoperation.OperationType
+	MinAmount    sebakcommon.Amount
+	MaxAmount    sebakcommon.Amount
+	Counterparty string
+	FromHeight   uint64
+	ToHeight     uint64
+}
+
+func (f OperationFilter) IsEmpty() bool {
+	return len(f.Types) < 1 &&
+		f.MinAmount == 0 &&
+		f.MaxAmount == 0 &&
+		len(f.Counterparty) < 1 &&
+		f.FromHeight == 0 &&
+		f.ToHeight == 0
+}
+
+// MatchHeight checks only the block height; the potions check the height
+// before loading the operation.
+func (f OperationFilter) MatchHeight(height uint64) bool {
+	if height < f.FromHeight {
+		return false
+	}
+	if f.ToHeight > 0 && height > f.ToHeight {
+		return false
+	}
+
+	return true
+}
+
+// Match checks the operation of `address`.
+func (f OperationFilter) Match(address string, op Operation) bool {
+	if op.Source != address && op.Target != address {
+		return false
+	}
+
+	if !f.MatchHeight(op.Block) {
+		return false
+	}
+
+	if len(f.Types) > 0 {
+		var found bool
+		for _, t := range f.Types {
+			if t == op.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if op.Amount < f.MinAmount {
+		return false
+	}
+	if f.MaxAmount > 0 && op.Amount > f.MaxAmount {
+		return false
+	}
+
+	if len(f.Counterparty) > 0 {
+		counterparty := op.Target
+		if op.Target == address {
+			counterparty = op.Source
+		}
+
+		if counterparty != f.Counterparty {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HeightRangeByTime returns the range of block height, which are confirmed
+// between `from` and `to`; the blocks are confirmed in order of height, so the
+// heights are found by binary search. The zero time means no limit. If no
+// block is found in the range, `found` is false.
+func HeightRangeByTime(potion Potion, from, to time.Time) (start, end uint64, found bool, err error) {
+	last, err := potion.LastBlock()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	n := int(last.Header.Height - sebakcommon.GenesisBlockHeight + 1)
+
+	// search returns the index of the first block, which satisfies `f`.
+	search := func(f func(time.Time) bool) (int, error) {
+		var err error
+		i := sort.Search(n, func(i int) bool {
+			if err != nil {
+				return true
+			}
+
+			var block Block
+			if block, err = potion.BlockByHeight(sebakcommon.GenesisBlockHeight + uint64(i)); err != nil {
+				return true
+			}
+
+			return f(block.Confirmed)
+		})
+
+		return i, err
+	}
+
+	// NOTE the first block, which is confirmed at or after `from`
+	start = sebakcommon.GenesisBlockHeight
+	if !from.IsZero() {
+		i, err := search(func(t time.Time) bool { return !t.Before(from) })
+		if err != nil {
+			return 0, 0, false, err
+		} else if i == n {
+			return 0, 0, false, nil
+		}
+		start = sebakcommon.GenesisBlockHeight + uint64(i)
+	}
+
+	// NOTE the last block, which is confirmed at or before `to`
+	end = last.Header.Height
+	if !to.IsZero() {
+		i, err := search(func(t time.Time) bool { return t.After(to) })
+		if err != nil {
+			return 0, 0, false, err
+		} else if i == 0 {
+			return 0, 0, false, nil
+		}
+		end = sebakcommon.GenesisBlockHeight + uint64(i) - 1
+	}
+
+	if start > end {
+		return 0, 0, false, nil
+	}
+
+	return start, end, true, nil
+}
+
+// WithTime narrows the height range of filter by the confirmed time of blocks;
+// see HeightRangeByTime. If no block is in both ranges, `found` is false.
+func (f OperationFilter) WithTime(potion Potion, from, to time.Time) (OperationFilter, bool, error) {
+	if from.IsZero() && to.IsZero() {
+		return f, true, nil
+	}
+
+	start, end, found, err := HeightRangeByTime(potion, from, to)
+	if err != nil || !found {
+		return OperationFilter{}, false, err
+	}
+
+	if start > f.FromHeight {
+		f.FromHeight = start
+	}
+	if f.ToHeight == 0 || end < f.ToHeight {
+		f.ToHeight = end
+	}
+	if f.FromHeight > f.ToHeight {
+		return OperationFilter{}, false, nil
+	}
+
+	return f, true, nil
+}
@@ -1,6 +1,7 @@
 package element
 
 import (
+	"github.com/spikeekips/naru/query"
 	"github.com/spikeekips/naru/storage"
 )
 
@@ -8,6 +9,7 @@ type Potion interface {
 	Check() error
 	Storage() storage.Storage
 	Account( /* address */ string) (Account, error)
+	AccountAt( /* address */ string /* height */, uint64) (Account, error)
 	Accounts(string, storage.ListOptions) (
 		func() (Account, bool, []byte),
 		func(),
@@ -24,6 +26,10 @@ type Potion interface {
 		func() (Operation, bool, []byte),
 		func(),
 	)
+	OperationsByAccountFilter( /* address */ string, OperationFilter /* options */, storage.ListOptions) (
+		func() (Operation, bool, []byte),
+		func(),
+	)
 	OperationsByTransaction( /* hash */ string /* options */, storage.ListOptions) (
 		func() (Operation, bool, []byte),
 		func(),
@@ -42,5 +48,14 @@ type Potion interface {
 		func() (Operation, bool, []byte),
 		func(),
 	)
+	// Find searches the elements of SearchCollections by query; the elements
+	// are sorted by the sort field and `ID`, and the cursor is the `ID`.
+	Find( /* collection */ string, query.Query /* sort */, string, storage.ListOptions) (
+		func() (interface{}, bool, []byte),
+		func(),
+		error,
+	)
 	BlockStat() (BlockStat, error)
+	Rollback( /* height */ uint64) error
+	Migrations() *Migrations
 }
@@ -5,6 +5,9 @@ const (
 	BlockPrefix                   = "1000" // block
 	TransactionPrefix             = "2000" // transaction
 	AccountPrefix                 = "3000" // account
+	AccountStatePrefix            = "3100" // account state by height
 	OperationPrefix               = "4000" // operation
 	OperationAccountRelatedPrefix = "4010"
+	WebhookPrefix                 = "5000" // webhook
+	WebhookDeliveryPrefix         = "5010" // webhook delivery queue
 )
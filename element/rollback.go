@@ -0,0 +1,161 @@
+package element
+
+import (
+	"sort"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+
+	"github.com/spikeekips/naru/storage"
+)
+
+// Reverter reverts the accounts and BlockStat by the transactions and
+// operations, which will be removed by rollback. The transactions and
+// operations should be collected before they are removed.
+type Reverter struct {
+	transactions []Transaction
+	operations   map[string][]Operation
+}
+
+func NewReverter() *Reverter {
+	return &Reverter{operations: map[string][]Operation{}}
+}
+
+func (r *Reverter) Add(tx Transaction, operations ...Operation) {
+	r.transactions = append(r.transactions, tx)
+	r.operations[tx.Hash] = append(r.operations[tx.Hash], operations...)
+}
+
+// Revert reverses the balance and sequence id of the touched accounts; the
+// accounts created by the removed operations are deleted. BlockStat.TotalSupply
+// is also reversed like `OnAfterDigest` adds.
+func (r *Reverter) Revert(st storage.Storage) error {
+	sort.SliceStable(r.transactions, func(i, j int) bool {
+		return r.transactions[i].Block < r.transactions[j].Block
+	})
+
+	accounts := map[string]*Account{}
+	deleted := map[string]struct{}{}
+
+	getAccount := func(address string) (*Account, error) {
+		if _, found := deleted[address]; found {
+			return nil, nil
+		}
+		if ac, found := accounts[address]; found {
+			return ac, nil
+		}
+
+		if found, err := st.Has(GetAccountKey(address)); err != nil {
+			return nil, err
+		} else if !found {
+			return nil, nil
+		}
+
+		var ac Account
+		if err := st.Get(GetAccountKey(address), &ac); err != nil {
+			return nil, err
+		}
+		accounts[address] = &ac
+
+		return &ac, nil
+	}
+
+	var removedSupply uint64
+	for i := len(r.transactions) - 1; i >= 0; i-- { // the later transaction is reverted first
+		tx := r.transactions[i]
+		var isProposerTransaction bool
+		for _, op := range r.operations[tx.Hash] {
+			if op.Type == sebakoperation.TypeCollectTxFee {
+				isProposerTransaction = true
+			}
+
+			switch op.Type {
+			case sebakoperation.TypeCreateAccount, sebakoperation.TypeInflation, sebakoperation.TypeInflationPF:
+				removedSupply += uint64(op.Amount)
+			}
+
+			if len(op.Target) < 1 {
+				continue
+			}
+
+			if op.Type == sebakoperation.TypeCreateAccount {
+				if _, found := accounts[op.Target]; found {
+					delete(accounts, op.Target)
+				}
+				deleted[op.Target] = struct{}{}
+				continue
+			}
+
+			ac, err := getAccount(op.Target)
+			if err != nil {
+				return err
+			} else if ac == nil {
+				continue
+			}
+			ac.Balance = subAmount(ac.Balance, op.Amount)
+		}
+
+		if isProposerTransaction {
+			continue
+		}
+
+		ac, err := getAccount(tx.Source)
+		if err != nil {
+			return err
+		} else if ac == nil {
+			continue
+		}
+
+		ac.Balance = ac.Balance + tx.Amount
+		ac.SequenceID = tx.SequenceID
+	}
+
+	for address := range deleted {
+		if found, err := st.Has(GetAccountKey(address)); err != nil {
+			return err
+		} else if !found {
+			continue
+		}
+
+		if err := st.Delete(GetAccountKey(address)); err != nil {
+			return err
+		}
+	}
+
+	for _, ac := range accounts {
+		if err := ac.Save(st); err != nil {
+			return err
+		}
+	}
+
+	if removedSupply < 1 {
+		return nil
+	}
+
+	if found, err := st.Has(GetBlockStatKey()); err != nil {
+		return err
+	} else if !found {
+		return nil
+	}
+
+	var bs BlockStat
+	if err := st.Get(GetBlockStatKey(), &bs); err != nil {
+		return err
+	}
+
+	if bs.TotalSupply < removedSupply {
+		bs.TotalSupply = 0
+	} else {
+		bs.TotalSupply -= removedSupply
+	}
+
+	return bs.Save(st)
+}
+
+func subAmount(a, b sebakcommon.Amount) sebakcommon.Amount {
+	if a < b {
+		return 0
+	}
+
+	return a - b
+}
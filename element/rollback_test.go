@@ -0,0 +1,106 @@
+package element
+
+import (
+	"testing"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type testReverter struct {
+	suite.Suite
+	s *leveldbstorage.Storage
+}
+
+func (t *testReverter) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+}
+
+func (t *testReverter) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testReverter) account(address string) (Account, bool) {
+	found, err := t.s.Has(GetAccountKey(address))
+	t.NoError(err)
+	if !found {
+		return Account{}, false
+	}
+
+	var ac Account
+	t.NoError(t.s.Get(GetAccountKey(address), &ac))
+
+	return ac, true
+}
+
+func (t *testReverter) TestRevert() {
+	// state after block 3: `a` created `c` at block 2 and paid to `b` at block
+	// 3.
+	t.NoError(Account{Address: "a", Balance: 70, SequenceID: 2}.Save(t.s))
+	t.NoError(Account{Address: "b", Balance: 120}.Save(t.s))
+	t.NoError(Account{Address: "c", Balance: 10}.Save(t.s))
+	t.NoError(BlockStat{TotalSupply: 1000}.Save(t.s))
+
+	reverter := NewReverter()
+	reverter.Add(
+		Transaction{Hash: "tx3", Block: 3, Source: "a", SequenceID: 1, Amount: sebakcommon.Amount(20)},
+		Operation{TxHash: "tx3", Type: sebakoperation.TypePayment, Source: "a", Target: "b", Amount: 15},
+	)
+	reverter.Add(
+		Transaction{Hash: "tx2", Block: 2, Source: "a", SequenceID: 0, Amount: sebakcommon.Amount(15)},
+		Operation{TxHash: "tx2", Type: sebakoperation.TypeCreateAccount, Source: "a", Target: "c", Amount: 10},
+	)
+
+	t.NoError(reverter.Revert(t.s))
+
+	a, found := t.account("a")
+	t.True(found)
+	t.Equal(sebakcommon.Amount(105), a.Balance)
+	t.Equal(uint64(0), a.SequenceID)
+
+	b, found := t.account("b")
+	t.True(found)
+	t.Equal(sebakcommon.Amount(105), b.Balance)
+
+	_, found = t.account("c")
+	t.False(found)
+
+	var bs BlockStat
+	t.NoError(t.s.Get(GetBlockStatKey(), &bs))
+	t.Equal(uint64(990), bs.TotalSupply)
+}
+
+func (t *testReverter) TestProposerTransaction() {
+	t.NoError(Account{Address: "proposer", Balance: 100, SequenceID: 5}.Save(t.s))
+	t.NoError(Account{Address: "common", Balance: 30}.Save(t.s))
+
+	reverter := NewReverter()
+	reverter.Add(
+		Transaction{Hash: "ptx", Block: 4, Source: "proposer", SequenceID: 5, Amount: sebakcommon.Amount(10)},
+		Operation{TxHash: "ptx", Type: sebakoperation.TypeCollectTxFee, Source: "proposer", Target: "common", Amount: 4},
+		Operation{TxHash: "ptx", Type: sebakoperation.TypeInflation, Source: "proposer", Target: "common", Amount: 6},
+	)
+
+	t.NoError(reverter.Revert(t.s))
+
+	proposer, _ := t.account("proposer")
+	t.Equal(sebakcommon.Amount(100), proposer.Balance)
+	t.Equal(uint64(5), proposer.SequenceID)
+
+	common, _ := t.account("common")
+	t.Equal(sebakcommon.Amount(20), common.Balance)
+}
+
+func TestReverter(t *testing.T) {
+	suite.Run(t, new(testReverter))
+}
@@ -0,0 +1,68 @@
+package element
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spikeekips/naru/storage"
+)
+
+// Schema keeps the schema version, which the stored elements are written with.
+// The storage without Schema is regarded as version 0.
+type Schema struct {
+	Version uint64
+	Updated time.Time
+}
+
+func NewSchema(version uint64) Schema {
+	return Schema{Version: version, Updated: time.Now()}
+}
+
+// GetSchema returns the stored Schema. If not found, returns `false`.
+func GetSchema(st storage.Storage) (Schema, bool, error) {
+	var schema Schema
+	if found, err := st.Has(GetSchemaKey()); err != nil {
+		return schema, false, err
+	} else if !found {
+		return schema, false, nil
+	}
+
+	if err := st.Get(GetSchemaKey(), &schema); err != nil {
+		return schema, false, err
+	}
+
+	return schema, true, nil
+}
+
+func (s Schema) Save(st storage.Storage) error {
+	var f func(string, interface{}) error
+	if found, err := st.Has(GetSchemaKey()); err != nil {
+		return err
+	} else if found {
+		f = st.Update
+	} else {
+		f = st.Insert
+	}
+
+	return f(GetSchemaKey(), s)
+}
+
+func GetSchemaKey() string {
+	return fmt.Sprintf("%s-schema", InternalPrefix)
+}
+
+// isEmptyStorage checks whether any block is stored.
+func isEmptyStorage(st storage.Storage) (bool, error) {
+	iterFunc, closeFunc, err := st.Iterator(BlockPrefix, Block{}, storage.NewDefaultListOptions(false, nil, 1))
+	if err != nil {
+		return false, err
+	}
+	defer closeFunc()
+
+	_, next, err := iterFunc()
+	if err != nil {
+		return false, err
+	}
+
+	return !next, nil
+}
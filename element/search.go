@@ -0,0 +1,221 @@
+package element
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/spikeekips/naru/query"
+)
+
+// SearchField is the field, which can be used in the query and sort of
+// `Potion.Find`. The query value is converted into `Hint`; `query.Uint64`
+// and `query.Time` are allowed besides `query.String`.
+type SearchField struct {
+	Hint  query.Hint
+	Value func(interface{}) interface{}
+}
+
+// SearchCollection is the element type for `Potion.Find`; `ID` is the unique
+// field, which is used as cursor and to break the tie in sort.
+type SearchCollection struct {
+	Name    string
+	Prefix  string
+	Element interface{}
+	ID      string
+	Sort    string
+	Fields  map[string]SearchField
+}
+
+var SearchCollections = map[string]SearchCollection{
+	"blocks": SearchCollection{
+		Name:    "blocks",
+		Prefix:  BlockPrefix,
+		Element: Block{},
+		ID:      "hash",
+		Sort:    "height",
+		Fields: map[string]SearchField{
+			"hash":            {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Block).Hash }},
+			"height":          {Hint: query.Uint64, Value: func(v interface{}) interface{} { return v.(Block).Header.Height }},
+			"prev_block_hash": {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Block).Header.PrevBlockHash }},
+			"confirmed":       {Hint: query.Time, Value: func(v interface{}) interface{} { return v.(Block).Confirmed }},
+		},
+	},
+	"transactions": SearchCollection{
+		Name:    "transactions",
+		Prefix:  TransactionPrefix,
+		Element: Transaction{},
+		ID:      "hash",
+		Sort:    "block",
+		Fields: map[string]SearchField{
+			"hash":      {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Transaction).Hash }},
+			"block":     {Hint: query.Uint64, Value: func(v interface{}) interface{} { return v.(Transaction).Block }},
+			"source":    {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Transaction).Source }},
+			"confirmed": {Hint: query.Time, Value: func(v interface{}) interface{} { return v.(Transaction).Confirmed }},
+		},
+	},
+	"accounts": SearchCollection{
+		Name:    "accounts",
+		Prefix:  AccountPrefix,
+		Element: Account{},
+		ID:      "address",
+		Sort:    "created_block",
+		Fields: map[string]SearchField{
+			"address":       {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Account).Address }},
+			"balance":       {Hint: query.Uint64, Value: func(v interface{}) interface{} { return uint64(v.(Account).Balance) }},
+			"linked":        {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Account).Linked }},
+			"created_block": {Hint: query.Uint64, Value: func(v interface{}) interface{} { return v.(Account).CreatedBlock }},
+		},
+	},
+	"operations": SearchCollection{
+		Name:    "operations",
+		Prefix:  OperationPrefix,
+		Element: Operation{},
+		ID:      "hash",
+		Sort:    "block",
+		Fields: map[string]SearchField{
+			"hash":    {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Operation).Hash }},
+			"tx_hash": {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Operation).TxHash }},
+			"type":    {Hint: query.String, Value: func(v interface{}) interface{} { return string(v.(Operation).Type) }},
+			"source":  {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Operation).Source }},
+			"target":  {Hint: query.String, Value: func(v interface{}) interface{} { return v.(Operation).Target }},
+			"block":   {Hint: query.Uint64, Value: func(v interface{}) interface{} { return v.(Operation).Block }},
+			"amount":  {Hint: query.Uint64, Value: func(v interface{}) interface{} { return uint64(v.(Operation).Amount) }},
+		},
+	},
+}
+
+func GetSearchCollection(name string) (SearchCollection, error) {
+	c, found := SearchCollections[name]
+	if !found {
+		return SearchCollection{}, UnknownSearchCollection.New().SetData("collection", name)
+	}
+
+	return c, nil
+}
+
+// PrepareSearch validates the arguments of `Potion.Find`; the nil query
+// matches with all the elements.
+func PrepareSearch(collection string, q query.Query, sort string) (SearchCollection, query.Query, string, error) {
+	c, err := GetSearchCollection(collection)
+	if err != nil {
+		return SearchCollection{}, nil, "", err
+	}
+
+	if q != nil {
+		if q, err = c.Validate(q); err != nil {
+			return SearchCollection{}, nil, "", err
+		}
+	}
+
+	if sort, err = c.SortField(sort); err != nil {
+		return SearchCollection{}, nil, "", err
+	}
+
+	return c, q, sort, nil
+}
+
+// Validate checks the fields of query and converts the values into the hint of
+// field, so the potions can use the values as they are.
+func (c SearchCollection) Validate(q query.Query) (query.Query, error) {
+	return query.Transform(q, func(tq query.TermQuery) (query.Query, error) {
+		field, found := c.Fields[tq.Term().Field()]
+		if !found {
+			return nil, InvalidSearchQuery.New().SetData("field", tq.Term().Field())
+		}
+
+		value := tq.Term().Value()
+
+		var converted query.Value
+		switch tq.Operator() {
+		case query.IN, query.NOTIN:
+			if value.Hint() != query.Array && value.Hint() != query.Slice {
+				return nil, InvalidSearchQuery.New().SetData("field", tq.Term().Field())
+			}
+
+			var values []interface{}
+			for _, i := range value.Value().([]query.Value) {
+				v, err := field.convert(i)
+				if err != nil {
+					return nil, InvalidSearchQuery.New().SetData("field", tq.Term().Field())
+				}
+				values = append(values, v)
+			}
+
+			n, err := query.NewValue(values)
+			if err != nil {
+				return nil, err
+			}
+			converted = n
+		default:
+			v, err := field.convert(value)
+			if err != nil {
+				return nil, InvalidSearchQuery.New().SetData("field", tq.Term().Field())
+			}
+
+			if converted, err = query.NewValue(v); err != nil {
+				return nil, err
+			}
+		}
+
+		return query.NewTermQuery(tq.Operator(), query.NewTermWithValue(tq.Term().Field(), converted)), nil
+	})
+}
+
+// SortField returns the sort field; if empty, the default sort field.
+func (c SearchCollection) SortField(sort string) (string, error) {
+	if len(sort) < 1 {
+		return c.Sort, nil
+	}
+
+	if _, found := c.Fields[sort]; !found {
+		return "", InvalidSearchQuery.New().SetData("sort", sort)
+	}
+
+	return sort, nil
+}
+
+// Match evaluates the validated query with the element in memory.
+func (c SearchCollection) Match(q query.Query, v interface{}) (bool, error) {
+	if q == nil {
+		return true, nil
+	}
+
+	return query.Match(q, func(name string) (interface{}, bool) {
+		field, found := c.Fields[name]
+		if !found {
+			return nil, false
+		}
+
+		return field.Value(v), true
+	})
+}
+
+// Cursor returns the `ID` of element.
+func (c SearchCollection) Cursor(v interface{}) []byte {
+	return []byte(c.Fields[c.ID].Value(v).(string))
+}
+
+func (f SearchField) convert(v query.Value) (interface{}, error) {
+	switch f.Hint {
+	case query.String:
+		if v.Hint() == query.String {
+			return v.Value(), nil
+		}
+	case query.Uint64:
+		switch v.Hint() {
+		// NOTE the big number like amount can be given as string
+		case query.Int, query.Int8, query.Int16, query.Int32, query.Int64,
+			query.Uint, query.Uint8, query.Uint16, query.Uint32, query.Uint64, query.String:
+			return strconv.ParseUint(v.StringValue(), 10, 64)
+		}
+	case query.Time:
+		switch v.Hint() {
+		case query.Time:
+			return v.Value(), nil
+		case query.String:
+			return time.Parse(time.RFC3339Nano, v.Value().(string))
+		}
+	}
+
+	return nil, InvalidSearchQuery.New()
+}
@@ -0,0 +1,81 @@
+package element
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/query"
+)
+
+type testSearchCollection struct {
+	suite.Suite
+}
+
+func (t *testSearchCollection) TestValidate() {
+	c, err := GetSearchCollection("blocks")
+	t.NoError(err)
+
+	{ // the number is converted into uint64
+		tm, _ := query.NewTerm("height", 3)
+		q, err := c.Validate(query.NewTermQuery(query.GTE, tm))
+		t.NoError(err)
+		t.Equal(uint64(3), q.(query.TermQuery).Term().Value().Value())
+	}
+
+	{ // the amount can be string
+		tm, _ := query.NewTerm("height", []string{"3", "4"})
+		q, err := c.Validate(query.NewTermQuery(query.IN, tm))
+		t.NoError(err)
+
+		values := q.(query.TermQuery).Term().Value().Value().([]query.Value)
+		t.Equal(uint64(3), values[0].Value())
+		t.Equal(uint64(4), values[1].Value())
+	}
+
+	{ // unknown field
+		tm, _ := query.NewTerm("proposer", "GABC")
+		_, err := c.Validate(query.NewTermQuery(query.IS, tm))
+		t.True(InvalidSearchQuery.Equal(err))
+	}
+
+	{ // invalid value
+		tm, _ := query.NewTerm("height", -1)
+		_, err := c.Validate(query.NewTermQuery(query.IS, tm))
+		t.True(InvalidSearchQuery.Equal(err))
+	}
+}
+
+func (t *testSearchCollection) TestMatch() {
+	c, q, sort, err := PrepareSearch(
+		"operations",
+		func() query.Query {
+			a, _ := query.NewTerm("amount", "100")
+			b, _ := query.NewTerm("type", "payment")
+			return query.NewTermQuery(query.GT, a).Conjunct(query.AND, query.NewTermQuery(query.IS, b))
+		}(),
+		"",
+	)
+	t.NoError(err)
+	t.Equal("block", sort)
+
+	matched, err := c.Match(q, Operation{Type: "payment", Amount: 101})
+	t.NoError(err)
+	t.True(matched)
+
+	matched, err = c.Match(q, Operation{Type: "payment", Amount: 100})
+	t.NoError(err)
+	t.False(matched)
+}
+
+func (t *testSearchCollection) TestUnknown() {
+	_, _, _, err := PrepareSearch("webhooks", nil, "")
+	t.True(UnknownSearchCollection.Equal(err))
+
+	_, _, _, err = PrepareSearch("blocks", nil, "proposer")
+	t.True(InvalidSearchQuery.Equal(err))
+}
+
+func TestSearchCollection(t *testing.T) {
+	suite.Run(t, new(testSearchCollection))
+}
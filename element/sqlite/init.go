@@ -0,0 +1,11 @@
+package sqliteelement
+
+import (
+	logging "github.com/inconshreveable/log15"
+)
+
+var log logging.Logger = logging.New("module", "sqliteelement")
+
+func Log() logging.Logger {
+	return log
+}
@@ -0,0 +1,15 @@
+package sqliteelement
+
+import (
+	"github.com/spikeekips/naru/element"
+)
+
+// migrations is the ordered migrations of SQLite storage; the new migration
+// should be appended with the next version.
+var migrations = element.NewMigrations(
+	element.DefaultMigrations()...,
+)
+
+func (g Potion) Migrations() *element.Migrations {
+	return migrations
+}
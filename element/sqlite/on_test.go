@@ -0,0 +1,59 @@
+package sqliteelement
+
+import (
+	"testing"
+
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	sqlitestorage "github.com/spikeekips/naru/storage/backend/sqlite"
+)
+
+type testOnAfterDigest struct {
+	suite.Suite
+	s *sqlitestorage.Storage
+	p Potion
+}
+
+func (t *testOnAfterDigest) SetupTest() {
+	s, err := sqlitestorage.NewStorage(&config.SQLiteStorage{Path: "sqlite://:memory:"})
+	t.NoError(err)
+
+	t.s = s
+	t.p = NewPotion(s)
+}
+
+func (t *testOnAfterDigest) TearDownTest() {
+	t.s.Close()
+}
+
+// TestConsecutiveRanges digests `(0, 2]` and `(2, 3]`; the block 2 is counted
+// only once.
+func (t *testOnAfterDigest) TestConsecutiveRanges() {
+	for _, op := range []element.Operation{
+		{Hash: "op1", Type: sebakoperation.TypeCreateAccount, Amount: 100, Block: 1},
+		{Hash: "op2", Type: sebakoperation.TypeInflation, Amount: 10, Block: 2},
+		{Hash: "op3", Type: sebakoperation.TypeInflation, Amount: 1, Block: 3},
+		{Hash: "op4", Type: sebakoperation.TypePayment, Amount: 1000, Block: 3},
+	} {
+		t.NoError(t.s.Insert(element.GetOperationKey(op.Hash), op))
+	}
+
+	OnAfterDigest(t.p, 0, 2)
+
+	bs, err := t.p.BlockStat()
+	t.NoError(err)
+	t.Equal(uint64(110), bs.TotalSupply)
+
+	OnAfterDigest(t.p, 2, 3)
+
+	bs, err = t.p.BlockStat()
+	t.NoError(err)
+	t.Equal(uint64(111), bs.TotalSupply)
+}
+
+func TestOnAfterDigest(t *testing.T) {
+	suite.Run(t, new(testOnAfterDigest))
+}
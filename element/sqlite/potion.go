@@ -0,0 +1,779 @@
+package sqliteelement
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	sebakerrors "boscoin.io/sebak/lib/errors"
+
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/query"
+	"github.com/spikeekips/naru/storage"
+	sqlitestorage "github.com/spikeekips/naru/storage/backend/sqlite"
+)
+
+// accountSortColumns is the allowed sort fields of `Accounts()`.
+var accountSortColumns = map[string]string{
+	"":              "created_block",
+	"createdheight": "created_block",
+	"address":       "address",
+	"balance":       "balance",
+	"linked":        "linked",
+}
+
+type Potion struct {
+	s *sqlitestorage.Storage
+}
+
+func NewPotion(s *sqlitestorage.Storage) Potion {
+	return Potion{s: s}
+}
+
+func (g Potion) Check() error {
+	return migrations.Check(g.s)
+}
+
+func (g Potion) Storage() storage.Storage {
+	return g.s
+}
+
+// queryPageSize is the number of rows, which `iterate()` fetches at once.
+var queryPageSize uint64 = 1000
+
+// query selects the encoded values from the table of prefix. `orders` are the
+// sort columns; with cursor, the records after the record, which has the
+// cursor value in `cursorColumn`, are selected.
+func (g Potion) query(
+	prefix string,
+	where string,
+	args []interface{},
+	orders []string,
+	cursorColumn string,
+	options storage.ListOptions,
+) ([][]byte, error) {
+	var reverse bool
+	var cursor []byte
+	var limit uint64
+	if options != nil {
+		reverse = options.Reverse()
+		cursor = options.Cursor()
+		limit = options.Limit()
+	}
+
+	records, err := g.queryRecords(prefix, where, args, orders, cursorColumn, cursor, reverse, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]byte
+	for _, r := range records {
+		values = append(values, r[1])
+	}
+
+	return values, nil
+}
+
+// iterate runs the query like `query()`, but the rows are fetched by the page
+// of `queryPageSize` with the key of last row as cursor, so the unbounded
+// query does not load all the rows in memory at once.
+func (g Potion) iterate(
+	prefix string,
+	where string,
+	args []interface{},
+	orders []string,
+	cursorColumn string,
+	options storage.ListOptions,
+) (func() ([]byte, bool, error), error) {
+	var reverse bool
+	var cursor []byte
+	var limit uint64
+	if options != nil {
+		reverse = options.Reverse()
+		cursor = options.Cursor()
+		limit = options.Limit()
+	}
+
+	var records [][2][]byte
+	var n int
+	var fetched uint64
+	var done bool
+
+	fetch := func() error {
+		size := queryPageSize
+		if limit > 0 && limit-fetched < size {
+			size = limit - fetched
+		}
+
+		var err error
+		records, err = g.queryRecords(prefix, where, args, orders, cursorColumn, cursor, reverse, size)
+		if err != nil {
+			return err
+		}
+		n = 0
+		fetched += uint64(len(records))
+
+		if uint64(len(records)) < size || (limit > 0 && fetched >= limit) {
+			done = true
+		}
+		if len(records) > 0 {
+			cursorColumn = "k"
+			cursor = records[len(records)-1][0]
+		}
+
+		return nil
+	}
+
+	if err := fetch(); err != nil {
+		return nil, err
+	}
+
+	return func() ([]byte, bool, error) {
+		if n >= len(records) {
+			if done {
+				return nil, false, nil
+			}
+			if err := fetch(); err != nil {
+				return nil, false, err
+			} else if len(records) < 1 {
+				return nil, false, nil
+			}
+		}
+
+		r := records[n]
+		n++
+
+		return r[1], true, nil
+	}, nil
+}
+
+// queryRecords selects the keys and the encoded values; if `limit` is 0, all
+// the rows are selected.
+func (g Potion) queryRecords(
+	prefix string,
+	where string,
+	args []interface{},
+	orders []string,
+	cursorColumn string,
+	cursor []byte,
+	reverse bool,
+	limit uint64,
+) ([][2][]byte, error) {
+	t, err := sqlitestorage.GetTable(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := "ASC"
+	if reverse {
+		dir = "DESC"
+	}
+
+	var columns, orderBy []string
+	for _, o := range orders {
+		columns = append(columns, fmt.Sprintf(`"%s"`, o))
+		orderBy = append(orderBy, fmt.Sprintf(`"%s" %s`, o, dir))
+	}
+	orderBy = append(orderBy, "k "+dir)
+
+	if len(cursor) > 0 && len(cursorColumn) > 0 {
+		op := ">"
+		if reverse {
+			op = "<"
+		}
+
+		c := strings.Join(append(columns, "k"), ", ")
+		where = fmt.Sprintf(
+			`(%s) AND (%s) %s (SELECT %s FROM %s WHERE "%s" = ? LIMIT 1)`,
+			where, c, op, c, t.QuotedName(), cursorColumn,
+		)
+		args = append(args, string(cursor))
+	}
+
+	q := fmt.Sprintf(
+		"SELECT k, v FROM %s WHERE %s ORDER BY %s",
+		t.QuotedName(),
+		where,
+		strings.Join(orderBy, ", "),
+	)
+	if limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := g.s.DB().Query(q, args...)
+	if err != nil {
+		log.Error("failed to query", "query", q, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records [][2][]byte
+	for rows.Next() {
+		var k string
+		var b []byte
+		if err := rows.Scan(&k, &b); err != nil {
+			return nil, err
+		}
+		records = append(records, [2][]byte{[]byte(k), b})
+	}
+
+	return records, rows.Err()
+}
+
+func (g Potion) queryOne(prefix, where string, args []interface{}, orders []string, reverse bool, v interface{}) error {
+	values, err := g.query(prefix, where, args, orders, "", storage.NewDefaultListOptions(reverse, nil, 1))
+	if err != nil {
+		return err
+	} else if len(values) < 1 {
+		return sql.ErrNoRows
+	}
+
+	if err := storage.Deserialize(values[0], v); err != nil {
+		return storage.DecodeValueFailed.New().SetData("error", err.Error())
+	}
+
+	return nil
+}
+
+func (g Potion) Account(address string) (element.Account, error) {
+	var ac element.Account
+	err := g.s.Get(element.GetAccountKey(address), &ac)
+	return ac, err
+}
+
+func (g Potion) AccountAt(address string, height uint64) (element.Account, error) {
+	return element.GetAccountAt(g.s, address, height)
+}
+
+func (g Potion) Accounts(sort string, options storage.ListOptions) (
+	func() (element.Account, bool, []byte),
+	func(),
+) {
+	nullIterFunc := func() (element.Account, bool, []byte) {
+		return element.Account{}, false, nil
+	}
+	nullCloseFunc := func() {}
+
+	column, found := accountSortColumns[strings.ToLower(sort)]
+	if !found {
+		log.Error("unknown sort field", "sort", sort)
+		return nullIterFunc, nullCloseFunc
+	}
+
+	next, err := g.iterate(element.AccountPrefix, "1", nil, []string{column}, "address", options)
+	if err != nil {
+		return nullIterFunc, nullCloseFunc
+	}
+
+	return func() (element.Account, bool, []byte) {
+		b, found, err := next()
+		if err != nil {
+			log.Error("failed to query", "error", err)
+			return element.Account{}, false, nil
+		} else if !found {
+			return element.Account{}, false, nil
+		}
+
+		var account element.Account
+		if err := storage.Deserialize(b, &account); err != nil {
+			return element.Account{}, false, nil
+		}
+
+		return account, true, []byte(account.Address)
+	}, nullCloseFunc
+}
+
+func (g Potion) Block(hash string) (element.Block, error) {
+	var block element.Block
+	if err := g.s.Get(element.GetBlockKey(hash), &block); err != nil {
+		return element.Block{}, err
+	}
+
+	return block, nil
+}
+
+func (g Potion) BlockByHeight(height uint64) (element.Block, error) {
+	var block element.Block
+	err := g.queryOne(element.BlockPrefix, `"height" = ?`, []interface{}{height}, nil, false, &block)
+	if err == sql.ErrNoRows {
+		return element.Block{}, storage.NotFound.New()
+	} else if err != nil {
+		return element.Block{}, err
+	}
+
+	return block, nil
+}
+
+func (g Potion) LastBlock() (element.Block, error) {
+	var block element.Block
+	err := g.queryOne(element.BlockPrefix, "1", nil, []string{"height"}, true, &block)
+	if err == sql.ErrNoRows {
+		return element.Block{}, sebakerrors.StorageRecordDoesNotExist
+	} else if err != nil {
+		return element.Block{}, err
+	}
+
+	return block, nil
+}
+
+func (g Potion) BlocksByHeight(start, end uint64) (
+	func() (element.Block, bool, []byte),
+	func(),
+) {
+	nullIterFunc := func() (element.Block, bool, []byte) {
+		return element.Block{}, false, nil
+	}
+	nullCloseFunc := func() {}
+
+	next, err := g.iterate(
+		element.BlockPrefix,
+		`"height" >= ? AND "height" < ?`,
+		[]interface{}{start, end},
+		[]string{"height"},
+		"",
+		nil,
+	)
+	if err != nil {
+		return nullIterFunc, nullCloseFunc
+	}
+
+	return func() (element.Block, bool, []byte) {
+		b, found, err := next()
+		if err != nil {
+			log.Error("failed to query", "error", err)
+			return element.Block{}, false, nil
+		} else if !found {
+			return element.Block{}, false, nil
+		}
+
+		var block element.Block
+		if err := storage.Deserialize(b, &block); err != nil {
+			return element.Block{}, false, nil
+		}
+
+		return block, true, []byte(block.Hash)
+	}, nullCloseFunc
+}
+
+func (g Potion) Operation(hash string) (op element.Operation, err error) {
+	err = g.s.Get(element.GetOperationKey(hash), &op)
+	return
+}
+
+func (g Potion) operations(where string, args []interface{}, orders []string, options storage.ListOptions) (
+	func() (element.Operation, bool, []byte),
+	func(),
+) {
+	nullIterFunc := func() (element.Operation, bool, []byte) {
+		return element.Operation{}, false, nil
+	}
+	nullCloseFunc := func() {}
+
+	next, err := g.iterate(element.OperationPrefix, where, args, orders, "hash", options)
+	if err != nil {
+		return nullIterFunc, nullCloseFunc
+	}
+
+	return func() (element.Operation, bool, []byte) {
+		b, found, err := next()
+		if err != nil {
+			log.Error("failed to query", "error", err)
+			return element.Operation{}, false, nil
+		} else if !found {
+			return element.Operation{}, false, nil
+		}
+
+		var operation element.Operation
+		if err := storage.Deserialize(b, &operation); err != nil {
+			return element.Operation{}, false, nil
+		}
+
+		return operation, true, []byte(operation.Hash)
+	}, nullCloseFunc
+}
+
+func (g Potion) OperationsByAccount(address string, options storage.ListOptions) (
+	func() (element.Operation, bool, []byte),
+	func(),
+) {
+	return g.operations(
+		`"source" = ? OR "target" = ?`,
+		[]interface{}{address, address},
+		[]string{"block"},
+		options,
+	)
+}
+
+func (g Potion) OperationsByAccountFilter(address string, filter element.OperationFilter, options storage.ListOptions) (
+	func() (element.Operation, bool, []byte),
+	func(),
+) {
+	var where []string
+	var args []interface{}
+
+	if len(filter.Counterparty) > 0 {
+		where = append(where, `(("source" = ? AND "target" = ?) OR ("source" = ? AND "target" = ?))`)
+		args = append(args, address, filter.Counterparty, filter.Counterparty, address)
+	} else {
+		where = append(where, `("source" = ? OR "target" = ?)`)
+		args = append(args, address, address)
+	}
+
+	if len(filter.Types) > 0 {
+		var marks []string
+		for _, t := range filter.Types {
+			marks = append(marks, "?")
+			args = append(args, string(t))
+		}
+		where = append(where, fmt.Sprintf(`"type" IN (%s)`, strings.Join(marks, ", ")))
+	}
+
+	if filter.MinAmount > 0 {
+		where = append(where, `"amount" >= ?`)
+		args = append(args, int64(filter.MinAmount))
+	}
+	if filter.MaxAmount > 0 {
+		where = append(where, `"amount" <= ?`)
+		args = append(args, int64(filter.MaxAmount))
+	}
+	if filter.FromHeight > 0 {
+		where = append(where, `"block" >= ?`)
+		args = append(args, filter.FromHeight)
+	}
+	if filter.ToHeight > 0 {
+		where = append(where, `"block" <= ?`)
+		args = append(args, filter.ToHeight)
+	}
+
+	return g.operations(strings.Join(where, " AND "), args, []string{"block"}, options)
+}
+
+func (g Potion) OperationsByTransaction(hash string, options storage.ListOptions) (
+	func() (element.Operation, bool, []byte),
+	func(),
+) {
+	return g.operations(`"tx_hash" = ?`, []interface{}{hash}, []string{"hash"}, options)
+}
+
+func (g Potion) OperationsByHeight(start, end uint64) (
+	func() (element.Operation, bool, []byte),
+	func(),
+) {
+	return g.operations(
+		`"block" >= ? AND "block" < ?`,
+		[]interface{}{start, end},
+		[]string{"block"},
+		nil,
+	)
+}
+
+func (g Potion) ExistsTransaction(hash string) (bool, error) {
+	return g.s.Has(element.GetTransactionKey(hash))
+}
+
+func (g Potion) Transaction(hash string) (tx element.Transaction, err error) {
+	err = g.s.Get(element.GetTransactionKey(hash), &tx)
+	return
+}
+
+func (g Potion) transactions(where string, args []interface{}, orders []string, options storage.ListOptions) (
+	func() (element.Transaction, bool, []byte),
+	func(),
+) {
+	nullIterFunc := func() (element.Transaction, bool, []byte) {
+		return element.Transaction{}, false, nil
+	}
+	nullCloseFunc := func() {}
+
+	next, err := g.iterate(element.TransactionPrefix, where, args, orders, "hash", options)
+	if err != nil {
+		return nullIterFunc, nullCloseFunc
+	}
+
+	return func() (element.Transaction, bool, []byte) {
+		b, found, err := next()
+		if err != nil {
+			log.Error("failed to query", "error", err)
+			return element.Transaction{}, false, nil
+		} else if !found {
+			return element.Transaction{}, false, nil
+		}
+
+		var transaction element.Transaction
+		if err := storage.Deserialize(b, &transaction); err != nil {
+			return element.Transaction{}, false, nil
+		}
+
+		return transaction, true, []byte(transaction.Hash)
+	}, nullCloseFunc
+}
+
+func (g Potion) TransactionsByBlock(hash string, options storage.ListOptions) (
+	func() (element.Transaction, bool, []byte),
+	func(),
+) {
+	block, err := g.Block(hash)
+	if err != nil {
+		return func() (element.Transaction, bool, []byte) {
+			return element.Transaction{}, false, nil
+		}, func() {}
+	}
+
+	// NOTE proposer transaction is not included like the other potions.
+	return g.transactions(
+		`"block" = ? AND "hash" != ?`,
+		[]interface{}{block.Header.Height, block.ProposerTransaction},
+		[]string{"hash"},
+		options,
+	)
+}
+
+func (g Potion) TransactionsByAccount(address string, options storage.ListOptions) (
+	func() (element.Transaction, bool, []byte),
+	func(),
+) {
+	return g.transactions(`"source" = ?`, []interface{}{address}, []string{"block"}, options)
+}
+
+// Find runs the query by the columns of table; the fields of
+// `element.SearchCollections` have the same name with the columns.
+func (g Potion) Find(collection string, q query.Query, sort string, options storage.ListOptions) (
+	func() (interface{}, bool, []byte),
+	func(),
+	error,
+) {
+	c, q, sort, err := element.PrepareSearch(collection, q, sort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	where, args := "1", []interface{}(nil)
+	if q != nil {
+		if where, args, err = searchWhere(q); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	next, err := g.iterate(c.Prefix, where, args, []string{sort}, c.ID, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func() (interface{}, bool, []byte) {
+		b, found, err := next()
+		if err != nil {
+			log.Error("failed to query", "error", err)
+			return nil, false, nil
+		} else if !found {
+			return nil, false, nil
+		}
+
+		nv := reflect.New(reflect.TypeOf(c.Element)).Interface()
+		if err := storage.Deserialize(b, nv); err != nil {
+			log.Error("failed to decode value", "collection", c.Name, "error", err)
+			return nil, false, nil
+		}
+
+		v := reflect.ValueOf(nv).Elem().Interface()
+		return v, true, c.Cursor(v)
+	}, func() {}, nil
+}
+
+var searchOperators = map[query.Operator]string{
+	query.IS:    "=",
+	query.NOT:   "!=",
+	query.GT:    ">",
+	query.GTE:   ">=",
+	query.LT:    "<",
+	query.LTE:   "<=",
+	query.IN:    "IN",
+	query.NOTIN: "NOT IN",
+}
+
+// searchWhere builds the WHERE clause of the validated query.
+func searchWhere(q query.Query) (string, []interface{}, error) {
+	switch q.Type() {
+	case query.TermQueryType:
+		tq := q.(query.TermQuery)
+
+		operator, found := searchOperators[tq.Operator()]
+		if !found {
+			return "", nil, query.NotSupportedOperator.New()
+		}
+
+		column := fmt.Sprintf(`"%s"`, tq.Term().Field())
+		value := tq.Term().Value()
+
+		switch tq.Operator() {
+		case query.IN, query.NOTIN:
+			var marks []string
+			var args []interface{}
+			for _, i := range value.Value().([]query.Value) {
+				marks = append(marks, searchMark(i))
+				args = append(args, searchArg(i))
+			}
+			if len(marks) < 1 { // NOTE "IN ()" is not allowed in some sqlite
+				if tq.Operator() == query.IN {
+					return "0", nil, nil
+				}
+				return "1", nil, nil
+			}
+
+			if value.Value().([]query.Value)[0].Hint() == query.Time {
+				column = fmt.Sprintf("julianday(%s)", column)
+			}
+
+			return fmt.Sprintf("%s %s (%s)", column, operator, strings.Join(marks, ", ")), args, nil
+		}
+
+		if value.Hint() == query.Time {
+			column = fmt.Sprintf("julianday(%s)", column)
+		}
+
+		return fmt.Sprintf("%s %s %s", column, operator, searchMark(value)), []interface{}{searchArg(value)}, nil
+	case query.ConjunctionQueryType:
+		cq := q.(query.ConjunctionQuery)
+
+		var conjunction string
+		switch cq.Conjunction() {
+		case query.AND:
+			conjunction = " AND "
+		case query.OR:
+			conjunction = " OR "
+		default:
+			return "", nil, query.NotSupportedConjunction.New()
+		}
+
+		var wheres []string
+		var args []interface{}
+		for _, i := range cq.Queries() {
+			w, a, err := searchWhere(i)
+			if err != nil {
+				return "", nil, err
+			}
+			wheres = append(wheres, "("+w+")")
+			args = append(args, a...)
+		}
+
+		return strings.Join(wheres, conjunction), args, nil
+	}
+
+	return "", nil, query.InvalidQueryType.New()
+}
+
+// searchMark returns the placeholder of value; the time is stored as text, so
+// it is compared by `julianday()`.
+func searchMark(v query.Value) string {
+	if v.Hint() == query.Time {
+		return "julianday(?)"
+	}
+
+	return "?"
+}
+
+func searchArg(v query.Value) interface{} {
+	switch v.Hint() {
+	case query.Uint64:
+		return int64(v.Value().(uint64))
+	case query.Time:
+		return v.Value().(time.Time).Format(time.RFC3339Nano)
+	}
+
+	return v.Value()
+}
+
+func (g Potion) BlockStat() (element.BlockStat, error) {
+	var bs element.BlockStat
+	err := g.s.Get(element.GetBlockStatKey(), &bs)
+	return bs, err
+}
+
+// Rollback removes the operations, transactions, account states and blocks
+// above the given height with reverting accounts in one transaction.
+func (g Potion) Rollback(height uint64) error {
+	reverter, err := g.newReverter(height)
+	if err != nil {
+		return err
+	}
+
+	bs, err := g.s.Batch()
+	if err != nil {
+		return err
+	}
+	batch := bs.(*sqlitestorage.Batch)
+	defer batch.Close()
+
+	if err := reverter.Revert(batch); err != nil {
+		log.Error("failed to revert accounts", "height", height, "error", err)
+		return err
+	}
+
+	removes := []struct {
+		prefix string
+		column string
+	}{
+		{prefix: element.OperationPrefix, column: "block"},
+		{prefix: element.TransactionPrefix, column: "block"},
+		{prefix: element.AccountStatePrefix, column: "height"},
+		{prefix: element.BlockPrefix, column: "height"},
+	}
+
+	for _, r := range removes {
+		t, err := sqlitestorage.GetTable(r.prefix)
+		if err != nil {
+			return err
+		}
+
+		batch.Exec(fmt.Sprintf(`DELETE FROM %s WHERE "%s" > ?`, t.QuotedName(), r.column), height)
+	}
+
+	if err := batch.Write(); err != nil {
+		log.Error("failed to rollback", "height", height, "error", err)
+		return err
+	}
+	log.Debug("rolled back", "height", height)
+
+	return nil
+}
+
+// newReverter collects the transactions and operations above the height.
+func (g Potion) newReverter(height uint64) (*element.Reverter, error) {
+	where := `"block" > ?`
+	args := []interface{}{height}
+
+	operations := map[string][]element.Operation{}
+	{
+		values, err := g.query(element.OperationPrefix, where, args, []string{"block"}, "", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range values {
+			var op element.Operation
+			if err := storage.Deserialize(b, &op); err != nil {
+				return nil, err
+			}
+			operations[op.TxHash] = append(operations[op.TxHash], op)
+		}
+	}
+
+	reverter := element.NewReverter()
+	{
+		values, err := g.query(element.TransactionPrefix, where, args, []string{"block"}, "", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range values {
+			var tx element.Transaction
+			if err := storage.Deserialize(b, &tx); err != nil {
+				return nil, err
+			}
+			reverter.Add(tx, operations[tx.Hash]...)
+		}
+	}
+
+	return reverter, nil
+}
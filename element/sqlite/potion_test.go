@@ -0,0 +1,71 @@
+package sqliteelement
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/storage"
+	sqlitestorage "github.com/spikeekips/naru/storage/backend/sqlite"
+)
+
+type testPotionCursor struct {
+	suite.Suite
+	s *sqlitestorage.Storage
+	p Potion
+}
+
+func (t *testPotionCursor) SetupTest() {
+	s, err := sqlitestorage.NewStorage(&config.SQLiteStorage{Path: "sqlite://:memory:"})
+	t.NoError(err)
+
+	t.s = s
+	t.p = NewPotion(s)
+
+	for i := uint64(1); i <= 5; i++ {
+		op := element.Operation{Hash: fmt.Sprintf("op%d", i), Source: "GABC", Target: "GDEF", Block: i}
+		t.NoError(t.s.Insert(element.GetOperationKey(op.Hash), op))
+	}
+}
+
+func (t *testPotionCursor) TearDownTest() {
+	t.s.Close()
+}
+
+// TestNextPage feeds the returned cursor into the next query.
+func (t *testPotionCursor) TestNextPage() {
+	page := func(cursor []byte) ([]string, []byte) {
+		iterFunc, closeFunc := t.p.OperationsByAccount("GABC", storage.NewDefaultListOptions(false, cursor, 2))
+		defer closeFunc()
+
+		var hashes []string
+		var last []byte
+		for {
+			op, next, c := iterFunc()
+			if !next {
+				break
+			}
+			hashes = append(hashes, op.Hash)
+			last = c
+		}
+
+		return hashes, last
+	}
+
+	hashes, cursor := page(nil)
+	t.Equal([]string{"op1", "op2"}, hashes)
+	t.Equal("op2", string(cursor))
+
+	hashes, cursor = page(cursor)
+	t.Equal([]string{"op3", "op4"}, hashes)
+
+	hashes, _ = page(cursor)
+	t.Equal([]string{"op5"}, hashes)
+}
+
+func TestPotionCursor(t *testing.T) {
+	suite.Run(t, new(testPotionCursor))
+}
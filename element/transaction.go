@@ -78,6 +78,7 @@ func (t Transaction) Save(st storage.Storage) error {
 	}
 
 	st.Event("OnAfterSaveTransaction", st, t, t.tx, t.block)
+	st.Event(GetTransactionEventKey(t.Hash), t)
 
 	for opIndex, op := range t.tx.B.Operations {
 		o, err := NewOperation(op, t.tx, uint64(opIndex), t.block.Header.Height)
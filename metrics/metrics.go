@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "naru"
+
+var (
+	DigestLocalBlockHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "digest",
+		Name:      "local_block_height",
+		Help:      "height of the last digested block",
+	})
+	DigestRemoteBlockHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "digest",
+		Name:      "remote_block_height",
+		Help:      "height of the last block of SEBAK",
+	})
+	DigestLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "digest",
+		Name:      "lag_blocks",
+		Help:      "remote block height minus the last digested block height",
+	})
+	DigestBlocksTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "digest",
+		Name:      "blocks_total",
+		Help:      "number of digested blocks",
+	})
+	DigestTransactionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "digest",
+		Name:      "transactions_total",
+		Help:      "number of digested transactions",
+	})
+	DigestOperationsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "digest",
+		Name:      "operations_total",
+		Help:      "number of digested operations",
+	})
+
+	StorageBatchWriteSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "batch_write_seconds",
+		Help:      "latency of BatchStorage.Write",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"backend", "status"})
+
+	HTTPRequestDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "latency of HTTP requests by route",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "number of HTTP requests by route and status",
+	}, []string{"route", "method", "status"})
+
+	CacheRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "number of cache lookups by result, `hit` or `miss`",
+	}, []string{"cache", "result"})
+
+	StreamsOpen = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "streams_open",
+		Help:      "number of open event streams",
+	})
+
+	SubscriberDroppedEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "event",
+		Name:      "subscriber_dropped_total",
+		Help:      "number of events dropped, because the subscriber buffer is full",
+	}, []string{"observable", "policy"})
+	SubscriberDisconnectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "event",
+		Name:      "subscriber_disconnected_total",
+		Help:      "number of subscribers disconnected by the slow consumption",
+	}, []string{"observable"})
+
+	WebhookDeliveriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhook",
+		Name:      "deliveries_total",
+		Help:      "number of webhook delivery attempts by result, `ok`, `retry` or `failed`",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		DigestLocalBlockHeight,
+		DigestRemoteBlockHeight,
+		DigestLag,
+		DigestBlocksTotal,
+		DigestTransactionsTotal,
+		DigestOperationsTotal,
+		StorageBatchWriteSeconds,
+		HTTPRequestDurationSeconds,
+		HTTPRequestsTotal,
+		CacheRequestsTotal,
+		StreamsOpen,
+		SubscriberDroppedEventsTotal,
+		SubscriberDisconnectedTotal,
+		WebhookDeliveriesTotal,
+	)
+}
+
+// Handler serves the registered metrics, including the metrics of SEBAK
+// packages.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// SetDigestHeight updates the block heights and the lag of digest.
+func SetDigestHeight(local, remote uint64) {
+	DigestLocalBlockHeight.Set(float64(local))
+	DigestRemoteBlockHeight.Set(float64(remote))
+
+	var lag float64
+	if remote > local {
+		lag = float64(remote - local)
+	}
+	DigestLag.Set(lag)
+}
+
+// ObserveBatchWrite records the latency of `BatchStorage.Write` since
+// `started`; it is used with `defer`.
+func ObserveBatchWrite(backend string, started time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+
+	StorageBatchWriteSeconds.WithLabelValues(backend, status).Observe(time.Since(started).Seconds())
+}
+
+func ObserveHTTPRequest(route, method string, status int, elapsed time.Duration) {
+	s := strconv.Itoa(status)
+	HTTPRequestDurationSeconds.WithLabelValues(route, method, s).Observe(elapsed.Seconds())
+	HTTPRequestsTotal.WithLabelValues(route, method, s).Inc()
+}
+
+func ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	CacheRequestsTotal.WithLabelValues(cache, result).Inc()
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type testMetrics struct {
+	suite.Suite
+}
+
+func (t *testMetrics) TestDigestLag() {
+	SetDigestHeight(10, 15)
+	t.Equal(float64(10), testutil.ToFloat64(DigestLocalBlockHeight))
+	t.Equal(float64(15), testutil.ToFloat64(DigestRemoteBlockHeight))
+	t.Equal(float64(5), testutil.ToFloat64(DigestLag))
+
+	// local is ahead of the stale remote height
+	SetDigestHeight(16, 15)
+	t.Equal(float64(0), testutil.ToFloat64(DigestLag))
+}
+
+func (t *testMetrics) TestCache() {
+	ObserveCache("test", true)
+	ObserveCache("test", true)
+	ObserveCache("test", false)
+
+	t.Equal(float64(2), testutil.ToFloat64(CacheRequestsTotal.WithLabelValues("test", "hit")))
+	t.Equal(float64(1), testutil.ToFloat64(CacheRequestsTotal.WithLabelValues("test", "miss")))
+}
+
+func (t *testMetrics) TestHTTPRequest() {
+	ObserveHTTPRequest("/api/v1/accounts/{id}", "GET", 404, time.Millisecond)
+	t.Equal(
+		float64(1),
+		testutil.ToFloat64(HTTPRequestsTotal.WithLabelValues("/api/v1/accounts/{id}", "GET", "404")),
+	)
+}
+
+func TestMetrics(t *testing.T) {
+	suite.Run(t, new(testMetrics))
+}
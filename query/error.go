@@ -10,6 +10,7 @@ const (
 	NotSupportedOperatorCode
 	NotSupportedConjunctionCode
 	NotSupportedValueInMongoCode
+	InvalidQuerySyntaxCode
 )
 
 var (
@@ -18,4 +19,5 @@ var (
 	NotSupportedOperator     = common.NewError(NotSupportedOperatorCode, "not supported operator")
 	NotSupportedConjunction  = common.NewError(NotSupportedConjunctionCode, "not supported conjunction")
 	NotSupportedValueInMongo = common.NewError(NotSupportedValueInMongoCode, "not supported value in mongodb")
+	InvalidQuerySyntax       = common.NewError(InvalidQuerySyntaxCode, "invalid query syntax")
 )
@@ -0,0 +1,182 @@
+package query
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Match evaluates the query in memory; `get` returns the value of field. It is
+// for the storage, which can not run the query by itself. The numbers are
+// compared regardless of their types.
+func Match(q Query, get func(string) (interface{}, bool)) (bool, error) {
+	switch q.Type() {
+	case TermQueryType:
+		return matchTermQuery(q.(TermQuery), get)
+	case ConjunctionQueryType:
+		cq := q.(ConjunctionQuery)
+		for _, i := range cq.Queries() {
+			matched, err := Match(i, get)
+			if err != nil {
+				return false, err
+			}
+
+			switch cq.Conjunction() {
+			case AND:
+				if !matched {
+					return false, nil
+				}
+			case OR:
+				if matched {
+					return true, nil
+				}
+			default:
+				return false, NotSupportedConjunction.New()
+			}
+		}
+
+		return cq.Conjunction() == AND, nil
+	}
+
+	return false, InvalidQueryType.New()
+}
+
+// Transform rebuilds the query with the term queries replaced by `f`.
+func Transform(q Query, f func(TermQuery) (Query, error)) (Query, error) {
+	switch q.Type() {
+	case TermQueryType:
+		return f(q.(TermQuery))
+	case ConjunctionQueryType:
+		cq := q.(ConjunctionQuery)
+
+		var queries []Query
+		for _, i := range cq.Queries() {
+			n, err := Transform(i, f)
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, n)
+		}
+
+		return NewConjunctionQuery(cq.Conjunction(), queries...), nil
+	}
+
+	return nil, InvalidQueryType.New()
+}
+
+func matchTermQuery(q TermQuery, get func(string) (interface{}, bool)) (bool, error) {
+	v, found := get(q.Term().Field())
+	if !found {
+		return false, nil
+	}
+
+	value := q.Term().Value()
+
+	switch q.Operator() {
+	case IN, NOTIN:
+		if value.Hint() != Array && value.Hint() != Slice {
+			return false, InvaludValue.New().SetData("operator", q.Operator().String())
+		}
+
+		var found bool
+		for _, i := range value.Value().([]Value) {
+			c, err := Compare(v, i.Value())
+			if err != nil {
+				return false, err
+			}
+			if c == 0 {
+				found = true
+				break
+			}
+		}
+
+		return found == (q.Operator() == IN), nil
+	}
+
+	c, err := Compare(v, value.Value())
+	if err != nil {
+		return false, err
+	}
+
+	switch q.Operator() {
+	case IS:
+		return c == 0, nil
+	case NOT:
+		return c != 0, nil
+	case GT:
+		return c > 0, nil
+	case GTE:
+		return c >= 0, nil
+	case LT:
+		return c < 0, nil
+	case LTE:
+		return c <= 0, nil
+	}
+
+	return false, NotSupportedOperator.New()
+}
+
+// Compare compares the values like Match does; it returns -1, 0 or 1.
+func Compare(a, b interface{}) (int, error) {
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		if !ok {
+			return 0, InvaludValue.New().SetData("value", b)
+		}
+
+		switch {
+		case at.Before(bt):
+			return -1, nil
+		case at.After(bt):
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if fa, ok := bigFloat(ra); ok {
+		fb, ok := bigFloat(rb)
+		if !ok {
+			return 0, InvaludValue.New().SetData("value", b)
+		}
+		return fa.Cmp(fb), nil
+	}
+
+	switch ra.Kind() {
+	case reflect.String:
+		if rb.Kind() != reflect.String {
+			return 0, InvaludValue.New().SetData("value", b)
+		}
+		return strings.Compare(ra.String(), rb.String()), nil
+	case reflect.Bool:
+		if rb.Kind() != reflect.Bool {
+			return 0, InvaludValue.New().SetData("value", b)
+		}
+
+		switch {
+		case ra.Bool() == rb.Bool():
+			return 0, nil
+		case rb.Bool():
+			return -1, nil
+		}
+		return 1, nil
+	}
+
+	return 0, InvaludValue.New().SetData("value", a)
+}
+
+// bigFloat converts the number into big.Float, which keeps the precision of
+// int64 and uint64.
+func bigFloat(v reflect.Value) (*big.Float, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Float).SetInt64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Float).SetUint64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return new(big.Float).SetFloat64(v.Float()), true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,100 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testMatch struct {
+	suite.Suite
+}
+
+func (t *testMatch) get(m map[string]interface{}) func(string) (interface{}, bool) {
+	return func(field string) (interface{}, bool) {
+		v, found := m[field]
+		return v, found
+	}
+}
+
+func (t *testMatch) TestTerm() {
+	get := t.get(map[string]interface{}{
+		"height":    uint64(10),
+		"source":    "GABC",
+		"confirmed": time.Date(2019, 3, 7, 0, 0, 0, 0, time.UTC),
+	})
+
+	cases := []struct {
+		operator Operator
+		field    string
+		value    interface{}
+		expected bool
+	}{
+		{IS, "height", 10, true},
+		{NOT, "height", 10, false},
+		{GT, "height", int64(9), true},
+		{LTE, "height", uint8(9), false},
+		{IS, "source", "GABC", true},
+		{GT, "source", "GABD", false},
+		{IN, "source", []string{"GABB", "GABC"}, true},
+		{NOTIN, "source", []string{"GABB", "GABC"}, false},
+		{GTE, "confirmed", time.Date(2019, 3, 7, 0, 0, 0, 0, time.UTC), true},
+		{LT, "confirmed", time.Date(2019, 3, 6, 0, 0, 0, 0, time.UTC), false},
+		{IS, "unknown", "a", false},
+	}
+
+	for _, c := range cases {
+		tm, err := NewTerm(c.field, c.value)
+		t.NoError(err)
+
+		matched, err := Match(NewTermQuery(c.operator, tm), get)
+		t.NoError(err)
+		t.Equal(c.expected, matched, "%s %s %v", c.field, c.operator, c.value)
+	}
+}
+
+func (t *testMatch) TestConjunction() {
+	get := t.get(map[string]interface{}{"height": uint64(10), "source": "GABC"})
+
+	a, _ := NewTerm("height", 10)
+	b, _ := NewTerm("source", "GABD")
+
+	matched, err := Match(NewTermQuery(IS, a).Conjunct(AND, NewTermQuery(IS, b)), get)
+	t.NoError(err)
+	t.False(matched)
+
+	matched, err = Match(NewTermQuery(IS, a).Conjunct(OR, NewTermQuery(IS, b)), get)
+	t.NoError(err)
+	t.True(matched)
+}
+
+func (t *testMatch) TestTypeMismatch() {
+	get := t.get(map[string]interface{}{"height": uint64(10)})
+
+	tm, _ := NewTerm("height", "10")
+	_, err := Match(NewTermQuery(IS, tm), get)
+	t.True(InvaludValue.Equal(err))
+}
+
+func (t *testMatch) TestTransform() {
+	a, _ := NewTerm("height", 10)
+	b, _ := NewTerm("source", "GABD")
+	q := NewTermQuery(IS, a).Conjunct(OR, NewTermQuery(IS, b))
+
+	n, err := Transform(q, func(tq TermQuery) (Query, error) {
+		tm := NewTermWithValue("_v."+tq.Term().Field(), tq.Term().Value())
+		return NewTermQuery(tq.Operator(), tm), nil
+	})
+	t.NoError(err)
+
+	var fields []string
+	for _, i := range n.Queries() {
+		fields = append(fields, i.(TermQuery).Term().Field())
+	}
+	t.Equal([]string{"_v.height", "_v.source"}, fields)
+}
+
+func TestMatch(t *testing.T) {
+	suite.Run(t, new(testMatch))
+}
@@ -39,6 +39,24 @@ func mongoConjunction(conjunction Conjunction) (string, error) {
 	return m, nil
 }
 
+// MongoBuilder builds the query for mongodb; the fields and values of query
+// should be already converted for the stored documents.
+type MongoBuilder struct {
+	query Query
+}
+
+func NewMongoBuilder(query Query) *MongoBuilder {
+	return &MongoBuilder{query: query}
+}
+
+func (m *MongoBuilder) Query() Query {
+	return m.query
+}
+
+func (m *MongoBuilder) Build() (interface{}, error) {
+	return mongoBuildQuery(m.query)
+}
+
 type TestMongoBuilder struct {
 	query Query
 }
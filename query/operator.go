@@ -1,6 +1,8 @@
 package query
 
 import (
+	"encoding/json"
+
 	"github.com/spikeekips/naru/common"
 )
 
@@ -67,3 +69,53 @@ func (o Operator) String() string {
 func (o Operator) MarshalJSON() ([]byte, error) {
 	return common.MarshalJSONNotEscapeHTML(o.String())
 }
+
+func ParseConjunction(s string) (Conjunction, error) {
+	for _, c := range []Conjunction{AND, OR} {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+
+	return EMPTYConjunction, NotSupportedConjunction.New().SetData("conjunction", s)
+}
+
+func (c *Conjunction) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	n, err := ParseConjunction(s)
+	if err != nil {
+		return err
+	}
+	*c = n
+
+	return nil
+}
+
+func ParseOperator(s string) (Operator, error) {
+	for _, o := range []Operator{IS, NOT, GT, GTE, LT, LTE, IN, NOTIN} {
+		if o.String() == s {
+			return o, nil
+		}
+	}
+
+	return EMPTYOperator, NotSupportedOperator.New().SetData("operator", s)
+}
+
+func (o *Operator) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	n, err := ParseOperator(s)
+	if err != nil {
+		return err
+	}
+	*o = n
+
+	return nil
+}
@@ -0,0 +1,462 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parse parses the text query into Query.
+//
+//	source:GABC AND amount>=1000 AND type IN (payment, create-account)
+//
+// The term is `<field><operator><value>`; the operators are ":" or "=" for
+// IS, "!=" for NOT, ">", ">=", "<", "<=", "IN" and "NOT IN". The terms are
+// joined by "AND" and "OR"; "AND" precedes "OR" and the parentheses group the
+// terms. The value can be typed by the hint like `time("2019-03-07T16:40:27Z")`,
+// `duration(10s)` or `uint8(3)`, and `(a, b)` is the list. Without hint, the
+// number is int64 or float64, true and false are bool, and the others are
+// string; the quoted string is always string.
+//
+// The error has the "position" in data, which is the byte offset of input.
+func Parse(s string) (Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, syntaxError(0, "empty query")
+	}
+
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, syntaxError(t.pos, "unexpected %s", t)
+	}
+
+	return q, nil
+}
+
+func syntaxError(pos int, format string, args ...interface{}) error {
+	return InvalidQuerySyntax.New().
+		SetData("position", pos).
+		SetData("error", fmt.Sprintf(format, args...)+fmt.Sprintf(" at position %d", pos))
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	text string // NOTE unquoted for tokenString
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+// isKeyword checks the word is "AND", "OR", "NOT" or "IN" in any case.
+func (t token) isKeyword(k string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, k)
+}
+
+const wordDelimiters = `:=!<>(),"`
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(wordDelimiters, r)
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, pos: i, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, pos: i, text: ")"})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, pos: i, text: ","})
+			i++
+		case r == ':' || r == '=':
+			tokens = append(tokens, token{kind: tokenOperator, pos: i, text: string(r)})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(s) && s[i+1] == '=' {
+				op += "="
+			} else if r == '!' {
+				return nil, syntaxError(i, "unexpected '!'")
+			}
+			tokens = append(tokens, token{kind: tokenOperator, pos: i, text: op})
+			i += len(op)
+		case r == '"':
+			end := i + 1
+			for ; end < len(s); end++ {
+				if s[end] == '\\' {
+					end++
+				} else if s[end] == '"' {
+					break
+				}
+			}
+			if end >= len(s) {
+				return nil, syntaxError(i, "unterminated string")
+			}
+
+			text, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, syntaxError(i, "invalid string")
+			}
+			tokens = append(tokens, token{kind: tokenString, pos: i, text: text})
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) {
+				r, size := utf8.DecodeRuneInString(s[end:])
+				if !isWordRune(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{kind: tokenWord, pos: i, text: s[i:end]})
+			i = end
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+
+	return t
+}
+
+func (p *parser) parseOr() (Query, error) {
+	return p.parseConjunction(OR, "OR", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (Query, error) {
+	return p.parseConjunction(AND, "AND", p.parsePrimary)
+}
+
+// parseConjunction parses the queries joined by the same conjunction into one
+// ConjunctionQuery; the single query is returned as it is.
+func (p *parser) parseConjunction(conjunction Conjunction, keyword string, parse func() (Query, error)) (Query, error) {
+	q, err := parse()
+	if err != nil {
+		return nil, err
+	}
+
+	queries := []Query{q}
+	for p.peek().isKeyword(keyword) {
+		p.next()
+
+		q, err := parse()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+
+	return NewConjunctionQuery(conjunction, queries...), nil
+}
+
+func (p *parser) parsePrimary() (Query, error) {
+	t := p.peek()
+	if t.kind == tokenLParen {
+		p.next()
+
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, syntaxError(t.pos, "expected ')', but %s", t)
+		}
+
+		return q, nil
+	}
+
+	return p.parseTerm()
+}
+
+func (p *parser) parseTerm() (Query, error) {
+	field := p.next()
+	if field.kind != tokenWord && field.kind != tokenString {
+		return nil, syntaxError(field.pos, "expected field, but %s", field)
+	} else if field.kind == tokenWord {
+		for _, k := range []string{"AND", "OR", "NOT", "IN"} {
+			if field.isKeyword(k) {
+				return nil, syntaxError(field.pos, "expected field, but %s", field)
+			}
+		}
+	}
+
+	var operator Operator
+	t := p.next()
+	switch {
+	case t.kind == tokenOperator:
+		operator = operatorByText[t.text]
+	case t.isKeyword("IN"):
+		operator = IN
+	case t.isKeyword("NOT"):
+		if n := p.next(); !n.isKeyword("IN") {
+			return nil, syntaxError(n.pos, "expected IN, but %s", n)
+		}
+		operator = NOTIN
+	default:
+		return nil, syntaxError(t.pos, "expected operator, but %s", t)
+	}
+
+	pos := p.peek().pos
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if operator == IN || operator == NOTIN {
+		if value.Hint() != Array && value.Hint() != Slice {
+			return nil, syntaxError(pos, "%s needs list", operator)
+		}
+	}
+
+	return NewTermQuery(operator, NewTermWithValue(field.text, value)), nil
+}
+
+var operatorByText = map[string]Operator{
+	":":  IS,
+	"=":  IS,
+	"!=": NOT,
+	">":  GT,
+	">=": GTE,
+	"<":  LT,
+	"<=": LTE,
+}
+
+func (p *parser) parseValue() (Value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		return p.parseList(Slice)
+	case tokenString:
+		return Value{value: t.text, hint: String}, nil
+	case tokenWord:
+		if p.peek().kind != tokenLParen {
+			return literalValue(t)
+		}
+
+		// NOTE typed value
+		hint, err := ParseHint(t.text)
+		if err != nil {
+			return Value{}, syntaxError(t.pos, "unknown hint %s", t)
+		}
+		p.next()
+
+		if hint == Array || hint == Slice {
+			return p.parseList(hint)
+		}
+
+		v := p.next()
+		if v.kind != tokenWord && v.kind != tokenString {
+			return Value{}, syntaxError(v.pos, "expected value, but %s", v)
+		}
+
+		value, err := parseHintValue(hint, v.text)
+		if err != nil {
+			return Value{}, syntaxError(v.pos, "invalid %s value %s", hint, v)
+		}
+
+		if e := p.next(); e.kind != tokenRParen {
+			return Value{}, syntaxError(e.pos, "expected ')', but %s", e)
+		}
+
+		return Value{value: value, hint: hint}, nil
+	}
+
+	return Value{}, syntaxError(t.pos, "expected value, but %s", t)
+}
+
+// parseList parses the values after "(" until ")".
+func (p *parser) parseList(hint Hint) (Value, error) {
+	values := []Value{}
+	if p.peek().kind == tokenRParen {
+		p.next()
+		return Value{value: values, hint: hint}, nil
+	}
+
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return Value{}, err
+		}
+		values = append(values, v)
+
+		t := p.next()
+		if t.kind == tokenRParen {
+			break
+		} else if t.kind != tokenComma {
+			return Value{}, syntaxError(t.pos, "expected ',' or ')', but %s", t)
+		}
+	}
+
+	return Value{value: values, hint: hint}, nil
+}
+
+var (
+	reInteger = regexp.MustCompile(`^-?[0-9]+$`)
+	reFloat   = regexp.MustCompile(`^-?[0-9]+\.[0-9]+$`)
+)
+
+// literalValue guesses the hint of the bare word.
+func literalValue(t token) (Value, error) {
+	switch {
+	case t.text == "true" || t.text == "false":
+		return Value{value: t.text == "true", hint: Bool}, nil
+	case reInteger.MatchString(t.text):
+		if n, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return Value{value: n, hint: Int64}, nil
+		}
+		if n, err := strconv.ParseUint(t.text, 10, 64); err == nil {
+			return Value{value: n, hint: Uint64}, nil
+		}
+		return Value{}, syntaxError(t.pos, "too big number %s", t)
+	case reFloat.MatchString(t.text):
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return Value{}, syntaxError(t.pos, "invalid number %s", t)
+		}
+		return Value{value: n, hint: Float64}, nil
+	}
+
+	return Value{value: t.text, hint: String}, nil
+}
+
+// parseHintValue converts the text into the type of hint.
+func parseHintValue(hint Hint, s string) (interface{}, error) {
+	switch hint {
+	case Bool:
+		return strconv.ParseBool(s)
+	case Int, Int8, Int16, Int32, Int64:
+		n, err := strconv.ParseInt(s, 10, hintBitSize(hint))
+		if err != nil {
+			return nil, err
+		}
+
+		switch hint {
+		case Int:
+			return int(n), nil
+		case Int8:
+			return int8(n), nil
+		case Int16:
+			return int16(n), nil
+		case Int32:
+			return int32(n), nil
+		}
+		return n, nil
+	case Uint, Uint8, Uint16, Uint32, Uint64:
+		n, err := strconv.ParseUint(s, 10, hintBitSize(hint))
+		if err != nil {
+			return nil, err
+		}
+
+		switch hint {
+		case Uint:
+			return uint(n), nil
+		case Uint8:
+			return uint8(n), nil
+		case Uint16:
+			return uint16(n), nil
+		case Uint32:
+			return uint32(n), nil
+		}
+		return n, nil
+	case Float32:
+		n, err := strconv.ParseFloat(s, 32)
+		return float32(n), err
+	case Float64:
+		return strconv.ParseFloat(s, 64)
+	case Complex64, Complex128:
+		var n complex128
+		if _, err := fmt.Sscan(s, &n); err != nil {
+			return nil, err
+		}
+
+		if hint == Complex64 {
+			return complex64(n), nil
+		}
+		return n, nil
+	case String:
+		return s, nil
+	case Time:
+		return time.Parse(time.RFC3339Nano, s)
+	case Duration:
+		return time.ParseDuration(s)
+	}
+
+	return nil, InvaludValue.New()
+}
+
+func hintBitSize(hint Hint) int {
+	switch hint {
+	case Int8, Uint8:
+		return 8
+	case Int16, Uint16:
+		return 16
+	case Int32, Uint32:
+		return 32
+	case Int, Uint:
+		return strconv.IntSize
+	}
+
+	return 64
+}
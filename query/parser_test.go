@@ -0,0 +1,198 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/common"
+)
+
+type testParser struct {
+	suite.Suite
+}
+
+func (t *testParser) term(operator Operator, field string, value interface{}) TermQuery {
+	tm, err := NewTerm(field, value)
+	t.NoError(err)
+
+	return NewTermQuery(operator, tm)
+}
+
+func (t *testParser) TestExample() {
+	q, err := Parse(`source:GABC AND amount>=1000 AND type IN (payment, create-account)`)
+	t.NoError(err)
+
+	expected := NewConjunctionQuery(
+		AND,
+		t.term(IS, "source", "GABC"),
+		t.term(GTE, "amount", int64(1000)),
+		t.term(IN, "type", []string{"payment", "create-account"}),
+	)
+	t.True(expected.Equal(q), q.String())
+}
+
+func (t *testParser) TestOperators() {
+	cases := []struct {
+		s        string
+		expected TermQuery
+	}{
+		{`a:1`, t.term(IS, "a", int64(1))},
+		{`a = 1`, t.term(IS, "a", int64(1))},
+		{`a != 1`, t.term(NOT, "a", int64(1))},
+		{`a>1`, t.term(GT, "a", int64(1))},
+		{`a >= 1`, t.term(GTE, "a", int64(1))},
+		{`a<1`, t.term(LT, "a", int64(1))},
+		{`a <= 1`, t.term(LTE, "a", int64(1))},
+		{`a in (1, 2)`, t.term(IN, "a", []int64{1, 2})},
+		{`a NOT IN (x)`, t.term(NOTIN, "a", []string{"x"})},
+	}
+
+	for _, c := range cases {
+		q, err := Parse(c.s)
+		t.NoError(err, c.s)
+		t.True(c.expected.Equal(q), c.s)
+	}
+}
+
+func (t *testParser) TestConjunction() {
+	a := t.term(IS, "a", int64(1))
+	b := t.term(IS, "b", int64(2))
+	c := t.term(IS, "c", int64(3))
+
+	cases := []struct {
+		s        string
+		expected Query
+	}{
+		{`a:1 AND b:2 OR c:3`, NewConjunctionQuery(OR, NewConjunctionQuery(AND, a, b), c)},
+		{`a:1 or b:2 and c:3`, NewConjunctionQuery(OR, a, NewConjunctionQuery(AND, b, c))},
+		{`a:1 AND (b:2 OR c:3)`, NewConjunctionQuery(AND, a, NewConjunctionQuery(OR, b, c))},
+		{`((a:1))`, a},
+	}
+
+	for _, c := range cases {
+		q, err := Parse(c.s)
+		t.NoError(err, c.s)
+		t.True(c.expected.Equal(q), c.s)
+	}
+}
+
+func (t *testParser) TestValues() {
+	tm := time.Date(2019, 3, 7, 16, 40, 27, 100, time.UTC)
+
+	cases := []struct {
+		s     string
+		value interface{}
+	}{
+		{`a:GABC`, "GABC"},
+		{`a:"AND"`, "AND"},
+		{`a:"1"`, "1"},
+		{`a:"with \"space\""`, `with "space"`},
+		{`a:true`, true},
+		{`a:-10`, int64(-10)},
+		{`a:18446744073709551615`, uint64(18446744073709551615)},
+		{`a:1.5`, float64(1.5)},
+		{`a:uint8(3)`, uint8(3)},
+		{`a:string(3)`, "3"},
+		{`a:bool(false)`, false},
+		{`a:time("2019-03-07T16:40:27.0000001Z")`, tm},
+		{`a:duration(1m30s)`, time.Minute + time.Second*30},
+		{`a:(1, x)`, []interface{}{int64(1), "x"}},
+		{`a:array(1, 2)`, [2]int64{1, 2}},
+	}
+
+	for _, c := range cases {
+		q, err := Parse(c.s)
+		t.NoError(err, c.s)
+		t.True(t.term(IS, "a", c.value).Equal(q), c.s)
+	}
+}
+
+func (t *testParser) TestError() {
+	cases := []struct {
+		s        string
+		position int
+	}{
+		{``, 0},
+		{`a`, 1},
+		{`a:1 AND`, 7},
+		{`a:1 b:2`, 4},
+		{`(a:1`, 4},
+		{`a:1)`, 3},
+		{`a:"b`, 2},
+		{`a ! 1`, 2},
+		{`a IN 1`, 5},
+		{`a NOT 1`, 6},
+		{`AND:1`, 0},
+		{`a:uint8(300)`, 8},
+		{`a:unknown(1)`, 2},
+		{`a:99999999999999999999`, 2},
+		{`a:(1, 2`, 7},
+	}
+
+	for _, c := range cases {
+		_, err := Parse(c.s)
+		t.True(InvalidQuerySyntax.Equal(err), c.s)
+		t.Equal(c.position, err.(*common.Error).Data()["position"], c.s)
+	}
+}
+
+func (t *testParser) TestFormat() {
+	tm := time.Date(2019, 3, 7, 16, 40, 27, 0, time.UTC)
+
+	cases := []struct {
+		q        Query
+		expected string
+	}{
+		{t.term(IS, "source", "GABC"), `source:GABC`},
+		{t.term(NOT, "source", "in"), `source!="in"`},
+		{t.term(GTE, "amount", int64(1000)), `amount>=1000`},
+		{t.term(LT, "amount", uint64(1000)), `amount<uint64(1000)`},
+		{t.term(IS, "a", "1"), `a:"1"`},
+		{t.term(IS, "a b", "c d"), `"a b":"c d"`},
+		{t.term(IS, "a", float64(2)), `a:float64(2)`},
+		{t.term(IS, "a", tm), `a:time("2019-03-07T16:40:27Z")`},
+		{t.term(IN, "type", []string{"payment", "create-account"}), `type IN (payment, create-account)`},
+		{t.term(NOTIN, "a", [2]int{1, 2}), `a NOT IN array(int(1), int(2))`},
+		{
+			NewConjunctionQuery(
+				OR,
+				NewConjunctionQuery(AND, t.term(IS, "a", int64(1)), t.term(IS, "b", int64(2))),
+				t.term(IS, "c", int64(3)),
+			),
+			`(a:1 AND b:2) OR c:3`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Equal(c.expected, Format(c.q))
+	}
+}
+
+func (t *testParser) TestFormatParse() {
+	for _, value := range []interface{}{
+		"GABC", "", "true", "-1", "1.5", "OR", "유니코드", "a\nb", true, false,
+		int(-1), int8(-2), int16(3), int32(4), int64(5),
+		uint(1), uint8(2), uint16(3), uint32(4), uint64(5), uint64(18446744073709551615),
+		float32(1.5), float64(1e100), float64(0.25), complex64(1 + 2i), complex128(-1.5 - 2i),
+		time.Date(2019, 3, 7, 16, 40, 27, 123456789, time.UTC),
+		time.Second * -90,
+		[]interface{}{int64(1), []string{"a", "b"}, [1]uint8{3}},
+	} {
+		q := NewConjunctionQuery(
+			AND,
+			t.term(IS, "a", value),
+			NewConjunctionQuery(OR, t.term(NOT, "b", value), t.term(GT, "c", value)),
+		)
+
+		s := Format(q)
+		parsed, err := Parse(s)
+		t.NoError(err, s)
+		t.True(q.Equal(parsed), s)
+	}
+}
+
+func TestParser(t *testing.T) {
+	suite.Run(t, new(testParser))
+}
@@ -0,0 +1,122 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Format renders the query in the text query syntax of `Parse`. The nested
+// conjunctions are always grouped by parentheses and the values, which can not
+// be guessed back by `Parse`, are typed by hint, so `Parse` returns the same
+// query.
+func Format(q Query) string {
+	return formatQuery(q, false)
+}
+
+func formatQuery(q Query, nested bool) string {
+	switch q.Type() {
+	case TermQueryType:
+		return formatTermQuery(q.(TermQuery))
+	case ConjunctionQueryType:
+		cq := q.(ConjunctionQuery)
+
+		var s []string
+		for _, i := range cq.Queries() {
+			s = append(s, formatQuery(i, true))
+		}
+
+		f := strings.Join(s, " "+strings.TrimPrefix(cq.Conjunction().String(), "$")+" ")
+		if nested {
+			return "(" + f + ")"
+		}
+		return f
+	}
+
+	return ""
+}
+
+var textByOperator = map[Operator]string{
+	IS:    ":",
+	NOT:   "!=",
+	GT:    ">",
+	GTE:   ">=",
+	LT:    "<",
+	LTE:   "<=",
+	IN:    " IN ",
+	NOTIN: " NOT IN ",
+}
+
+func formatTermQuery(q TermQuery) string {
+	return formatWord(q.Term().Field()) + textByOperator[q.Operator()] + formatValue(q.Term().Value())
+}
+
+func formatValue(v Value) string {
+	switch v.Hint() {
+	case Array, Slice:
+		var s []string
+		for _, i := range v.Value().([]Value) {
+			s = append(s, formatValue(i))
+		}
+
+		f := "(" + strings.Join(s, ", ") + ")"
+		if v.Hint() == Array {
+			return Array.String() + f
+		}
+		return f
+	case String:
+		s := v.Value().(string)
+		if literal, err := literalValue(token{text: s}); err == nil && literal.Hint() == String {
+			return formatWord(s)
+		}
+		return strconv.Quote(s)
+	case Bool:
+		return strconv.FormatBool(v.Value().(bool))
+	case Int64:
+		return strconv.FormatInt(v.Value().(int64), 10)
+	case Uint64:
+		if n := v.Value().(uint64); n > math.MaxInt64 {
+			return strconv.FormatUint(n, 10)
+		}
+	case Float64:
+		if s := strconv.FormatFloat(v.Value().(float64), 'f', -1, 64); reFloat.MatchString(s) {
+			return s
+		}
+	case Time:
+		return formatHint(Time, strconv.Quote(v.Value().(time.Time).Format(time.RFC3339Nano)))
+	case Duration:
+		return formatHint(Duration, v.Value().(time.Duration).String())
+	case Complex64, Complex128:
+		return formatHint(v.Hint(), strconv.Quote(fmt.Sprint(v.Value())))
+	}
+
+	return formatHint(v.Hint(), formatWord(fmt.Sprint(v.Value())))
+}
+
+func formatHint(hint Hint, s string) string {
+	return hint.String() + "(" + s + ")"
+}
+
+// formatWord quotes the string unless it can be the bare word.
+func formatWord(s string) string {
+	if len(s) < 1 {
+		return strconv.Quote(s)
+	}
+
+	for _, k := range []string{"AND", "OR", "NOT", "IN"} {
+		if strings.EqualFold(s, k) {
+			return strconv.Quote(s)
+		}
+	}
+
+	for _, r := range s {
+		if !isWordRune(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+
+	return s
+}
@@ -31,6 +31,31 @@ func (q QueryType) String() string {
 	return "InvalidQuery"
 }
 
+func ParseQueryType(s string) (QueryType, error) {
+	for _, q := range []QueryType{TermQueryType, ConjunctionQueryType} {
+		if q.String() == s {
+			return q, nil
+		}
+	}
+
+	return InvalidQuery, InvalidQueryType.New().SetData("type", s)
+}
+
+func (q *QueryType) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	n, err := ParseQueryType(s)
+	if err != nil {
+		return err
+	}
+	*q = n
+
+	return nil
+}
+
 type Query interface {
 	String() string
 	Type() QueryType
@@ -41,6 +66,34 @@ type Query interface {
 	Queries() []Query
 }
 
+// UnmarshalQuery decodes the JSON form of TermQuery or ConjunctionQuery by
+// it's "type".
+func UnmarshalQuery(b []byte) (Query, error) {
+	var h struct {
+		Type QueryType `json:"type"`
+	}
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, err
+	}
+
+	switch h.Type {
+	case TermQueryType:
+		var q TermQuery
+		if err := json.Unmarshal(b, &q); err != nil {
+			return nil, err
+		}
+		return q, nil
+	case ConjunctionQueryType:
+		var q ConjunctionQuery
+		if err := json.Unmarshal(b, &q); err != nil {
+			return nil, err
+		}
+		return q, nil
+	}
+
+	return nil, InvalidQueryType.New()
+}
+
 type TermQuery struct {
 	operator Operator
 	term     Term
@@ -65,6 +118,25 @@ func (t TermQuery) MarshalJSON() ([]byte, error) {
 	return b, err
 }
 
+func (t *TermQuery) UnmarshalJSON(b []byte) error {
+	var m struct {
+		Type     QueryType `json:"type"`
+		Operator Operator  `json:"operator"`
+		Term     Term      `json:"term"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	if m.Type != TermQueryType {
+		return InvalidQueryType.New().SetData("type", m.Type.String())
+	}
+
+	*t = NewTermQuery(m.Operator, m.Term)
+
+	return nil
+}
+
 func (t TermQuery) Equal(v Query) bool {
 	if v.Type() != TermQueryType {
 		return false
@@ -150,6 +222,34 @@ func (t ConjunctionQuery) MarshalJSON() ([]byte, error) {
 	})
 }
 
+func (t *ConjunctionQuery) UnmarshalJSON(b []byte) error {
+	var m struct {
+		Type        QueryType         `json:"type"`
+		Conjunction Conjunction       `json:"conjunction"`
+		Queries     []json.RawMessage `json:"queries"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	if m.Type != ConjunctionQueryType {
+		return InvalidQueryType.New().SetData("type", m.Type.String())
+	}
+
+	var queries []Query
+	for _, r := range m.Queries {
+		q, err := UnmarshalQuery(r)
+		if err != nil {
+			return err
+		}
+		queries = append(queries, q)
+	}
+
+	*t = NewConjunctionQuery(m.Conjunction, queries...)
+
+	return nil
+}
+
 func (t ConjunctionQuery) String() string {
 	b, _ := json.Marshal(t)
 	return string(b)
@@ -190,7 +290,7 @@ func (t ConjunctionQuery) Equal(v Query) bool {
 	}
 
 	queries := tq.Queries()
-	for i, q := range queries {
+	for i, q := range t.queries {
 		if !q.Equal(queries[i]) {
 			return false
 		}
@@ -1,7 +1,9 @@
 package query
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/bson"
@@ -216,6 +218,67 @@ func (t *testQueryBuilder) TestNew() {
 	}
 }
 
+type testQueryJSON struct {
+	suite.Suite
+}
+
+func (t *testQueryJSON) TestTermQuery() {
+	tm, _ := NewTerm("this-a", uint64(33))
+	a := NewTermQuery(GTE, tm)
+
+	b, err := json.Marshal(a)
+	t.NoError(err)
+
+	q, err := UnmarshalQuery(b)
+	t.NoError(err)
+	t.Equal(TermQueryType, q.Type())
+	t.True(a.Equal(q))
+	t.Equal(uint64(33), q.(TermQuery).Term().Value().Value())
+}
+
+func (t *testQueryJSON) TestConjunctionQuery() {
+	var a, b, c TermQuery
+	{
+		tm, _ := NewTerm("this-a", "this-a-value")
+		a = NewTermQuery(NOT, tm)
+	}
+	{
+		tm, _ := NewTerm("this-b", []string{"x", "y"})
+		b = NewTermQuery(IN, tm)
+	}
+	{
+		tm, _ := NewTerm("this-c", time.Date(2019, 3, 7, 16, 40, 27, 0, time.UTC))
+		c = NewTermQuery(LT, tm)
+	}
+
+	d := NewConjunctionQuery(OR, a, NewConjunctionQuery(AND, b, c))
+
+	j, err := json.Marshal(d)
+	t.NoError(err)
+
+	q, err := UnmarshalQuery(j)
+	t.NoError(err)
+	t.Equal(ConjunctionQueryType, q.Type())
+	t.True(d.Equal(q))
+}
+
+func (t *testQueryJSON) TestInvalid() {
+	{ // unknown operator
+		_, err := UnmarshalQuery([]byte(`{"type":"TermQuery","operator":"$LIKE","term":{"field":"a","value":{"hint":"string","value":"b"}}}`))
+		t.True(NotSupportedOperator.Equal(err))
+	}
+
+	{ // unknown type
+		_, err := UnmarshalQuery([]byte(`{"type":"FindQuery"}`))
+		t.True(InvalidQueryType.Equal(err))
+	}
+
+	{ // value does not match with hint
+		_, err := UnmarshalQuery([]byte(`{"type":"TermQuery","operator":"$IS","term":{"field":"a","value":{"hint":"uint8","value":300}}}`))
+		t.True(InvaludValue.Equal(err))
+	}
+}
+
 func TestTermQuery(t *testing.T) {
 	suite.Run(t, new(testTermQuery))
 }
@@ -227,3 +290,7 @@ func TestConjunctionQuery(t *testing.T) {
 func TestQueryBuilder(t *testing.T) {
 	suite.Run(t, new(testQueryBuilder))
 }
+
+func TestQueryJSON(t *testing.T) {
+	suite.Run(t, new(testQueryJSON))
+}
@@ -66,6 +66,16 @@ func (h Hint) String() string {
 	return s
 }
 
+func ParseHint(s string) (Hint, error) {
+	for h := Bool; h <= Duration; h++ {
+		if h.String() == s {
+			return h, nil
+		}
+	}
+
+	return InvalidValue, InvaludValue.New().SetData("hint", s)
+}
+
 type Value struct {
 	value interface{}
 	hint  Hint
@@ -93,6 +103,32 @@ func (t Value) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// UnmarshalJSON decodes the value by it's hint; the numbers are decoded into
+// the exact type of hint, so the decoded value is equal with the original one.
+func (t *Value) UnmarshalJSON(b []byte) error {
+	var m struct {
+		Value json.RawMessage `json:"value"`
+		Hint  string          `json:"hint"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	hint, err := ParseHint(m.Hint)
+	if err != nil {
+		return err
+	}
+
+	v, err := fromJSONValue(hint, m.Value)
+	if err != nil {
+		return err
+	}
+
+	*t = Value{value: v, hint: hint}
+
+	return nil
+}
+
 func NewValue(v interface{}) (Value, error) {
 	hint, nv, err := normalizeValue(v)
 	if err != nil {
@@ -125,7 +161,27 @@ func (t Value) StringValue() string {
 }
 
 func (t Value) Equal(v Value) bool {
-	return t.value == v.value && t.hint == v.hint
+	if t.hint != v.hint {
+		return false
+	}
+
+	switch t.hint {
+	case Time:
+		return t.value.(time.Time).Equal(v.value.(time.Time))
+	case Array, Slice:
+		a, b := t.value.([]Value), v.value.([]Value)
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !a[i].Equal(b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return t.value == v.value
 }
 
 type Term struct {
@@ -142,6 +198,11 @@ func NewTerm(field string, value interface{}) (Term, error) {
 	return Term{field: field, value: tv}, nil
 }
 
+// NewTermWithValue makes Term with the already normalized Value.
+func NewTermWithValue(field string, value Value) Term {
+	return Term{field: field, value: value}
+}
+
 func (t Term) Field() string {
 	return t.field
 }
@@ -160,6 +221,20 @@ func (t Term) MarshalJSON() ([]byte, error) {
 	})
 }
 
+func (t *Term) UnmarshalJSON(b []byte) error {
+	var m struct {
+		Field string `json:"field"`
+		Value Value  `json:"value"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	*t = NewTermWithValue(m.Field, m.Value)
+
+	return nil
+}
+
 func (t Term) String() string {
 	b, _ := json.Marshal(t)
 	return string(b)
@@ -284,3 +359,95 @@ func toStringValue(hint Hint, v interface{}) (string, error) {
 
 	return fmt.Sprintf("%v", v), nil
 }
+
+// fromJSONValue decodes the JSON value into the type of hint.
+func fromJSONValue(hint Hint, b json.RawMessage) (interface{}, error) {
+	invalid := func() error {
+		return InvaludValue.New().SetData("hint", hint.String()).SetData("value", string(b))
+	}
+
+	var v interface{}
+	var err error
+	switch hint {
+	case Bool:
+		var n bool
+		err = json.Unmarshal(b, &n)
+		v = n
+	case Int, Int8, Int16, Int32, Int64, Duration:
+		var n int64
+		if n, err = strconv.ParseInt(string(b), 10, 64); err != nil {
+			return nil, invalid()
+		}
+
+		switch hint {
+		case Int:
+			v = int(n)
+		case Int8:
+			v = int8(n)
+		case Int16:
+			v = int16(n)
+		case Int32:
+			v = int32(n)
+		case Int64:
+			v = n
+		case Duration:
+			v = time.Duration(n)
+		}
+
+		// NOTE check overflow
+		if reflect.ValueOf(v).Int() != n {
+			return nil, invalid()
+		}
+	case Uint, Uint8, Uint16, Uint32, Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(string(b), 10, 64); err != nil {
+			return nil, invalid()
+		}
+
+		switch hint {
+		case Uint:
+			v = uint(n)
+		case Uint8:
+			v = uint8(n)
+		case Uint16:
+			v = uint16(n)
+		case Uint32:
+			v = uint32(n)
+		case Uint64:
+			v = n
+		}
+
+		// NOTE check overflow
+		if reflect.ValueOf(v).Uint() != n {
+			return nil, invalid()
+		}
+	case Float32:
+		var n float32
+		err = json.Unmarshal(b, &n)
+		v = n
+	case Float64:
+		var n float64
+		err = json.Unmarshal(b, &n)
+		v = n
+	case String:
+		var n string
+		err = json.Unmarshal(b, &n)
+		v = n
+	case Time:
+		var n time.Time
+		err = json.Unmarshal(b, &n)
+		v = n
+	case Array, Slice:
+		var n []Value
+		err = json.Unmarshal(b, &n)
+		v = n
+	default:
+		return nil, invalid()
+	}
+
+	if err != nil {
+		return nil, invalid()
+	}
+
+	return v, nil
+}
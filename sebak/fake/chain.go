@@ -0,0 +1,733 @@
+package fakesebak
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sebakblock "boscoin.io/sebak/lib/block"
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakkeypair "boscoin.io/sebak/lib/common/keypair"
+	sebaktransaction "boscoin.io/sebak/lib/transaction"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/sebak"
+	"github.com/spikeekips/naru/storage"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+var (
+	DefaultNetworkID                          = "naru-fake-sebak-network"
+	DefaultInitialBalance  sebakcommon.Amount = 1000000000000000
+	DefaultFee             sebakcommon.Amount = 10000
+	DefaultInflationAmount sebakcommon.Amount = 1000000000
+	DefaultBlockTime                          = time.Second * 5
+	DefaultGenesisTime                        = time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+var (
+	accountsInTransaction int = 100
+	maxPickTries          int = 10
+)
+
+// ChainOptions decides the shape of the generated chain.
+type ChainOptions struct {
+	// Path is the path of leveldb storage like `config.LevelDBStorage`.
+	Path string
+	// Accounts is the number of accounts, which are created by the genesis
+	// account before the random transactions.
+	Accounts int
+	// MaxOperations is the maximum number of operations in one transaction.
+	MaxOperations int
+	// The weights decide the mix of operation types. In SEBAK, inflation and
+	// collect-tx-fee are only in the proposer transaction, but they can be
+	// also mixed in the common transactions to load the digest.
+	PaymentWeight        int
+	CreateAccountWeight  int
+	InflationWeight      int
+	CollectTxFeeWeight   int
+	CongressVotingWeight int
+	UnfreezingWeight     int
+}
+
+func NewChainOptions() ChainOptions {
+	return ChainOptions{
+		Path:                "memory://",
+		MaxOperations:       3,
+		PaymentWeight:       7,
+		CreateAccountWeight: 3,
+	}
+}
+
+type operationWeight struct {
+	opType sebakoperation.OperationType
+	weight int
+}
+
+// operationWeights returns the weights of operation types in the fixed order,
+// so the random choice is deterministic.
+func (o ChainOptions) operationWeights() []operationWeight {
+	return []operationWeight{
+		{opType: sebakoperation.TypeCreateAccount, weight: o.CreateAccountWeight},
+		{opType: sebakoperation.TypePayment, weight: o.PaymentWeight},
+		{opType: sebakoperation.TypeInflation, weight: o.InflationWeight},
+		{opType: sebakoperation.TypeCollectTxFee, weight: o.CollectTxFeeWeight},
+		{opType: sebakoperation.TypeCongressVoting, weight: o.CongressVotingWeight},
+		{opType: sebakoperation.TypeUnfreezingRequest, weight: o.UnfreezingWeight},
+	}
+}
+
+func (o ChainOptions) Validate() error {
+	switch {
+	case o.Accounts < 0:
+		return InvalidChainOptions.New().SetData("accounts", o.Accounts)
+	case o.MaxOperations < 1:
+		return InvalidChainOptions.New().SetData("max-operations", o.MaxOperations)
+	}
+
+	var total int
+	for _, w := range o.operationWeights() {
+		if w.weight < 0 {
+			return InvalidChainOptions.New().SetData(string(w.opType), w.weight)
+		}
+		total += w.weight
+	}
+
+	if total < 1 {
+		return InvalidChainOptions.New().SetData("error", "empty operation weights")
+	}
+
+	return nil
+}
+
+// Chain generates the synthetic SEBAK blocks. Chain stores the blocks,
+// transactions and accounts like SEBAK does in it's own storage, so
+// `sebak.LocalStorageProvider` or `Server` can serve them to digest. With the
+// same seed and options, Chain generates the same blocks.
+type Chain struct {
+	sync.RWMutex
+	networkID string
+	options   ChainOptions
+	st        *leveldbstorage.Storage
+	r         *rand.Rand
+	keypairs  map[string]sebakkeypair.KP
+	accounts  map[string]sebakblock.BlockAccount
+	addresses []string // NOTE ordered by creation, so the random choice is deterministic
+	genesis   string
+	common    string
+	last      sebakblock.Block
+	// proposerSequenceID is the sequence id of the next proposer transaction;
+	// it is not shared with the transactions of the genesis account.
+	proposerSequenceID uint64
+}
+
+func NewChain(networkID string, seed int64) (*Chain, error) {
+	return NewChainWithOptions(networkID, seed, NewChainOptions())
+}
+
+func NewChainWithOptions(networkID string, seed int64, options ChainOptions) (*Chain, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := &config.LevelDBStorage{Path: options.Path}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	st, err := leveldbstorage.NewStorage(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if found, err := st.Has(sebak.BlockHeightKey(sebakcommon.GenesisBlockHeight)); err != nil {
+		st.Close()
+		return nil, err
+	} else if found {
+		st.Close()
+		return nil, StorageNotEmpty.New().SetData("path", options.Path)
+	}
+
+	chain := &Chain{
+		networkID: networkID,
+		options:   options,
+		st:        st,
+		r:         rand.New(rand.NewSource(seed)),
+		keypairs:  map[string]sebakkeypair.KP{},
+		accounts:  map[string]sebakblock.BlockAccount{},
+	}
+
+	if err := chain.genesisBlock(DefaultInitialBalance); err != nil {
+		st.Close()
+		return nil, err
+	}
+
+	if err := chain.createAccounts(options.Accounts); err != nil {
+		st.Close()
+		return nil, err
+	}
+
+	return chain, nil
+}
+
+func (c *Chain) NetworkID() string {
+	return c.networkID
+}
+
+func (c *Chain) Options() ChainOptions {
+	return c.options
+}
+
+// Storage returns the storage, which has the SEBAK data.
+func (c *Chain) Storage() *leveldbstorage.Storage {
+	return c.st
+}
+
+func (c *Chain) Close() error {
+	return c.st.Close()
+}
+
+func (c *Chain) GenesisAccount() string {
+	return c.genesis
+}
+
+func (c *Chain) CommonAccount() string {
+	return c.common
+}
+
+func (c *Chain) LastBlock() sebakblock.Block {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.last
+}
+
+func (c *Chain) NumberOfAccounts() int {
+	c.RLock()
+	defer c.RUnlock()
+
+	return len(c.addresses)
+}
+
+// Accounts returns the all accounts ordered by address.
+func (c *Chain) Accounts() []sebakblock.BlockAccount {
+	c.RLock()
+	defer c.RUnlock()
+
+	var accounts []sebakblock.BlockAccount
+	for _, ac := range c.accounts {
+		accounts = append(accounts, ac)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Address < accounts[j].Address })
+
+	return accounts
+}
+
+// NewBlocks generates the `n` blocks and each block has `txs` transactions at
+// most.
+func (c *Chain) NewBlocks(n, txs int) error {
+	for i := 0; i < n; i++ {
+		if _, err := c.NewBlock(txs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewBlock generates the new block, which has `txs` transactions at most; one
+// account can send only one transaction in a block, so when the source
+// account is not found, the block has less transactions. Every block has the
+// proposer transaction, which collects the transaction fees and inflation to
+// the common account.
+func (c *Chain) NewBlock(txs int) (sebakblock.Block, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.newBlockWith(func(state *blockState, created time.Time) ([]sebaktransaction.Transaction, error) {
+		var transactions []sebaktransaction.Transaction
+		for i := 0; i < txs; i++ {
+			tx, err := c.newTransaction(state, created)
+			if err != nil {
+				if NotEnoughAccounts.Equal(err) {
+					break
+				}
+				return nil, err
+			}
+
+			transactions = append(transactions, tx)
+		}
+
+		return transactions, nil
+	})
+}
+
+// blockState keeps the changes of accounts in the new block.
+type blockState struct {
+	updated map[string]sebakblock.BlockAccount
+	used    map[string]bool
+	created []string
+}
+
+func newBlockState() *blockState {
+	return &blockState{
+		updated: map[string]sebakblock.BlockAccount{},
+		used:    map[string]bool{},
+	}
+}
+
+func (c *Chain) account(state *blockState, address string) sebakblock.BlockAccount {
+	if ac, found := state.updated[address]; found {
+		return ac
+	}
+
+	return c.accounts[address]
+}
+
+func (c *Chain) newBlockWith(f func(*blockState, time.Time) ([]sebaktransaction.Transaction, error)) (sebakblock.Block, error) {
+	height := c.last.Header.Height + 1
+	proposed := DefaultGenesisTime.Add(DefaultBlockTime * time.Duration(height-sebakcommon.GenesisBlockHeight))
+
+	state := newBlockState()
+	transactions, err := f(state, proposed)
+	if err != nil {
+		return sebakblock.Block{}, err
+	}
+
+	var fees sebakcommon.Amount
+	var ops uint64
+	for _, tx := range transactions {
+		fees += tx.B.Fee
+		ops += uint64(len(tx.B.Operations))
+	}
+
+	proposerTx, err := c.newProposerTransaction(state.updated, fees, proposed)
+	if err != nil {
+		return sebakblock.Block{}, err
+	}
+	ops += uint64(len(proposerTx.B.Operations))
+
+	block := c.newBlock(height, proposed, transactions, proposerTx, ops)
+	if err := c.save(block, append(transactions, proposerTx), state); err != nil {
+		return sebakblock.Block{}, err
+	}
+
+	c.proposerSequenceID++
+
+	log.Debug("new block", "height", height, "hash", block.Hash, "transactions", len(transactions))
+
+	return block, nil
+}
+
+func (c *Chain) genesisBlock(balance sebakcommon.Amount) error {
+	master := sebakkeypair.Master(c.networkID)
+
+	genesis, err := c.newKeypair()
+	if err != nil {
+		return err
+	}
+	commonAccount, err := c.newKeypair()
+	if err != nil {
+		return err
+	}
+	c.genesis = genesis.Address()
+	c.common = commonAccount.Address()
+
+	ops, err := newOperations(
+		sebakoperation.CreateAccount{Target: c.genesis, Amount: balance},
+		sebakoperation.CreateAccount{Target: c.common, Amount: 0},
+	)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.newTransactionMessage(master, 0, 0, DefaultGenesisTime, ops...)
+	if err != nil {
+		return err
+	}
+
+	state := newBlockState()
+	state.updated[c.genesis] = sebakblock.BlockAccount{Address: c.genesis, Balance: balance}
+	state.updated[c.common] = sebakblock.BlockAccount{Address: c.common}
+	state.created = []string{c.genesis, c.common}
+
+	block := c.newBlock(
+		sebakcommon.GenesisBlockHeight,
+		DefaultGenesisTime,
+		[]sebaktransaction.Transaction{tx},
+		sebaktransaction.Transaction{},
+		uint64(len(ops)),
+	)
+
+	return c.save(block, []sebaktransaction.Transaction{tx}, state)
+}
+
+// createAccounts creates the `n` accounts from the genesis account. The
+// genesis account shares the half of it's balance to the new accounts. One
+// block has one transaction, which creates `accountsInTransaction` accounts.
+func (c *Chain) createAccounts(n int) error {
+	if n < 1 {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	amount := c.accounts[c.genesis].Balance / sebakcommon.Amount(n*2)
+	if amount < 1 {
+		return InvalidChainOptions.New().SetData("accounts", n).SetData("error", "too many accounts")
+	}
+
+	for created := 0; created < n; created += accountsInTransaction {
+		count := accountsInTransaction
+		if n-created < count {
+			count = n - created
+		}
+
+		_, err := c.newBlockWith(func(state *blockState, proposed time.Time) ([]sebaktransaction.Transaction, error) {
+			var bodies []sebakoperation.Body
+			for i := 0; i < count; i++ {
+				address, err := c.newAccount(state, amount)
+				if err != nil {
+					return nil, err
+				}
+				bodies = append(bodies, sebakoperation.CreateAccount{Target: address, Amount: amount})
+			}
+
+			tx, err := c.newTransactionFrom(state, c.genesis, bodies, sebakcommon.Amount(count)*amount, proposed)
+			if err != nil {
+				return nil, err
+			}
+
+			return []sebaktransaction.Transaction{tx}, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pickSource picks the random account, which can send the transaction in this
+// block. pickSource does not scan the all accounts; it tries `maxPickTries`
+// times.
+func (c *Chain) pickSource(state *blockState, minimum sebakcommon.Amount) (sebakblock.BlockAccount, bool) {
+	if len(c.addresses) < 1 {
+		return sebakblock.BlockAccount{}, false
+	}
+
+	for i := 0; i < maxPickTries; i++ {
+		address := c.addresses[c.r.Intn(len(c.addresses))]
+		if state.used[address] || address == c.common {
+			continue
+		}
+
+		ac := c.account(state, address)
+		if ac.Balance < minimum {
+			continue
+		}
+
+		return ac, true
+	}
+
+	return sebakblock.BlockAccount{}, false
+}
+
+// pickTarget picks the random account except the source, including the
+// accounts created in this block.
+func (c *Chain) pickTarget(state *blockState, source string) (string, bool) {
+	total := len(c.addresses) + len(state.created)
+	for i := 0; i < maxPickTries; i++ {
+		var address string
+		if n := c.r.Intn(total); n < len(c.addresses) {
+			address = c.addresses[n]
+		} else {
+			address = state.created[n-len(c.addresses)]
+		}
+
+		if address != source {
+			return address, true
+		}
+	}
+
+	return "", false
+}
+
+func (c *Chain) newAccount(state *blockState, amount sebakcommon.Amount) (string, error) {
+	kp, err := c.newKeypair()
+	if err != nil {
+		return "", err
+	}
+
+	// NOTE new account can not send transaction in the same block
+	state.used[kp.Address()] = true
+	state.updated[kp.Address()] = sebakblock.BlockAccount{Address: kp.Address(), Balance: amount}
+	state.created = append(state.created, kp.Address())
+
+	return kp.Address(), nil
+}
+
+// newTransaction makes new transaction from the random account, which is not
+// used in this block. The operation types are chosen by the weights of
+// ChainOptions; the amount of payment, inflation and collect-tx-fee is sent to
+// the existing account, and if the target is not found, new account is
+// created instead. Congress voting and unfreezing request do not change the
+// balance.
+func (c *Chain) newTransaction(state *blockState, created time.Time) (sebaktransaction.Transaction, error) {
+	numberOfOps := 1 + c.r.Intn(c.options.MaxOperations)
+	fee := DefaultFee * sebakcommon.Amount(numberOfOps)
+	minimum := fee + sebakcommon.Amount(numberOfOps*10)
+
+	source, found := c.pickSource(state, minimum)
+	if !found {
+		return sebaktransaction.Transaction{}, NotEnoughAccounts.New()
+	}
+	state.used[source.Address] = true
+
+	available := (source.Balance - fee) / sebakcommon.Amount(numberOfOps*10)
+
+	var bodies []sebakoperation.Body
+	var total sebakcommon.Amount
+	for i := 0; i < numberOfOps; i++ {
+		amount := sebakcommon.Amount(1 + c.r.Int63n(int64(available)))
+
+		opType := c.pickOperationType()
+		switch opType {
+		case sebakoperation.TypeUnfreezingRequest:
+			bodies = append(bodies, sebakoperation.UnfreezeRequest{})
+			continue
+		case sebakoperation.TypeCongressVoting:
+			funding, _ := c.pickTarget(state, source.Address)
+			bodies = append(bodies, sebakoperation.NewCongressVoting([]byte(source.Address), 1, 1, 0, funding))
+			continue
+		}
+
+		total += amount
+
+		var target string
+		if opType != sebakoperation.TypeCreateAccount {
+			target, _ = c.pickTarget(state, source.Address)
+		}
+
+		if len(target) < 1 {
+			address, err := c.newAccount(state, amount)
+			if err != nil {
+				return sebaktransaction.Transaction{}, err
+			}
+
+			bodies = append(bodies, sebakoperation.CreateAccount{Target: address, Amount: amount})
+			continue
+		}
+
+		ac := c.account(state, target)
+		ac.Balance += amount
+		state.updated[target] = ac
+
+		switch opType {
+		case sebakoperation.TypeInflation:
+			bodies = append(bodies, sebakoperation.Inflation{Target: target, Amount: amount})
+		case sebakoperation.TypeCollectTxFee:
+			bodies = append(bodies, sebakoperation.CollectTxFee{Target: target, Amount: amount})
+		default:
+			bodies = append(bodies, sebakoperation.Payment{Target: target, Amount: amount})
+		}
+	}
+
+	return c.newTransactionFrom(state, source.Address, bodies, total, created)
+}
+
+func (c *Chain) pickOperationType() sebakoperation.OperationType {
+	weights := c.options.operationWeights()
+
+	var total int
+	for _, w := range weights {
+		total += w.weight
+	}
+
+	n := c.r.Intn(total)
+	for _, w := range weights {
+		if n < w.weight {
+			return w.opType
+		}
+		n -= w.weight
+	}
+
+	return sebakoperation.TypePayment
+}
+
+// newTransactionFrom makes the transaction from source and subtracts the
+// amount and fee from the source account.
+func (c *Chain) newTransactionFrom(state *blockState, address string, bodies []sebakoperation.Body, total sebakcommon.Amount, created time.Time) (sebaktransaction.Transaction, error) {
+	ops, err := newOperations(bodies...)
+	if err != nil {
+		return sebaktransaction.Transaction{}, err
+	}
+
+	fee := DefaultFee * sebakcommon.Amount(len(ops))
+	source := c.account(state, address)
+
+	tx, err := c.newTransactionMessage(c.keypairs[address], source.SequenceID, fee, created, ops...)
+	if err != nil {
+		return sebaktransaction.Transaction{}, err
+	}
+
+	source.Balance -= total + fee
+	source.SequenceID += 1
+	state.updated[address] = source
+
+	return tx, nil
+}
+
+// newProposerTransaction makes the proposer transaction from the genesis
+// account, which collects the fees and the inflation to the common account.
+func (c *Chain) newProposerTransaction(updated map[string]sebakblock.BlockAccount, fees sebakcommon.Amount, created time.Time) (sebaktransaction.Transaction, error) {
+	commonAccount, found := updated[c.common]
+	if !found {
+		commonAccount = c.accounts[c.common]
+	}
+	commonAccount.Balance += fees + DefaultInflationAmount
+	updated[c.common] = commonAccount
+
+	ops, err := newOperations(
+		sebakoperation.CollectTxFee{Target: c.common, Amount: fees},
+		sebakoperation.Inflation{Target: c.common, Amount: DefaultInflationAmount},
+	)
+	if err != nil {
+		return sebaktransaction.Transaction{}, err
+	}
+
+	return c.newTransactionMessage(c.keypairs[c.genesis], c.proposerSequenceID, 0, created, ops...)
+}
+
+func (c *Chain) newTransactionMessage(kp sebakkeypair.KP, sequenceID uint64, fee sebakcommon.Amount, created time.Time, ops ...sebakoperation.Operation) (sebaktransaction.Transaction, error) {
+	tx, err := sebaktransaction.NewTransaction(kp.Address(), sequenceID, ops...)
+	if err != nil {
+		return sebaktransaction.Transaction{}, err
+	}
+
+	tx.B.Fee = fee
+	tx.H.Created = common.FormatISO8601(created)
+	tx.Sign(kp, []byte(c.networkID))
+
+	return tx, nil
+}
+
+func (c *Chain) newBlock(height uint64, proposed time.Time, txs []sebaktransaction.Transaction, proposerTx sebaktransaction.Transaction, ops uint64) sebakblock.Block {
+	var hashes []string
+	for _, tx := range txs {
+		hashes = append(hashes, tx.GetHash())
+	}
+
+	var proposerTxHash string
+	totalTxs := c.last.Header.TotalTxs + uint64(len(txs))
+	if len(proposerTx.B.Operations) > 0 {
+		proposerTxHash = proposerTx.GetHash()
+		totalTxs += 1
+	}
+
+	header := sebakblock.Header{
+		PrevBlockHash:    c.last.Hash,
+		TransactionsRoot: sebakcommon.MustMakeObjectHashString(strings.Join(hashes, ",")),
+		ProposedTime:     common.FormatISO8601(proposed),
+		Height:           height,
+		TotalTxs:         totalTxs,
+		TotalOps:         c.last.Header.TotalOps + ops,
+	}
+
+	return sebakblock.Block{
+		Header:              header,
+		Transactions:        hashes,
+		ProposerTransaction: proposerTxHash,
+		Hash: sebakcommon.MustMakeObjectHashString(
+			fmt.Sprintf("%d-%s-%s-%s", height, header.PrevBlockHash, header.TransactionsRoot, proposerTxHash),
+		),
+		Proposer:  c.genesis,
+		Confirmed: common.FormatISO8601(proposed),
+	}
+}
+
+// save stores the block, transactions and the updated accounts in one batch.
+func (c *Chain) save(block sebakblock.Block, txs []sebaktransaction.Transaction, state *blockState) error {
+	batch, err := c.st.Batch()
+	if err != nil {
+		return err
+	}
+	defer batch.Close()
+
+	for _, tx := range txs {
+		tp := sebakblock.TransactionPool{Message: sebakcommon.MustMarshalJSON(tx)}
+		if err := batch.Insert(sebak.TransactionKey(tx.GetHash()), tp); err != nil {
+			return err
+		}
+	}
+
+	for _, ac := range state.updated {
+		if err := saveAccount(c.st, batch, ac); err != nil {
+			return err
+		}
+	}
+
+	if err := batch.Insert(sebak.BlockHashKey(block.Hash), block); err != nil {
+		return err
+	}
+	if err := batch.Insert(sebak.BlockHeightKey(block.Header.Height), block.Hash); err != nil {
+		return err
+	}
+
+	if err := batch.Write(); err != nil {
+		return err
+	}
+
+	for address, ac := range state.updated {
+		c.accounts[address] = ac
+	}
+	c.addresses = append(c.addresses, state.created...)
+	c.last = block
+
+	return nil
+}
+
+func (c *Chain) newKeypair() (sebakkeypair.KP, error) {
+	var seed [32]byte
+	if _, err := c.r.Read(seed[:]); err != nil {
+		return nil, err
+	}
+
+	kp, err := sebakkeypair.FromRawSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	c.keypairs[kp.Address()] = kp
+
+	return kp, nil
+}
+
+func newOperations(bodies ...sebakoperation.Body) ([]sebakoperation.Operation, error) {
+	var ops []sebakoperation.Operation
+	for _, body := range bodies {
+		op, err := sebakoperation.NewOperation(body)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+func saveAccount(st *leveldbstorage.Storage, batch storage.BatchStorage, ac sebakblock.BlockAccount) error {
+	key := sebak.AccountKey(ac.Address)
+	if found, err := st.Has(key); err != nil {
+		return err
+	} else if found {
+		return batch.Update(key, ac)
+	}
+
+	return batch.Insert(key, ac)
+}
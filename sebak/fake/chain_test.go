@@ -0,0 +1,196 @@
+package fakesebak
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sebakblock "boscoin.io/sebak/lib/block"
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebaktransaction "boscoin.io/sebak/lib/transaction"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/sebak"
+)
+
+type testChain struct {
+	suite.Suite
+}
+
+func (t *testChain) TestAccounts() {
+	options := NewChainOptions()
+	options.Accounts = 250
+
+	chain, err := NewChainWithOptions(DefaultNetworkID, 1, options)
+	t.NoError(err)
+	defer chain.Close()
+
+	// genesis and common account
+	t.Equal(options.Accounts+2, chain.NumberOfAccounts())
+	t.Equal(uint64(4), chain.LastBlock().Header.Height)
+
+	for _, ac := range chain.Accounts() {
+		var stored sebakblock.BlockAccount
+		t.NoError(chain.Storage().Get(sebak.AccountKey(ac.Address), &stored))
+		t.Equal(ac.Balance, stored.Balance)
+	}
+}
+
+func (t *testChain) TestOnlyPayments() {
+	options := NewChainOptions()
+	options.Accounts = 10
+	options.CreateAccountWeight = 0
+
+	chain, err := NewChainWithOptions(DefaultNetworkID, 1, options)
+	t.NoError(err)
+	defer chain.Close()
+
+	t.NoError(chain.NewBlocks(10, 5))
+	t.Equal(options.Accounts+2, chain.NumberOfAccounts())
+}
+
+// TestAllOperationTypes generates the all operation types of the weights; the
+// total balance is not changed by them.
+func (t *testChain) TestAllOperationTypes() {
+	options := NewChainOptions()
+	options.Accounts = 10
+	options.InflationWeight = 1
+	options.CollectTxFeeWeight = 1
+	options.CongressVotingWeight = 1
+	options.UnfreezingWeight = 1
+
+	chain, err := NewChainWithOptions(DefaultNetworkID, 1, options)
+	t.NoError(err)
+	defer chain.Close()
+
+	t.NoError(chain.NewBlocks(20, 5))
+
+	types := map[sebakoperation.OperationType]bool{}
+	for height := sebakcommon.GenesisBlockHeight + 1; height <= chain.LastBlock().Header.Height; height++ {
+		var hash string
+		t.NoError(chain.Storage().Get(sebak.BlockHeightKey(height), &hash))
+
+		var block sebakblock.Block
+		t.NoError(chain.Storage().Get(sebak.BlockHashKey(hash), &block))
+
+		for _, txHash := range block.Transactions {
+			var tp sebakblock.TransactionPool
+			t.NoError(chain.Storage().Get(sebak.TransactionKey(txHash), &tp))
+
+			var tx sebaktransaction.Transaction
+			t.NoError(json.Unmarshal(tp.Message, &tx))
+			for _, op := range tx.B.Operations {
+				types[op.H.Type] = true
+			}
+		}
+	}
+
+	for _, w := range options.operationWeights() {
+		t.True(types[w.opType], "type=%s", w.opType)
+	}
+
+	var total sebakcommon.Amount
+	for _, ac := range chain.Accounts() {
+		total += ac.Balance
+	}
+
+	inflation := DefaultInflationAmount * sebakcommon.Amount(chain.LastBlock().Header.Height-1)
+	t.Equal(DefaultInitialBalance+inflation, total)
+}
+
+// TestProposerSequenceID checks the proposer transaction has it's own
+// sequence id, which is not shared with the genesis account.
+func (t *testChain) TestProposerSequenceID() {
+	options := NewChainOptions()
+	options.Accounts = 10
+
+	chain, err := NewChainWithOptions(DefaultNetworkID, 1, options)
+	t.NoError(err)
+	defer chain.Close()
+
+	t.NoError(chain.NewBlocks(3, 5))
+
+	var sequenceIDs []uint64
+	for height := sebakcommon.GenesisBlockHeight + 1; height <= chain.LastBlock().Header.Height; height++ {
+		var hash string
+		t.NoError(chain.Storage().Get(sebak.BlockHeightKey(height), &hash))
+
+		var block sebakblock.Block
+		t.NoError(chain.Storage().Get(sebak.BlockHashKey(hash), &block))
+
+		var tp sebakblock.TransactionPool
+		t.NoError(chain.Storage().Get(sebak.TransactionKey(block.ProposerTransaction), &tp))
+
+		var tx sebaktransaction.Transaction
+		t.NoError(json.Unmarshal(tp.Message, &tx))
+		sequenceIDs = append(sequenceIDs, tx.B.SequenceID)
+	}
+
+	t.Equal([]uint64{0, 1, 2, 3}, sequenceIDs)
+}
+
+func (t *testChain) TestTotalBalance() {
+	chain, err := NewChain(DefaultNetworkID, 1)
+	t.NoError(err)
+	defer chain.Close()
+
+	t.NoError(chain.NewBlocks(10, 5))
+
+	var total sebakcommon.Amount
+	for _, ac := range chain.Accounts() {
+		total += ac.Balance
+	}
+
+	// NOTE the genesis block does not have the proposer transaction
+	inflation := DefaultInflationAmount * sebakcommon.Amount(chain.LastBlock().Header.Height-1)
+	t.Equal(DefaultInitialBalance+inflation, total)
+}
+
+func (t *testChain) TestInvalidOptions() {
+	options := NewChainOptions()
+	options.PaymentWeight = 0
+	options.CreateAccountWeight = 0
+
+	_, err := NewChainWithOptions(DefaultNetworkID, 1, options)
+	t.True(InvalidChainOptions.Equal(err))
+}
+
+func (t *testChain) TestStorageNotEmpty() {
+	dir, err := ioutil.TempDir("", "naru-fake-sebak")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+
+	options := NewChainOptions()
+	options.Path = "file://" + filepath.Join(dir, "db")
+
+	chain, err := NewChainWithOptions(DefaultNetworkID, 1, options)
+	t.NoError(err)
+	t.NoError(chain.Close())
+
+	_, err = NewChainWithOptions(DefaultNetworkID, 1, options)
+	t.True(StorageNotEmpty.Equal(err))
+}
+
+// TestDeterministic checks the chains by the same network id and seed are
+// same.
+func (t *testChain) TestDeterministic() {
+	a, err := NewChain(DefaultNetworkID, 1)
+	t.NoError(err)
+	defer a.Close()
+	t.NoError(a.NewBlocks(10, 5))
+
+	b, err := NewChain(DefaultNetworkID, 1)
+	t.NoError(err)
+	defer b.Close()
+	t.NoError(b.NewBlocks(10, 5))
+
+	t.Equal(a.LastBlock().Hash, b.LastBlock().Hash)
+	t.Equal(a.Accounts(), b.Accounts())
+}
+
+func TestChain(t *testing.T) {
+	suite.Run(t, new(testChain))
+}
@@ -0,0 +1,19 @@
+package fakesebak
+
+import (
+	"github.com/spikeekips/naru/common"
+)
+
+const (
+	UnknownSnapshotCode = iota + 100
+	NotEnoughAccountsCode
+	InvalidChainOptionsCode
+	StorageNotEmptyCode
+)
+
+var (
+	UnknownSnapshot     = common.NewError(UnknownSnapshotCode, "unknown snapshot")
+	NotEnoughAccounts   = common.NewError(NotEnoughAccountsCode, "not enough accounts to make transactions")
+	InvalidChainOptions = common.NewError(InvalidChainOptionsCode, "invalid chain options")
+	StorageNotEmpty     = common.NewError(StorageNotEmptyCode, "storage is not empty")
+)
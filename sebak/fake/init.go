@@ -0,0 +1,11 @@
+package fakesebak
+
+import (
+	logging "github.com/inconshreveable/log15"
+)
+
+var log logging.Logger = logging.New("module", "fakesebak")
+
+func Log() logging.Logger {
+	return log
+}
@@ -0,0 +1,207 @@
+package fakesebak
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebaknode "boscoin.io/sebak/lib/node"
+	sebakrunner "boscoin.io/sebak/lib/node/runner"
+	"github.com/gorilla/rpc"
+	jsonrpc "github.com/gorilla/rpc/json"
+
+	"github.com/spikeekips/naru/storage"
+)
+
+var JSONRPCPattern = "/jsonrpc"
+
+// Server serves the node info and the `DB.*` JSON-RPC methods of SEBAK from
+// the Chain. The chain only grows, so the snapshot is just the name; the
+// opened snapshot also sees the new blocks.
+type Server struct {
+	sync.RWMutex
+	chain     *Chain
+	server    *httptest.Server
+	snapshots map[string]bool
+	count     uint64
+}
+
+func NewServer(chain *Chain) (*Server, error) {
+	s := &Server{
+		chain:     chain,
+		snapshots: map[string]bool{},
+	}
+
+	rpcServer := rpc.NewServer()
+	rpcServer.RegisterCodec(jsonrpc.NewCodec(), "application/json")
+	if err := rpcServer.RegisterService(&DBService{s: s}, "DB"); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(JSONRPCPattern, rpcServer)
+	mux.HandleFunc(sebakrunner.NodeInfoHandlerPattern, s.nodeInfoHandler)
+
+	s.server = httptest.NewServer(mux)
+
+	log.Debug("fake sebak started", "url", s.server.URL)
+
+	return s, nil
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func (s *Server) Chain() *Chain {
+	return s.chain
+}
+
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Endpoint is the endpoint for node info.
+func (s *Server) Endpoint() *sebakcommon.Endpoint {
+	endpoint, _ := sebakcommon.ParseEndpoint(s.server.URL)
+	return endpoint
+}
+
+func (s *Server) JSONRPCEndpoint() *sebakcommon.Endpoint {
+	endpoint, _ := sebakcommon.ParseEndpoint(s.server.URL + JSONRPCPattern)
+	return endpoint
+}
+
+func (s *Server) NodeInfo() sebaknode.NodeInfo {
+	var nodeInfo sebaknode.NodeInfo
+	nodeInfo.Policy.NetworkID = s.chain.NetworkID()
+
+	return nodeInfo
+}
+
+func (s *Server) nodeInfoHandler(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(s.NodeInfo())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (s *Server) openSnapshot() string {
+	s.Lock()
+	defer s.Unlock()
+
+	s.count++
+	snapshot := fmt.Sprintf("fake-snapshot-%d", s.count)
+	s.snapshots[snapshot] = true
+
+	return snapshot
+}
+
+func (s *Server) releaseSnapshot(snapshot string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, found := s.snapshots[snapshot]; !found {
+		return false
+	}
+	delete(s.snapshots, snapshot)
+
+	return true
+}
+
+func (s *Server) checkSnapshot(snapshot string) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	if _, found := s.snapshots[snapshot]; !found {
+		return UnknownSnapshot.New().SetData("snapshot", snapshot)
+	}
+
+	return nil
+}
+
+// DBService implements the `DB` JSON-RPC service of SEBAK.
+type DBService struct {
+	s *Server
+}
+
+func (d *DBService) OpenSnapshot(r *http.Request, args *sebakrunner.DBOpenSnapshot, result *sebakrunner.DBOpenSnapshotResult) error {
+	result.Snapshot = d.s.openSnapshot()
+	return nil
+}
+
+func (d *DBService) ReleaseSnapshot(r *http.Request, args *sebakrunner.DBReleaseSnapshot, result *sebakrunner.DBReleaseSnapshotResult) error {
+	*result = sebakrunner.DBReleaseSnapshotResult(d.s.releaseSnapshot(args.Snapshot))
+	return nil
+}
+
+func (d *DBService) Has(r *http.Request, args *sebakrunner.DBHasArgs, result *sebakrunner.DBHasResult) error {
+	if err := d.s.checkSnapshot(args.Snapshot); err != nil {
+		return err
+	}
+
+	found, err := d.s.chain.Storage().Has(args.Key)
+	if err != nil {
+		return err
+	}
+	*result = sebakrunner.DBHasResult(found)
+
+	return nil
+}
+
+func (d *DBService) Get(r *http.Request, args *sebakrunner.DBGetArgs, result *sebakrunner.DBGetResult) error {
+	if err := d.s.checkSnapshot(args.Snapshot); err != nil {
+		return err
+	}
+
+	b, err := d.s.chain.Storage().GetRaw(args.Key)
+	if err != nil {
+		return err
+	}
+	result.Value = b
+
+	return nil
+}
+
+// GetIterator returns the items after the cursor like SEBAK; the cursor itself
+// is excluded and the limit is capped by `sebakrunner.MaxLimitListOptions`.
+func (d *DBService) GetIterator(r *http.Request, args *sebakrunner.DBGetIteratorArgs, result *storage.SEBAKDBGetIteratorResult) error {
+	if err := d.s.checkSnapshot(args.Snapshot); err != nil {
+		return err
+	}
+
+	limit := args.Options.Limit
+	if limit < 1 || limit > sebakrunner.MaxLimitListOptions {
+		limit = sebakrunner.MaxLimitListOptions
+	}
+
+	options := storage.NewDefaultListOptions(args.Options.Reverse, args.Options.Cursor, limit)
+	iterFunc, closeFunc, err := d.s.chain.Storage().IteratorRaw(args.Prefix, options)
+	if err != nil {
+		return err
+	}
+	defer closeFunc()
+
+	items := []storage.IterItem{}
+	for {
+		item, next, err := iterFunc()
+		if err != nil {
+			return err
+		} else if !next {
+			break
+		}
+		items = append(items, item)
+	}
+
+	result.Limit = limit
+	result.Items = items
+
+	return nil
+}
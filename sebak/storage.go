@@ -20,6 +20,17 @@ func (s *Storage) New() *Storage {
 	return NewStorage(s.provider.New())
 }
 
+// Ping checks the provider is reachable by opening and releasing new
+// snapshot.
+func (s *Storage) Ping() error {
+	sst := s.New()
+	if err := sst.Provider().Open(); err != nil {
+		return err
+	}
+
+	return sst.Provider().Close()
+}
+
 func (s *Storage) Close() error {
 	return s.provider.Close()
 }
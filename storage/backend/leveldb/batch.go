@@ -1,13 +1,14 @@
 package leveldbstorage
 
 import (
-	"strings"
 	"sync"
+	"time"
 
 	"boscoin.io/sebak/lib/errors"
 	"github.com/syndtr/goleveldb/leveldb"
 
 	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/metrics"
 	"github.com/spikeekips/naru/storage"
 )
 
@@ -152,45 +153,21 @@ func (b *Batch) MultipleDelete(keys ...string) error {
 	return nil
 }
 
-func (b *Batch) Write() error {
-	defer b.Close()
+func (b *Batch) Write() (err error) {
+	defer func(started time.Time) {
+		metrics.ObserveBatchWrite("leveldb", started, err)
+	}(time.Now())
 
-	var events []common.EventItem
-	{
-		b.RLock()
-		events = make([]common.EventItem, len(b.events))
-		copy(events, b.events)
-		b.RUnlock()
-	}
-
-	for _, e := range events { // only OnAfterSave event will be triggered
-		var es []string
-		for _, n := range strings.Fields(e.Events) {
-			if !strings.HasPrefix(n, "OnAfterSave") {
-				continue
-			}
-			es = append(es, n)
-		}
+	defer b.Close()
 
-		storage.Observer.Trigger(strings.Join(es, " "), e.Items...)
-	}
+	// NOTE events, which are added by OnAfterSave hooks, are also collected
+	events := storage.TriggerBeforeCommit(b.eventsFrom)
 
-	err := setError(b.s.Core().Write(b.b, nil))
-	if err != nil {
+	if err = setError(b.s.Core().Write(b.b, nil)); err != nil {
 		return err
 	}
 
-	for _, e := range events { // only non-OnAfterSave event will be triggered
-		var events []string
-		for _, n := range strings.Fields(e.Events) {
-			if strings.HasPrefix(n, "OnAfterSave") {
-				continue
-			}
-			events = append(events, n)
-		}
-
-		storage.Observer.Trigger(strings.Join(events, " "), e.Items...)
-	}
+	storage.TriggerAfterCommit(events)
 
 	return nil
 }
@@ -217,6 +194,20 @@ func (b *Batch) Event(event string, values ...interface{}) {
 	b.events = append(b.events, common.NewEventItem(event, values...))
 }
 
+func (b *Batch) eventsFrom(i int) []common.EventItem {
+	b.RLock()
+	defer b.RUnlock()
+
+	if i >= len(b.events) {
+		return nil
+	}
+
+	events := make([]common.EventItem, len(b.events)-i)
+	copy(events, b.events[i:])
+
+	return events
+}
+
 func (b *Batch) clearEvents() {
 	b.Lock()
 	defer b.Unlock()
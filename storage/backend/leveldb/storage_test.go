@@ -376,6 +376,62 @@ func (t *testLevelDBStorage) TestBatchEventTriggerAsynchronous() {
 	}
 }
 
+func (t *testLevelDBStorage) TestBatchEventAddedByHookTriggerAfterWrite() {
+	hook := "OnAfterSave" + common.RandomUUID()
+	event := common.RandomUUID()
+	defer storage.Observer.Off(hook)
+	defer storage.Observer.Off(event)
+
+	var written bool
+	storage.Observer.Sync(hook, func(st storage.Storage, value string) {
+		st.Event(event, value)
+	})
+
+	fired := make(chan bool, 1)
+	storage.Observer.On(event, func(args ...interface{}) {
+		var exists bool
+		t.NoError(t.s.Get("showme", &exists))
+		written = exists
+		fired <- true
+	})
+
+	batch, err := t.s.Batch()
+	t.NoError(err)
+	t.NoError(batch.Insert("showme", true))
+	batch.Event(hook, batch, "findme")
+	t.NoError(batch.Write())
+
+	select {
+	case <-fired:
+		t.True(written)
+	case <-time.After(time.Second * 1):
+		t.Fail("event added by hook was not triggered")
+	}
+}
+
+func (t *testLevelDBStorage) TestBatchEventDiscardedByCancel() {
+	event := common.RandomUUID()
+	defer storage.Observer.Off(event)
+
+	fired := make(chan bool, 1)
+	storage.Observer.On(event, func(args ...interface{}) {
+		fired <- true
+	})
+
+	batch, err := t.s.Batch()
+	t.NoError(err)
+	t.NoError(batch.Insert("showme", "findme"))
+	batch.Event(event, "findme")
+	t.NoError(batch.Cancel())
+	t.NoError(batch.Write())
+
+	select {
+	case <-fired:
+		t.Fail("canceled event was triggered")
+	case <-time.After(time.Millisecond * 500):
+	}
+}
+
 func TestLevelDBStorage(t *testing.T) {
 	suite.Run(t, new(testLevelDBStorage))
 }
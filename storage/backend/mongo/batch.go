@@ -2,14 +2,15 @@ package mongostorage
 
 import (
 	"context"
-	"strings"
 	"sync"
+	"time"
 
 	logging "github.com/inconshreveable/log15"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/metrics"
 	"github.com/spikeekips/naru/storage"
 )
 
@@ -59,6 +60,10 @@ func (b *Batch) Batch() (storage.BatchStorage, error) {
 }
 
 func (b *Batch) Write() (err error) {
+	defer func(started time.Time) {
+		metrics.ObserveBatchWrite("mongo", started, err)
+	}(time.Now())
+
 	defer func() {
 		if err == nil {
 			return
@@ -69,25 +74,8 @@ func (b *Batch) Write() (err error) {
 
 	defer b.Close()
 
-	var events []common.EventItem
-	{
-		b.RLock()
-		events = make([]common.EventItem, len(b.events))
-		copy(events, b.events)
-		b.RUnlock()
-	}
-
-	for _, e := range events { // only OnAfterSave event will be triggered
-		var es []string
-		for _, n := range strings.Fields(e.Events) {
-			if !strings.HasPrefix(n, "OnAfterSave") {
-				continue
-			}
-			es = append(es, n)
-		}
-
-		storage.Observer.Trigger(strings.Join(es, " "), e.Items...)
-	}
+	// NOTE events, which are added by OnAfterSave hooks, are also collected
+	events := storage.TriggerBeforeCommit(b.eventsFrom)
 
 	b.RLock()
 	ops := map[string][]mongo.WriteModel{}
@@ -117,17 +105,7 @@ func (b *Batch) Write() (err error) {
 		)
 	}
 
-	for _, e := range events { // only non-OnAfterSave event will be triggered
-		var events []string
-		for _, n := range strings.Fields(e.Events) {
-			if strings.HasPrefix(n, "OnAfterSave") {
-				continue
-			}
-			events = append(events, n)
-		}
-
-		storage.Observer.Trigger(strings.Join(events, " "), e.Items...)
-	}
+	storage.TriggerAfterCommit(events)
 
 	return
 }
@@ -254,6 +232,20 @@ func (b *Batch) Event(event string, values ...interface{}) {
 	return
 }
 
+func (b *Batch) eventsFrom(i int) []common.EventItem {
+	b.RLock()
+	defer b.RUnlock()
+
+	if i >= len(b.events) {
+		return nil
+	}
+
+	events := make([]common.EventItem, len(b.events)-i)
+	copy(events, b.events[i:])
+
+	return events
+}
+
 func (b *Batch) clearEvents() {
 	b.Lock()
 	defer b.Unlock()
@@ -4,11 +4,13 @@ import "github.com/spikeekips/naru/element"
 
 var (
 	CollectionByPrefix = map[string]string{
-		element.InternalPrefix[:2]:    "internal",
-		element.BlockPrefix[:2]:       "block",
-		element.TransactionPrefix[:2]: "transaction",
-		element.AccountPrefix[:2]:     "account",
-		element.OperationPrefix[:2]:   "operation",
+		element.InternalPrefix[:2]:     "internal",
+		element.BlockPrefix[:2]:        "block",
+		element.TransactionPrefix[:2]:  "transaction",
+		element.AccountPrefix[:2]:      "account",
+		element.AccountStatePrefix[:2]: "accountstate",
+		element.OperationPrefix[:2]:    "operation",
+		element.WebhookPrefix[:2]:      "webhook",
 	}
 )
 
@@ -0,0 +1,198 @@
+package sqlitestorage
+
+import (
+	"sync"
+	"time"
+
+	logging "github.com/inconshreveable/log15"
+
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/metrics"
+	"github.com/spikeekips/naru/storage"
+)
+
+type statement struct {
+	query string
+	args  []interface{}
+}
+
+// Batch collects the statements and executes them in one transaction by
+// Write().
+type Batch struct {
+	sync.RWMutex
+	s          *Storage
+	statements []statement
+	events     []common.EventItem
+	log        logging.Logger
+}
+
+func NewBatch(s *Storage) (*Batch, error) {
+	return &Batch{
+		s:   s,
+		log: log.New(logging.Ctx{"name": "batch"}),
+	}, nil
+}
+
+func (b *Batch) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.statements = nil
+	b.events = nil
+
+	return nil
+}
+
+func (b *Batch) Initialize() error {
+	return nil
+}
+
+func (b *Batch) Batch() (storage.BatchStorage, error) {
+	return b, nil
+}
+
+func (b *Batch) Write() (err error) {
+	defer func(started time.Time) {
+		metrics.ObserveBatchWrite("sqlite", started, err)
+	}(time.Now())
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		b.log.Error("failed to write", "error", err)
+	}()
+
+	defer b.Close()
+
+	var statements []statement
+	{
+		b.RLock()
+		statements = make([]statement, len(b.statements))
+		copy(statements, b.statements)
+		b.RUnlock()
+	}
+
+	// NOTE events, which are added by OnAfterSave hooks, are also collected
+	events := storage.TriggerBeforeCommit(b.eventsFrom)
+
+	// NOTE OnAfterSave hooks may add new statements
+	b.RLock()
+	statements = append(statements, b.statements[len(statements):]...)
+	b.RUnlock()
+
+	tx, err := b.s.DB().Begin()
+	if err != nil {
+		return
+	}
+
+	for _, s := range statements {
+		if _, err = tx.Exec(s.query, s.args...); err != nil {
+			tx.Rollback()
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	b.log.Debug("write", "statements", len(statements))
+
+	storage.TriggerAfterCommit(events)
+
+	return
+}
+
+func (b *Batch) Cancel() error {
+	return b.Close()
+}
+
+func (b *Batch) Has(k string) (bool, error) {
+	return b.s.Has(k)
+}
+
+func (b *Batch) Get(k string, v interface{}) error {
+	return b.s.Get(k, v)
+}
+
+func (b *Batch) Iterator(prefix string, v interface{}, options storage.ListOptions) (func() (storage.Record, bool, error), func(), error) {
+	return b.s.Iterator(prefix, v, options)
+}
+
+func (b *Batch) Insert(k string, v interface{}) error {
+	if err := b.s.MustNotExist(k); err != nil {
+		return err
+	}
+
+	return b.put(k, v)
+}
+
+func (b *Batch) Update(k string, v interface{}) error {
+	if err := b.s.MustExist(k); err != nil {
+		return err
+	}
+
+	return b.put(k, v)
+}
+
+func (b *Batch) put(k string, v interface{}) error {
+	q, args, err := upsertStatement(k, v)
+	if err != nil {
+		return err
+	}
+
+	b.add(q, args...)
+
+	return nil
+}
+
+func (b *Batch) Delete(k string) error {
+	if err := b.s.MustExist(k); err != nil {
+		return err
+	}
+
+	t, err := GetTable(k)
+	if err != nil {
+		return err
+	}
+
+	q, args := t.deleteStatement(k)
+	b.add(q, args...)
+
+	return nil
+}
+
+// Exec adds the raw statement to the batch.
+func (b *Batch) Exec(query string, args ...interface{}) {
+	b.add(query, args...)
+}
+
+func (b *Batch) add(query string, args ...interface{}) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.statements = append(b.statements, statement{query: query, args: args})
+}
+
+func (b *Batch) Event(event string, values ...interface{}) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.events = append(b.events, common.NewEventItem(event, values...))
+}
+
+func (b *Batch) eventsFrom(i int) []common.EventItem {
+	b.RLock()
+	defer b.RUnlock()
+
+	if i >= len(b.events) {
+		return nil
+	}
+
+	events := make([]common.EventItem, len(b.events)-i)
+	copy(events, b.events[i:])
+
+	return events
+}
@@ -0,0 +1,16 @@
+package sqlitestorage
+
+import (
+	"github.com/spikeekips/naru/common"
+)
+
+const (
+	_ = iota
+	UnknownTableCode
+	InvalidColumnValueCode
+)
+
+var (
+	UnknownTable       = common.NewError(UnknownTableCode, "failed to guess table by key")
+	InvalidColumnValue = common.NewError(InvalidColumnValueCode, "failed to extract column value")
+)
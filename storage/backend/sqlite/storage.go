@@ -0,0 +1,387 @@
+package sqlitestorage
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/storage"
+)
+
+var defaultBusyTimeout = 5000 // milliseconds
+
+type Storage struct {
+	db     *sql.DB
+	config config.SQLiteStorage
+}
+
+func NewStorage(c *config.SQLiteStorage) (*Storage, error) {
+	path := c.RealPath
+	if len(path) < 1 {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		path = c.RealPath
+	}
+
+	var dsn string
+	if path == ":memory:" {
+		// NOTE in-memory database is not shared between connections, so only
+		// one connection is allowed.
+		dsn = fmt.Sprintf("file:naru-%s?mode=memory&_txlock=immediate", common.SequentialUUID())
+	} else {
+		dsn = fmt.Sprintf(
+			"file:%s?_journal_mode=WAL&_busy_timeout=%d&_txlock=immediate",
+			path,
+			defaultBusyTimeout,
+		)
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if path == ":memory:" {
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &Storage{db: db, config: *c}
+	if err := s.createTables(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (b *Storage) DB() *sql.DB {
+	return b.db
+}
+
+// Table returns the table name for the key.
+func (b *Storage) Table(key string) (string, error) {
+	t, err := GetTable(key)
+	if err != nil {
+		return "", err
+	}
+
+	return t.QuotedName(), nil
+}
+
+func (b *Storage) tables() []Table {
+	var tables []Table
+	for _, t := range TableByPrefix {
+		tables = append(tables, t)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	return tables
+}
+
+func (b *Storage) createTables() error {
+	for _, t := range b.tables() {
+		for _, q := range t.createStatements() {
+			if _, err := b.db.Exec(q); err != nil {
+				log.Error("failed to create table", "table", t.Name, "query", q, "error", err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *Storage) Close() error {
+	return b.db.Close()
+}
+
+func (b *Storage) Initialize() error {
+	for _, t := range b.tables() {
+		if _, err := b.db.Exec(t.dropStatement()); err != nil {
+			return err
+		}
+	}
+
+	return b.createTables()
+}
+
+func (b *Storage) Batch() (storage.BatchStorage, error) {
+	return NewBatch(b)
+}
+
+func (b *Storage) Has(k string) (bool, error) {
+	t, err := GetTable(k)
+	if err != nil {
+		return false, err
+	}
+
+	var found int
+	err = b.db.QueryRow(
+		fmt.Sprintf("SELECT 1 FROM %s WHERE k = ?", t.QuotedName()),
+		k,
+	).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (b *Storage) MustExist(k string) error {
+	exists, err := b.Has(k)
+	if err != nil {
+		return err
+	} else if !exists {
+		return storage.NotFound.New()
+	}
+
+	return nil
+}
+
+func (b *Storage) MustNotExist(k string) error {
+	exists, err := b.Has(k)
+	if err != nil {
+		return err
+	} else if exists {
+		return storage.AlreadyExists.New()
+	}
+
+	return nil
+}
+
+func (b *Storage) Get(k string, v interface{}) error {
+	t, err := GetTable(k)
+	if err != nil {
+		return err
+	}
+
+	var encoded []byte
+	err = b.db.QueryRow(
+		fmt.Sprintf("SELECT v FROM %s WHERE k = ?", t.QuotedName()),
+		k,
+	).Scan(&encoded)
+	if err == sql.ErrNoRows {
+		return storage.NotFound.New()
+	} else if err != nil {
+		return err
+	}
+
+	if err := storage.Deserialize(encoded, v); err != nil {
+		return storage.DecodeValueFailed.New().SetData("error", err.Error())
+	}
+
+	return nil
+}
+
+// iteratorPageSize is the number of rows, which Iterator fetches at once.
+var iteratorPageSize uint64 = 1000
+
+// Iterator iterates the records, which have the given key prefix. The rows are
+// fetched by the page of `iteratorPageSize` with the last key as cursor, so
+// the database connection is not held during iteration and all the records
+// are not loaded in memory at once.
+func (b *Storage) Iterator(prefix string, v interface{}, opt storage.ListOptions) (func() (storage.Record, bool, error), func(), error) {
+	t, err := GetTable(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reverse bool
+	var cursor []byte
+	var limit uint64
+	if opt != nil {
+		reverse = opt.Reverse()
+		cursor = opt.Cursor()
+		limit = opt.Limit()
+	}
+
+	var records [][2][]byte
+	var n int
+	var fetched uint64
+	var done bool
+
+	fetch := func() error {
+		size := iteratorPageSize
+		if limit > 0 && limit-fetched < size {
+			size = limit - fetched
+		}
+
+		var err error
+		if records, err = b.fetchPage(t, prefix, cursor, reverse, size); err != nil {
+			return err
+		}
+		n = 0
+		fetched += uint64(len(records))
+
+		if uint64(len(records)) < size || (limit > 0 && fetched >= limit) {
+			done = true
+		}
+		if len(records) > 0 {
+			cursor = records[len(records)-1][0]
+		}
+
+		return nil
+	}
+
+	if err := fetch(); err != nil {
+		return nil, nil, err
+	}
+
+	return func() (storage.Record, bool, error) {
+			if n >= len(records) {
+				if done {
+					return storage.Record{}, false, nil
+				}
+				if err := fetch(); err != nil {
+					return storage.Record{}, false, err
+				} else if len(records) < 1 {
+					return storage.Record{}, false, nil
+				}
+			}
+
+			r := records[n]
+			n++
+
+			nv := reflect.New(reflect.TypeOf(v)).Interface()
+			if err := storage.Deserialize(r[1], nv); err != nil {
+				return storage.Record{}, false, err
+			}
+
+			return storage.NewRecord(string(r[0]), reflect.ValueOf(nv).Elem().Interface()), true, nil
+		}, func() {
+			records = nil
+			done = true
+		},
+		nil
+}
+
+// fetchPage fetches the rows after the cursor.
+func (b *Storage) fetchPage(t Table, prefix string, cursor []byte, reverse bool, limit uint64) ([][2][]byte, error) {
+	if limit < 1 {
+		return nil, nil
+	}
+
+	where := "k >= ? AND k < ?"
+	args := []interface{}{prefix, PrefixLimit(prefix)}
+
+	order := "ASC"
+	if reverse {
+		order = "DESC"
+	}
+
+	if len(cursor) > 0 {
+		if reverse {
+			where += " AND k < ?"
+		} else {
+			where += " AND k > ?"
+		}
+		args = append(args, string(cursor))
+	}
+
+	rows, err := b.db.Query(
+		fmt.Sprintf("SELECT k, v FROM %s WHERE %s ORDER BY k %s LIMIT %d", t.QuotedName(), where, order, limit),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records [][2][]byte
+	for rows.Next() {
+		var k string
+		var encoded []byte
+		if err := rows.Scan(&k, &encoded); err != nil {
+			return nil, err
+		}
+		records = append(records, [2][]byte{[]byte(k), encoded})
+	}
+
+	return records, rows.Err()
+}
+
+func (b *Storage) Insert(k string, v interface{}) error {
+	if err := b.MustNotExist(k); err != nil {
+		return err
+	}
+
+	return b.put(k, v)
+}
+
+func (b *Storage) Update(k string, v interface{}) error {
+	if err := b.MustExist(k); err != nil {
+		return err
+	}
+
+	return b.put(k, v)
+}
+
+func (b *Storage) put(k string, v interface{}) error {
+	q, args, err := upsertStatement(k, v)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.db.Exec(q, args...)
+	return err
+}
+
+func (b *Storage) Delete(k string) error {
+	if err := b.MustExist(k); err != nil {
+		return err
+	}
+
+	t, err := GetTable(k)
+	if err != nil {
+		return err
+	}
+
+	q, args := t.deleteStatement(k)
+	_, err = b.db.Exec(q, args...)
+	return err
+}
+
+func (b *Storage) Event(event string, values ...interface{}) {
+	storage.Observer.Trigger(event, values...)
+}
+
+func upsertStatement(k string, v interface{}) (string, []interface{}, error) {
+	t, err := GetTable(k)
+	if err != nil {
+		return "", nil, err
+	}
+
+	encoded, err := storage.Serialize(v)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return t.upsertStatement(k, encoded)
+}
+
+// PrefixLimit returns the smallest string, which is greater than all the
+// strings with the given prefix.
+func PrefixLimit(prefix string) string {
+	p := []byte(prefix)
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i] < 0xff {
+			p[i]++
+			return string(p[:i+1])
+		}
+	}
+
+	return string([]byte{0xff})
+}
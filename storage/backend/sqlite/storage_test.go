@@ -0,0 +1,239 @@
+package sqlitestorage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/storage"
+)
+
+type testSQLiteStorage struct {
+	suite.Suite
+	s *Storage
+}
+
+func (t *testSQLiteStorage) SetupTest() {
+	s, err := NewStorage(&config.SQLiteStorage{Path: "sqlite://:memory:"})
+	t.NoError(err)
+	t.s = s
+}
+
+func (t *testSQLiteStorage) TearDownTest() {
+	t.s.Close()
+}
+
+func (t *testSQLiteStorage) TestInsertAndGet() {
+	key := element.InternalPrefix + "showme"
+
+	err := t.s.Insert(key, "findme")
+	t.NoError(err)
+
+	var v string
+	err = t.s.Get(key, &v)
+	t.NoError(err)
+	t.Equal("findme", v)
+
+	err = t.s.Insert(key, "findme")
+	t.True(storage.AlreadyExists.Equal(err))
+}
+
+func (t *testSQLiteStorage) TestGetNotFound() {
+	var v string
+	err := t.s.Get(element.InternalPrefix+"showme", &v)
+	t.True(storage.NotFound.Equal(err))
+
+	err = t.s.Update(element.InternalPrefix+"showme", "findme")
+	t.True(storage.NotFound.Equal(err))
+
+	err = t.s.Delete(element.InternalPrefix + "showme")
+	t.True(storage.NotFound.Equal(err))
+}
+
+func (t *testSQLiteStorage) TestUnknownTable() {
+	err := t.s.Insert("99showme", "findme")
+	t.True(UnknownTable.Equal(err))
+}
+
+func (t *testSQLiteStorage) TestUpdateAndDelete() {
+	key := element.InternalPrefix + "showme"
+	t.NoError(t.s.Insert(key, "findme"))
+	t.NoError(t.s.Update(key, "killme"))
+
+	var v string
+	t.NoError(t.s.Get(key, &v))
+	t.Equal("killme", v)
+
+	t.NoError(t.s.Delete(key))
+
+	found, err := t.s.Has(key)
+	t.NoError(err)
+	t.False(found)
+}
+
+func (t *testSQLiteStorage) TestExtractedColumns() {
+	key := element.AccountPrefix + "GABC"
+	err := t.s.Insert(key, map[string]interface{}{
+		"address":        "GABC",
+		"balance":        "1000000000000",
+		"created_height": 3,
+	})
+	t.NoError(err)
+
+	var address string
+	var balance, created int64
+	err = t.s.DB().QueryRow(
+		`SELECT "address", "balance", "created_block" FROM "account" WHERE k = ?`,
+		key,
+	).Scan(&address, &balance, &created)
+	t.NoError(err)
+	t.Equal("GABC", address)
+	t.Equal(int64(1000000000000), balance)
+	t.Equal(int64(3), created)
+}
+
+func (t *testSQLiteStorage) TestExtractedColumnsOverflow() {
+	// NOTE the amount over int64 can not be compared with the others
+	err := t.s.Insert(element.AccountPrefix+"GABC", map[string]interface{}{
+		"address": "GABC",
+		"balance": "9223372036854775808",
+	})
+	t.True(InvalidColumnValue.Equal(err))
+
+	found, err := t.s.Has(element.AccountPrefix + "GABC")
+	t.NoError(err)
+	t.False(found)
+}
+
+func (t *testSQLiteStorage) TestIterator() {
+	prefix := element.InternalPrefix + "iter-"
+	for i := 0; i < 10; i++ {
+		t.NoError(t.s.Insert(fmt.Sprintf("%s%02d", prefix, i), i))
+	}
+	t.NoError(t.s.Insert(element.InternalPrefix+"other", 100))
+
+	collect := func(options storage.ListOptions) []int {
+		iterFunc, closeFunc, err := t.s.Iterator(prefix, 0, options)
+		t.NoError(err)
+		defer closeFunc()
+
+		var l []int
+		for {
+			r, next, err := iterFunc()
+			t.NoError(err)
+			if !next {
+				break
+			}
+			l = append(l, r.Value.(int))
+		}
+
+		return l
+	}
+
+	t.Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, collect(nil))
+	t.Equal([]int{9, 8, 7}, collect(storage.NewDefaultListOptions(true, nil, 3)))
+	t.Equal(
+		[]int{4, 5},
+		collect(storage.NewDefaultListOptions(false, []byte(prefix+"03"), 2)),
+	)
+	t.Equal(
+		[]int{2, 1, 0},
+		collect(storage.NewDefaultListOptions(true, []byte(prefix+"03"), 0)),
+	)
+}
+
+func (t *testSQLiteStorage) TestIteratorPages() {
+	defer func(size uint64) { iteratorPageSize = size }(iteratorPageSize)
+	iteratorPageSize = 3
+
+	prefix := element.InternalPrefix + "iter-"
+	for i := 0; i < 10; i++ {
+		t.NoError(t.s.Insert(fmt.Sprintf("%s%02d", prefix, i), i))
+	}
+
+	var written int
+	collect := func(options storage.ListOptions) []int {
+		iterFunc, closeFunc, err := t.s.Iterator(prefix, 0, options)
+		t.NoError(err)
+		defer closeFunc()
+
+		var l []int
+		for {
+			r, next, err := iterFunc()
+			t.NoError(err)
+			if !next {
+				break
+			}
+			l = append(l, r.Value.(int))
+
+			// NOTE the connection is not held during iteration
+			t.NoError(t.s.Insert(fmt.Sprintf("%swritten-%02d", element.InternalPrefix, written), written))
+			written++
+		}
+
+		return l
+	}
+
+	t.Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, collect(nil))
+	t.Equal([]int{9, 8, 7, 6, 5, 4, 3}, collect(storage.NewDefaultListOptions(true, nil, 7)))
+	t.Equal(
+		[]int{2, 1, 0},
+		collect(storage.NewDefaultListOptions(true, []byte(prefix+"03"), 0)),
+	)
+}
+
+func (t *testSQLiteStorage) TestBatch() {
+	key := element.InternalPrefix + "showme"
+	t.NoError(t.s.Insert(key, "findme"))
+
+	batch, err := t.s.Batch()
+	t.NoError(err)
+
+	t.NoError(batch.Update(key, "killme"))
+	t.NoError(batch.Insert(element.InternalPrefix+"new", "eatme"))
+
+	{ // not yet written
+		var v string
+		t.NoError(t.s.Get(key, &v))
+		t.Equal("findme", v)
+
+		found, err := t.s.Has(element.InternalPrefix + "new")
+		t.NoError(err)
+		t.False(found)
+	}
+
+	t.NoError(batch.Write())
+
+	var v string
+	t.NoError(t.s.Get(key, &v))
+	t.Equal("killme", v)
+
+	t.NoError(t.s.Get(element.InternalPrefix+"new", &v))
+	t.Equal("eatme", v)
+}
+
+func (t *testSQLiteStorage) TestBatchFailed() {
+	batch, err := t.s.Batch()
+	t.NoError(err)
+
+	t.NoError(batch.Insert(element.InternalPrefix+"showme", "findme"))
+	batch.(*Batch).Exec("INSERT INTO unknown_table VALUES (1)")
+
+	t.Error(batch.Write())
+
+	found, err := t.s.Has(element.InternalPrefix + "showme")
+	t.NoError(err)
+	t.False(found)
+}
+
+func (t *testSQLiteStorage) TestPrefixLimit() {
+	t.Equal("1001", PrefixLimit("1000"))
+	t.Equal("2", PrefixLimit("1\xff"))
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	suite.Run(t, new(testSQLiteStorage))
+}
@@ -0,0 +1,247 @@
+package sqlitestorage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spikeekips/naru/element"
+)
+
+// Column is extracted from the JSON value by `Path` and stored in the table
+// with the raw value, so the column can be indexed and queried.
+type Column struct {
+	Name string
+	Type string // "TEXT" or "INTEGER"
+	Path []string
+}
+
+type Table struct {
+	Name    string
+	Columns []Column
+	Indexes [][]string
+}
+
+var (
+	TableByPrefix = map[string]Table{
+		element.InternalPrefix[:2]: Table{Name: "internal"},
+		element.BlockPrefix[:2]: Table{
+			Name: "block",
+			Columns: []Column{
+				{Name: "hash", Type: "TEXT", Path: []string{"Hash"}},
+				{Name: "height", Type: "INTEGER", Path: []string{"Header", "Height"}},
+				{Name: "prev_block_hash", Type: "TEXT", Path: []string{"Header", "PrevBlockHash"}},
+				{Name: "confirmed", Type: "TEXT", Path: []string{"Confirmed"}},
+			},
+			Indexes: [][]string{{"hash"}, {"height"}, {"prev_block_hash"}},
+		},
+		element.TransactionPrefix[:2]: Table{
+			Name: "transaction",
+			Columns: []Column{
+				{Name: "hash", Type: "TEXT", Path: []string{"hash"}},
+				{Name: "block", Type: "INTEGER", Path: []string{"block"}},
+				{Name: "source", Type: "TEXT", Path: []string{"source"}},
+				{Name: "confirmed", Type: "TEXT", Path: []string{"confirmed"}},
+			},
+			Indexes: [][]string{{"hash"}, {"block"}, {"source", "block"}},
+		},
+		element.AccountPrefix[:2]: Table{
+			Name: "account",
+			Columns: []Column{
+				{Name: "address", Type: "TEXT", Path: []string{"address"}},
+				{Name: "balance", Type: "INTEGER", Path: []string{"balance"}},
+				{Name: "linked", Type: "TEXT", Path: []string{"linked"}},
+				{Name: "created_block", Type: "INTEGER", Path: []string{"created_height"}},
+			},
+			Indexes: [][]string{{"address"}, {"balance"}, {"linked"}, {"created_block"}},
+		},
+		element.AccountStatePrefix[:2]: Table{
+			Name: "account_state",
+			Columns: []Column{
+				{Name: "address", Type: "TEXT", Path: []string{"Account", "address"}},
+				{Name: "height", Type: "INTEGER", Path: []string{"Height"}},
+			},
+			Indexes: [][]string{{"address", "height"}, {"height"}},
+		},
+		element.OperationPrefix[:2]: Table{
+			Name: "operation",
+			Columns: []Column{
+				{Name: "hash", Type: "TEXT", Path: []string{"hash"}},
+				{Name: "tx_hash", Type: "TEXT", Path: []string{"tx_hash"}},
+				{Name: "type", Type: "TEXT", Path: []string{"type"}},
+				{Name: "source", Type: "TEXT", Path: []string{"source"}},
+				{Name: "target", Type: "TEXT", Path: []string{"target"}},
+				{Name: "block", Type: "INTEGER", Path: []string{"block"}},
+				{Name: "amount", Type: "INTEGER", Path: []string{"amount"}},
+			},
+			Indexes: [][]string{
+				{"hash"}, {"tx_hash"}, {"type"}, {"source", "block"}, {"target", "block"}, {"block"},
+				{"source", "type", "block"}, {"target", "type", "block"}, {"source", "target", "block"},
+			},
+		},
+		element.WebhookPrefix[:2]: Table{Name: "webhook"},
+	}
+)
+
+func GetTable(key string) (Table, error) {
+	if len(key) < 2 {
+		return Table{}, UnknownTable.New()
+	}
+
+	t, ok := TableByPrefix[key[:2]]
+	if !ok {
+		return Table{}, UnknownTable.New()
+	}
+
+	return t, nil
+}
+
+// QuotedName returns the quoted table name; some table name like
+// 'transaction' is reserved by SQL.
+func (t Table) QuotedName() string {
+	return quote(t.Name)
+}
+
+func (t Table) createStatements() []string {
+	columns := []string{"k TEXT PRIMARY KEY", "v BLOB"}
+	for _, c := range t.Columns {
+		columns = append(columns, fmt.Sprintf("%s %s", quote(c.Name), c.Type))
+	}
+
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", t.QuotedName(), strings.Join(columns, ", ")),
+	}
+
+	for _, index := range t.Indexes {
+		var quoted []string
+		for _, c := range index {
+			quoted = append(quoted, quote(c))
+		}
+
+		statements = append(
+			statements,
+			fmt.Sprintf(
+				"CREATE INDEX IF NOT EXISTS %s ON %s (%s)",
+				quote(fmt.Sprintf("_naru_v0_%s_%s", t.Name, strings.Join(index, "_"))),
+				t.QuotedName(),
+				strings.Join(quoted, ", "),
+			),
+		)
+	}
+
+	return statements
+}
+
+func (t Table) dropStatement() string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s", t.QuotedName())
+}
+
+// upsertStatement returns the query and it's arguments to insert or replace
+// the record.
+func (t Table) upsertStatement(k string, encoded []byte) (string, []interface{}, error) {
+	names := []string{"k", "v"}
+	args := []interface{}{k, encoded}
+
+	if len(t.Columns) > 0 {
+		values, err := t.extract(encoded)
+		if err != nil {
+			return "", nil, err
+		}
+
+		for i, c := range t.Columns {
+			names = append(names, quote(c.Name))
+			args = append(args, values[i])
+		}
+	}
+
+	placeholders := strings.TrimRight(strings.Repeat("?, ", len(names)), ", ")
+
+	return fmt.Sprintf(
+		"INSERT OR REPLACE INTO %s (%s) VALUES (%s)",
+		t.QuotedName(),
+		strings.Join(names, ", "),
+		placeholders,
+	), args, nil
+}
+
+func (t Table) deleteStatement(k string) (string, []interface{}) {
+	return fmt.Sprintf("DELETE FROM %s WHERE k = ?", t.QuotedName()), []interface{}{k}
+}
+
+// extract extracts the column values from the JSON encoded value.
+func (t Table) extract(encoded []byte) ([]interface{}, error) {
+	var i interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&i); err != nil {
+		return nil, InvalidColumnValue.New().SetData("error", err.Error())
+	}
+
+	var values []interface{}
+	for _, c := range t.Columns {
+		v, err := c.value(i)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+func (c Column) value(i interface{}) (interface{}, error) {
+	for _, p := range c.Path {
+		m, ok := i.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+
+		if i, ok = m[p]; !ok {
+			return nil, nil
+		}
+	}
+
+	switch v := i.(type) {
+	case nil:
+		return nil, nil
+	case json.Number:
+		if c.Type == "INTEGER" {
+			n, err := v.Int64()
+			if err != nil {
+				return nil, InvalidColumnValue.New().SetData("column", c.Name).SetData("error", err.Error())
+			}
+			return n, nil
+		}
+		return v.String(), nil
+	case string:
+		if c.Type == "INTEGER" { // sebakcommon.Amount is encoded as string
+			if len(v) < 1 {
+				return nil, nil
+			}
+
+			// NOTE the value, which does not fit in int64 is not stored as
+			// TEXT; sqlite compares TEXT greater than any INTEGER, so the
+			// comparison like `amount > N` would be broken.
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, InvalidColumnValue.New().SetData("column", c.Name).SetData("error", err.Error())
+			}
+			return n, nil
+		}
+		return v, nil
+	case bool:
+		return v, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, InvalidColumnValue.New().SetData("column", c.Name).SetData("error", err.Error())
+		}
+		return string(b), nil
+	}
+}
+
+func quote(s string) string {
+	return fmt.Sprintf(`"%s"`, strings.Replace(s, `"`, `""`, -1))
+}
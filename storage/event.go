@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"strings"
+
 	"github.com/spikeekips/naru/common"
 )
 
@@ -11,3 +13,54 @@ var (
 func init() {
 	Observer = common.NewObservable("storage")
 }
+
+// beforeCommitEventPrefix is the prefix of the events, which are triggered
+// before the batch is written; their hooks can add records into the same
+// batch.
+const beforeCommitEventPrefix string = "OnAfterSave"
+
+func filterEvents(events string, beforeCommit bool) string {
+	var es []string
+	for _, n := range strings.Fields(events) {
+		if strings.HasPrefix(n, beforeCommitEventPrefix) != beforeCommit {
+			continue
+		}
+		es = append(es, n)
+	}
+
+	return strings.Join(es, " ")
+}
+
+// TriggerBeforeCommit triggers the "OnAfterSave" events of batch before the
+// batch is written. The hooks of these events can add the new events into the
+// batch; `fetch` returns the events of batch from the given index, so the
+// added events are also collected and triggered. The collected events should
+// be passed to TriggerAfterCommit only after the batch is successfully
+// written.
+func TriggerBeforeCommit(fetch func(int) []common.EventItem) []common.EventItem {
+	var events []common.EventItem
+	for {
+		added := fetch(len(events))
+		if len(added) < 1 {
+			break
+		}
+		events = append(events, added...)
+
+		for _, e := range added {
+			if es := filterEvents(e.Events, true); len(es) > 0 {
+				Observer.Trigger(es, e.Items...)
+			}
+		}
+	}
+
+	return events
+}
+
+// TriggerAfterCommit triggers the events except "OnAfterSave" events.
+func TriggerAfterCommit(events []common.EventItem) {
+	for _, e := range events {
+		if es := filterEvents(e.Events, false); len(es) > 0 {
+			Observer.Trigger(es, e.Items...)
+		}
+	}
+}
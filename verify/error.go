@@ -0,0 +1,13 @@
+package verify
+
+import (
+	"github.com/spikeekips/naru/common"
+)
+
+const (
+	FailedToGetRemoteBlocksCode = iota + 100
+)
+
+var (
+	FailedToGetRemoteBlocks = common.NewError(FailedToGetRemoteBlocksCode, "failed to get blocks from sebak")
+)
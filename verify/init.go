@@ -0,0 +1,11 @@
+package verify
+
+import (
+	logging "github.com/inconshreveable/log15"
+)
+
+var log logging.Logger = logging.New("module", "verify")
+
+func Log() logging.Logger {
+	return log
+}
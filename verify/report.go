@@ -0,0 +1,130 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type DiffKind string
+
+const (
+	DiffBlock       DiffKind = "block"
+	DiffTransaction DiffKind = "transaction"
+	DiffOperation   DiffKind = "operation"
+	DiffAccount     DiffKind = "account"
+)
+
+// Diff is the mismatch between the local and the remote.
+type Diff struct {
+	Kind    DiffKind    `json:"kind"`
+	Height  uint64      `json:"height"`
+	Key     string      `json:"key,omitempty"`
+	Remote  interface{} `json:"remote,omitempty"`
+	Local   interface{} `json:"local,omitempty"`
+	Message string      `json:"message"`
+}
+
+func NewDiff(kind DiffKind, height uint64, remote, local interface{}, message string) Diff {
+	return Diff{
+		Kind:    kind,
+		Height:  height,
+		Remote:  remote,
+		Local:   local,
+		Message: message,
+	}
+}
+
+func (d Diff) SetKey(key string) Diff {
+	d.Key = key
+	return d
+}
+
+func (d Diff) String() string {
+	s := fmt.Sprintf("[%s] height=%d", d.Kind, d.Height)
+	if len(d.Key) > 0 {
+		s += fmt.Sprintf(" key=%s", d.Key)
+	}
+	s += fmt.Sprintf(": %s", d.Message)
+	if d.Remote != nil || d.Local != nil {
+		s += fmt.Sprintf(" (remote=%v local=%v)", d.Remote, d.Local)
+	}
+
+	return s
+}
+
+type Report struct {
+	Start        uint64    `json:"start"`
+	End          uint64    `json:"end"`
+	Blocks       uint64    `json:"blocks"`
+	Transactions uint64    `json:"transactions"`
+	Operations   uint64    `json:"operations"`
+	Accounts     uint64    `json:"accounts"`
+	Diffs        []Diff    `json:"diffs"`
+	Started      time.Time `json:"started"`
+	Ended        time.Time `json:"ended"`
+}
+
+func NewReport(start, end uint64) Report {
+	return Report{
+		Start:   start,
+		End:     end,
+		Diffs:   []Diff{},
+		Started: time.Now(),
+	}
+}
+
+func (r *Report) Add(diff Diff) {
+	log.Debug("found diff", "diff", diff.String())
+	r.Diffs = append(r.Diffs, diff)
+}
+
+func (r *Report) Finish() {
+	r.Ended = time.Now()
+}
+
+func (r Report) OK() bool {
+	return len(r.Diffs) < 1
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func (r Report) WriteText(w io.Writer) error {
+	result := "ok"
+	if !r.OK() {
+		result = "mismatched"
+	}
+
+	if _, err := fmt.Fprintf(
+		w,
+		"verify [%d, %d]: %s\nblocks=%d transactions=%d operations=%d accounts=%d diffs=%d elapsed=%s\n",
+		r.Start,
+		r.End,
+		result,
+		r.Blocks,
+		r.Transactions,
+		r.Operations,
+		r.Accounts,
+		len(r.Diffs),
+		r.Ended.Sub(r.Started),
+	); err != nil {
+		return err
+	}
+
+	for _, d := range r.Diffs {
+		if _, err := fmt.Fprintln(w, "-", d.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package verify
+
+import (
+	"sort"
+
+	sebakblock "boscoin.io/sebak/lib/block"
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakerrors "boscoin.io/sebak/lib/errors"
+	logging "github.com/inconshreveable/log15"
+
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/sebak"
+	"github.com/spikeekips/naru/storage"
+)
+
+// Verifier compares the local `element.Potion` with the remote SEBAK storage
+// within the height range, `[start, end]`.
+type Verifier struct {
+	sst      *sebak.Storage
+	potion   element.Potion
+	start    uint64
+	end      uint64
+	skip     map[string]struct{}
+	accounts map[string]uint64
+}
+
+func NewVerifier(sst *sebak.Storage, potion element.Potion, start, end uint64) *Verifier {
+	if start < sebakcommon.GenesisBlockHeight {
+		start = sebakcommon.GenesisBlockHeight
+	}
+
+	return &Verifier{
+		sst:      sst.New(),
+		potion:   potion,
+		start:    start,
+		end:      end,
+		skip:     map[string]struct{}{},
+		accounts: map[string]uint64{},
+	}
+}
+
+// SkipAccounts excludes the accounts from comparing balance; for example,
+// the genesis account is not updated by digest.
+func (v *Verifier) SkipAccounts(addresses ...string) *Verifier {
+	for _, address := range addresses {
+		v.skip[address] = struct{}{}
+	}
+
+	return v
+}
+
+func (v *Verifier) Verify() (Report, error) {
+	if err := v.sst.Provider().Open(); err != nil {
+		if err != sebak.ProviderNotClosedError {
+			return Report{}, err
+		}
+	}
+	defer v.sst.Provider().Close()
+
+	report := NewReport(v.start, v.end)
+
+	log_ := log.New(logging.Ctx{"start": v.start, "end": v.end})
+	log_.Debug("start verify")
+
+	var cursor []byte
+	if v.start > sebakcommon.GenesisBlockHeight {
+		cursor = []byte(sebak.BlockHeightKey(v.start - 1))
+	}
+
+	iterFunc, closeFunc := sebak.GetBlocks(
+		v.sst,
+		storage.NewDefaultListOptions(false, cursor, v.end-v.start+1),
+	)
+	if iterFunc == nil {
+		return report, FailedToGetRemoteBlocks.New()
+	}
+	defer closeFunc()
+
+	expected := v.start
+	for {
+		blk, next := iterFunc()
+		if !next {
+			break
+		}
+		if blk.Header.Height > v.end {
+			break
+		}
+
+		for ; expected < blk.Header.Height; expected++ {
+			report.Add(NewDiff(DiffBlock, expected, nil, nil, "missing in remote"))
+		}
+		expected = blk.Header.Height + 1
+
+		if err := v.verifyBlock(&report, blk); err != nil {
+			log_.Error("failed to verify block", "height", blk.Header.Height, "error", err)
+			return report, err
+		}
+	}
+
+	for ; expected <= v.end; expected++ {
+		report.Add(NewDiff(DiffBlock, expected, nil, nil, "missing in remote"))
+	}
+
+	if err := v.verifyAccounts(&report); err != nil {
+		log_.Error("failed to verify accounts", "error", err)
+		return report, err
+	}
+
+	report.Finish()
+
+	log_.Debug(
+		"verify done",
+		"blocks", report.Blocks,
+		"transactions", report.Transactions,
+		"operations", report.Operations,
+		"accounts", report.Accounts,
+		"diffs", len(report.Diffs),
+		"elapsed", report.Ended.Sub(report.Started),
+	)
+
+	return report, nil
+}
+
+func (v *Verifier) verifyBlock(report *Report, blk sebakblock.Block) error {
+	height := blk.Header.Height
+	report.Blocks++
+
+	local, err := v.potion.BlockByHeight(height)
+	if isNotFound(err) {
+		report.Add(NewDiff(DiffBlock, height, nil, nil, "missing in local").SetKey(blk.Hash))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if local.Hash != blk.Hash {
+		report.Add(NewDiff(DiffBlock, height, blk.Hash, local.Hash, "hash mismatch").SetKey(blk.Hash))
+		return nil
+	}
+
+	remoteHashes := blockTransactions(blk.Transactions, blk.ProposerTransaction)
+	localHashes := blockTransactions(local.Transactions, local.ProposerTransaction)
+
+	for _, hash := range difference(remoteHashes, localHashes) {
+		report.Add(NewDiff(DiffTransaction, height, nil, nil, "missing in local block").SetKey(hash))
+	}
+	for _, hash := range difference(localHashes, remoteHashes) {
+		report.Add(NewDiff(DiffTransaction, height, nil, nil, "missing in remote block").SetKey(hash))
+	}
+
+	txs, err := sebak.GetTransactions(v.sst, remoteHashes...)
+	if err != nil {
+		return err
+	}
+
+	eb := element.NewBlock(blk)
+	for _, txm := range txs {
+		report.Transactions++
+
+		tx := element.NewTransaction(txm.Transaction, eb, txm.Raw)
+		for _, address := range tx.AllAccounts() {
+			v.accounts[address] = height
+		}
+
+		if err := v.verifyTransaction(report, height, tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *Verifier) verifyTransaction(report *Report, height uint64, tx element.Transaction) error {
+	local, err := v.potion.Transaction(tx.Hash)
+	if isNotFound(err) {
+		report.Add(NewDiff(DiffTransaction, height, nil, nil, "missing in local").SetKey(tx.Hash))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if local.Block != height {
+		report.Add(NewDiff(DiffTransaction, height, height, local.Block, "block mismatch").SetKey(tx.Hash))
+	}
+
+	remoteOps := uint64(len(tx.Operations))
+	report.Operations += remoteOps
+
+	var localOps uint64
+	for i := uint64(0); i < remoteOps; i++ {
+		if _, err := v.potion.Operation(element.GetOperationHash(tx.Hash, i)); err == nil {
+			localOps++
+		} else if !isNotFound(err) {
+			return err
+		}
+	}
+
+	if localOps != remoteOps {
+		report.Add(NewDiff(DiffOperation, height, remoteOps, localOps, "operation count mismatch").SetKey(tx.Hash))
+	}
+
+	return nil
+}
+
+// verifyAccounts compares the balance of the accounts, which are touched in
+// the range. The remote and local account are the latest state, so the
+// local should be digested up to the latest block.
+func (v *Verifier) verifyAccounts(report *Report) error {
+	var addresses []string
+	for address := range v.accounts {
+		if _, found := v.skip[address]; found {
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		report.Accounts++
+		height := v.accounts[address]
+
+		remote, err := sebak.GetAccount(v.sst, address)
+		if err != nil {
+			return err
+		}
+
+		local, err := v.potion.Account(address)
+		if isNotFound(err) {
+			report.Add(NewDiff(DiffAccount, height, remote.Balance, nil, "missing in local").SetKey(address))
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if local.Balance != remote.Balance {
+			report.Add(NewDiff(DiffAccount, height, remote.Balance, local.Balance, "balance mismatch").SetKey(address))
+		}
+		if local.SequenceID != remote.SequenceID {
+			report.Add(NewDiff(DiffAccount, height, remote.SequenceID, local.SequenceID, "sequence id mismatch").SetKey(address))
+		}
+	}
+
+	return nil
+}
+
+// isNotFound checks whether the element is not in the local; by the storage,
+// the potion returns storage.NotFound or sebakerrors.StorageRecordDoesNotExist.
+func isNotFound(err error) bool {
+	if storage.NotFound.Equal(err) {
+		return true
+	}
+
+	e, ok := err.(*sebakerrors.Error)
+	return ok && e.Code == sebakerrors.StorageRecordDoesNotExist.Code
+}
+
+func blockTransactions(hashes []string, proposerTransaction string) []string {
+	var all []string
+	all = append(all, hashes...)
+	if len(proposerTransaction) > 0 {
+		all = append(all, proposerTransaction)
+	}
+
+	return all
+}
+
+// difference returns the items of `a`, which are not in `b`.
+func difference(a, b []string) []string {
+	m := map[string]struct{}{}
+	for _, i := range b {
+		m[i] = struct{}{}
+	}
+
+	var d []string
+	for _, i := range a {
+		if _, found := m[i]; !found {
+			d = append(d, i)
+		}
+	}
+
+	return d
+}
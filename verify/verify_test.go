@@ -0,0 +1,72 @@
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	sebakerrors "boscoin.io/sebak/lib/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/storage"
+)
+
+type testVerify struct {
+	suite.Suite
+}
+
+func (t *testVerify) TestDifference() {
+	t.Equal([]string{"a", "c"}, difference([]string{"a", "b", "c"}, []string{"b", "d"}))
+	t.Nil(difference([]string{"a"}, []string{"a"}))
+	t.Equal([]string{"a"}, difference([]string{"a"}, nil))
+}
+
+func (t *testVerify) TestBlockTransactions() {
+	t.Equal([]string{"a", "b", "p"}, blockTransactions([]string{"a", "b"}, "p"))
+	t.Equal([]string{"a"}, blockTransactions([]string{"a"}, ""))
+}
+
+func (t *testVerify) TestIsNotFound() {
+	t.True(isNotFound(storage.NotFound.New()))
+	t.True(isNotFound(sebakerrors.StorageRecordDoesNotExist))
+	t.False(isNotFound(nil))
+	t.False(isNotFound(storage.DecodeValueFailed.New()))
+	t.False(isNotFound(errors.New("connection refused")))
+}
+
+func (t *testVerify) TestReport() {
+	report := NewReport(1, 10)
+	t.True(report.OK())
+
+	report.Add(NewDiff(DiffBlock, 3, "remote-hash", "local-hash", "hash mismatch").SetKey("remote-hash"))
+	report.Add(NewDiff(DiffAccount, 4, uint64(10), uint64(9), "balance mismatch").SetKey("GABC"))
+	report.Finish()
+	t.False(report.OK())
+
+	{ // text
+		var b bytes.Buffer
+		t.NoError(report.WriteText(&b))
+
+		lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+		t.Equal(4, len(lines))
+		t.Contains(lines[0], "mismatched")
+		t.Equal("- [block] height=3 key=remote-hash: hash mismatch (remote=remote-hash local=local-hash)", lines[2])
+	}
+
+	{ // json
+		var b bytes.Buffer
+		t.NoError(report.WriteJSON(&b))
+
+		var decoded Report
+		t.NoError(json.Unmarshal(b.Bytes(), &decoded))
+		t.Equal(2, len(decoded.Diffs))
+		t.Equal(DiffAccount, decoded.Diffs[1].Kind)
+		t.Equal("GABC", decoded.Diffs[1].Key)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	suite.Run(t, new(testVerify))
+}
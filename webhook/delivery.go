@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/storage"
+)
+
+const (
+	HeaderSignature string = "X-Naru-Signature"
+	HeaderDelivery  string = "X-Naru-Delivery"
+	HeaderHook      string = "X-Naru-Hook"
+)
+
+// Delivery is the queued webhook request; it is stored until it is delivered
+// or it exceeds the maximum attempts.
+type Delivery struct {
+	ID          string            `json:"id"`
+	Hook        string            `json:"hook"`
+	Operation   element.Operation `json:"operation"`
+	Attempts    int               `json:"attempts"`
+	NextAttempt time.Time         `json:"next_attempt"`
+	Created     time.Time         `json:"created"`
+	LastError   string            `json:"last_error,omitempty"`
+}
+
+func NewDelivery(hook Hook, op element.Operation) Delivery {
+	now := time.Now()
+
+	return Delivery{
+		ID:          common.SequentialUUID(),
+		Hook:        hook.ID,
+		Operation:   op,
+		NextAttempt: now,
+		Created:     now,
+	}
+}
+
+func GetDeliveryKey(id string) string {
+	return element.WebhookDeliveryPrefix + id
+}
+
+func (d Delivery) Save(st storage.Storage) error {
+	return st.Insert(GetDeliveryKey(d.ID), d)
+}
+
+// Payload is the body of webhook request.
+type Payload struct {
+	Hook      string            `json:"hook"`
+	Delivery  string            `json:"delivery"`
+	Operation element.Operation `json:"operation"`
+}
+
+func (d Delivery) Payload() Payload {
+	return Payload{Hook: d.Hook, Delivery: d.ID, Operation: d.Operation}
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body; it is sent by
+// `X-Naru-Signature` header like "sha256=<signature>".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
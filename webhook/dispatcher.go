@@ -0,0 +1,449 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spikeekips/naru/common"
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/metrics"
+	"github.com/spikeekips/naru/storage"
+)
+
+// EventNewDelivery is triggered after the new deliveries are stored.
+const EventNewDelivery string = element.EventPrefixNewOperation + element.WebhookDeliveryPrefix
+
+var (
+	DefaultMaxAttempts = 10
+	DefaultTimeout     = time.Second * 10
+	DefaultMinBackoff  = time.Second * 5
+	DefaultMaxBackoff  = time.Hour
+	DefaultInterval    = time.Second * 10
+	deliveriesPerRound = 100
+)
+
+// Dispatcher delivers the operations to the matched hooks. The deliveries are
+// stored by `OnAfterSaveOperation` hook in the same batch with the operation,
+// so they are queued only when the operation is committed; and they are
+// delivered after the batch is written. The failed deliveries are retried with
+// exponential backoff until `MaxAttempts`.
+type Dispatcher struct {
+	sync.RWMutex
+	st          storage.Storage
+	hooks       map[string]Hook
+	client      *http.Client
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	Interval    time.Duration
+
+	onAfterSaveOperation func(storage.Storage, element.Operation)
+	cancel               context.CancelFunc
+	done                 chan struct{}
+}
+
+func NewDispatcher(st storage.Storage, timeout time.Duration) *Dispatcher {
+	if timeout < 1 {
+		timeout = DefaultTimeout
+	}
+
+	d := &Dispatcher{
+		st:          st,
+		hooks:       map[string]Hook{},
+		client:      &http.Client{Timeout: timeout},
+		MaxAttempts: DefaultMaxAttempts,
+		MinBackoff:  DefaultMinBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+		Interval:    DefaultInterval,
+	}
+	d.onAfterSaveOperation = func(st storage.Storage, op element.Operation) {
+		d.queue(st, op)
+	}
+
+	return d
+}
+
+// Load loads the stored hooks and the static hooks from config file.
+func (d *Dispatcher) Load(static []Hook) error {
+	iterFunc, closeFunc, err := d.st.Iterator(element.WebhookPrefix, Hook{}, storage.NewDefaultListOptions(false, nil, 0))
+	if err != nil {
+		return err
+	}
+	defer closeFunc()
+
+	d.Lock()
+	defer d.Unlock()
+
+	for {
+		r, next, err := iterFunc()
+		if err != nil {
+			return err
+		}
+		if !next {
+			break
+		}
+
+		h := r.Value.(Hook)
+		d.hooks[h.ID] = h
+	}
+
+	for _, h := range static {
+		h.static = true
+		d.hooks[h.ID] = h
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) Hooks() []Hook {
+	d.RLock()
+	defer d.RUnlock()
+
+	var hooks []Hook
+	for _, h := range d.hooks {
+		hooks = append(hooks, h)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+
+	return hooks
+}
+
+func (d *Dispatcher) Hook(id string) (Hook, bool) {
+	d.RLock()
+	defer d.RUnlock()
+
+	h, found := d.hooks[id]
+	return h, found
+}
+
+// AddHook stores the new hook.
+func (d *Dispatcher) AddHook(h Hook) error {
+	if err := h.Validate(); err != nil {
+		return err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if _, found := d.hooks[h.ID]; found {
+		return HookAlreadyExists.New().SetData("id", h.ID)
+	}
+
+	h.static = false
+	if err := h.Save(d.st); err != nil {
+		return err
+	}
+	d.hooks[h.ID] = h
+
+	return nil
+}
+
+// RemoveHook removes the stored hook; the remaining deliveries of the hook are
+// discarded.
+func (d *Dispatcher) RemoveHook(id string) error {
+	d.Lock()
+	defer d.Unlock()
+
+	h, found := d.hooks[id]
+	if !found {
+		return HookNotFound.New().SetData("id", id)
+	} else if h.static {
+		return StaticHook.New().SetData("id", id)
+	}
+
+	if err := d.st.Delete(GetHookKey(id)); err != nil {
+		return err
+	}
+	delete(d.hooks, id)
+
+	return nil
+}
+
+// Start starts to queue and deliver. The operations, which are saved before
+// Start, are not delivered.
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.Lock()
+	if d.done != nil {
+		d.Unlock()
+		return
+	}
+
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.done = make(chan struct{})
+	done := d.done
+	d.Unlock()
+
+	// NOTE the observer is not touched under the lock of Dispatcher; the
+	// hook, which is called under the lock of observer, also locks
+	// Dispatcher.
+	storage.Observer.Sync(element.EventOnAfterSaveOperation, d.onAfterSaveOperation)
+
+	// NOTE the event only wakes up the delivery loop, so it can be dropped
+	wake := storage.Observer.Subscribe(EventNewDelivery, 1, common.SubscriberDrop)
+
+	go d.run(ctx, wake, done)
+}
+
+// Stop stops queueing and waits until the current delivery is finished.
+func (d *Dispatcher) Stop() error {
+	d.Lock()
+	cancel, done := d.cancel, d.done
+	d.cancel, d.done = nil, nil
+	d.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	storage.Observer.Off(element.EventOnAfterSaveOperation, d.onAfterSaveOperation)
+
+	cancel()
+	<-done
+
+	log.Debug("webhook dispatcher stopped")
+
+	return nil
+}
+
+func (d *Dispatcher) queue(st storage.Storage, op element.Operation) {
+	d.RLock()
+	defer d.RUnlock()
+
+	var queued bool
+	for _, h := range d.hooks {
+		if !h.Match(op) {
+			continue
+		}
+
+		delivery := NewDelivery(h, op)
+		if err := delivery.Save(st); err != nil {
+			log.Error("failed to queue delivery", "hook", h.ID, "operation", op.Hash, "error", err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		st.Event(EventNewDelivery)
+	}
+}
+
+func (d *Dispatcher) run(ctx context.Context, wake *common.Subscriber, done chan struct{}) {
+	defer close(done)
+	defer wake.Close()
+
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake.Events():
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverAll delivers the deliveries, which are ready to be sent. The
+// deliveries of each hook are delivered concurrently with the other hooks, so
+// the dead endpoint does not delay the other hooks.
+func (d *Dispatcher) deliverAll(ctx context.Context) {
+	for {
+		deliveries, err := d.ready(time.Now())
+		if err != nil {
+			log.Error("failed to load deliveries", "error", err)
+			return
+		}
+
+		var hooks []string
+		byHook := map[string][]Delivery{}
+		for _, delivery := range deliveries {
+			if _, found := byHook[delivery.Hook]; !found {
+				hooks = append(hooks, delivery.Hook)
+			}
+			byHook[delivery.Hook] = append(byHook[delivery.Hook], delivery)
+		}
+
+		var wg sync.WaitGroup
+		for _, id := range hooks {
+			wg.Add(1)
+			go func(deliveries []Delivery) {
+				defer wg.Done()
+				d.handleHook(ctx, deliveries)
+			}(byHook[id])
+		}
+		wg.Wait()
+
+		if ctx.Err() != nil || len(deliveries) < deliveriesPerRound {
+			return
+		}
+	}
+}
+
+// handleHook delivers the deliveries of one hook in order. After the first
+// failure, the remaining deliveries of the hook are postponed to the next
+// attempt of the failed one instead of waiting the timeout of each.
+func (d *Dispatcher) handleHook(ctx context.Context, deliveries []Delivery) {
+	for i, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+
+		next, failed := d.handle(ctx, delivery)
+		if !failed {
+			continue
+		}
+
+		for _, postponed := range deliveries[i+1:] {
+			postponed.NextAttempt = next
+			if err := d.st.Update(GetDeliveryKey(postponed.ID), postponed); err != nil {
+				log.Error("failed to update delivery", "delivery", postponed.ID, "error", err)
+			}
+		}
+
+		return
+	}
+}
+
+func (d *Dispatcher) ready(now time.Time) ([]Delivery, error) {
+	iterFunc, closeFunc, err := d.st.Iterator(
+		element.WebhookDeliveryPrefix,
+		Delivery{},
+		storage.NewDefaultListOptions(false, nil, 0),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFunc()
+
+	var deliveries []Delivery
+	for len(deliveries) < deliveriesPerRound {
+		r, next, err := iterFunc()
+		if err != nil {
+			return nil, err
+		}
+		if !next {
+			break
+		}
+
+		delivery := r.Value.(Delivery)
+		if delivery.NextAttempt.After(now) {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// handle delivers the delivery; if failed, returns the time of next attempt.
+func (d *Dispatcher) handle(ctx context.Context, delivery Delivery) (time.Time, bool) {
+	hook, found := d.Hook(delivery.Hook)
+	if !found {
+		log.Debug("hook was removed; delivery discarded", "hook", delivery.Hook, "delivery", delivery.ID)
+		d.remove(delivery)
+		return time.Time{}, false
+	}
+
+	err := d.deliver(ctx, hook, delivery)
+	if ctx.Err() != nil { // stopped; will be delivered again after restart
+		return time.Time{}, false
+	} else if err == nil {
+		log.Debug("delivered", "hook", hook.ID, "delivery", delivery.ID)
+		metrics.WebhookDeliveriesTotal.WithLabelValues("ok").Inc()
+		d.remove(delivery)
+		return time.Time{}, false
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	delivery.NextAttempt = time.Now().Add(d.backoff(delivery.Attempts))
+
+	if delivery.Attempts >= d.MaxAttempts {
+		log.Error(
+			"failed to deliver; delivery discarded",
+			"hook", hook.ID,
+			"delivery", delivery.ID,
+			"attempts", delivery.Attempts,
+			"error", err,
+		)
+		metrics.WebhookDeliveriesTotal.WithLabelValues("failed").Inc()
+		d.remove(delivery)
+		return delivery.NextAttempt, true
+	}
+
+	log.Debug(
+		"failed to deliver; will be retried",
+		"hook", hook.ID,
+		"delivery", delivery.ID,
+		"attempts", delivery.Attempts,
+		"next", delivery.NextAttempt,
+		"error", err,
+	)
+	metrics.WebhookDeliveriesTotal.WithLabelValues("retry").Inc()
+
+	if err := d.st.Update(GetDeliveryKey(delivery.ID), delivery); err != nil {
+		log.Error("failed to update delivery", "delivery", delivery.ID, "error", err)
+	}
+
+	return delivery.NextAttempt, true
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.MinBackoff
+	for i := 1; i < attempts; i++ {
+		if backoff *= 2; backoff >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+
+	return backoff
+}
+
+func (d *Dispatcher) remove(delivery Delivery) {
+	if err := d.st.Delete(GetDeliveryKey(delivery.ID)); err != nil {
+		log.Error("failed to remove delivery", "delivery", delivery.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, hook Hook, delivery Delivery) error {
+	body, err := json.Marshal(delivery.Payload())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderHook, hook.ID)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	if len(hook.Secret) > 0 {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(hook.Secret, body))
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return DeliveryFailed.New().SetData("status", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/spikeekips/naru/config"
+	"github.com/spikeekips/naru/element"
+	leveldbstorage "github.com/spikeekips/naru/storage/backend/leveldb"
+)
+
+type received struct {
+	header  http.Header
+	body    []byte
+	payload Payload
+}
+
+type testDispatcher struct {
+	suite.Suite
+	s *leveldbstorage.Storage
+	d *Dispatcher
+}
+
+func (t *testDispatcher) SetupTest() {
+	c := &config.LevelDBStorage{Path: "memory://"}
+	t.NoError(c.Validate())
+
+	s, err := leveldbstorage.NewStorage(c)
+	t.NoError(err)
+
+	t.s = s
+	t.d = NewDispatcher(s, time.Second)
+	t.d.MinBackoff = time.Millisecond * 10
+	t.d.MaxBackoff = time.Millisecond * 100
+	t.d.Interval = time.Millisecond * 10
+}
+
+func (t *testDispatcher) TearDownTest() {
+	t.d.Stop()
+	t.s.Close()
+}
+
+func (t *testDispatcher) server(statuses ...int) (*httptest.Server, <-chan received) {
+	ch := make(chan received, 10)
+
+	var count int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		var payload Payload
+		json.Unmarshal(body, &payload)
+		ch <- received{header: r.Header, body: body, payload: payload}
+
+		status := http.StatusOK
+		if count < len(statuses) {
+			status = statuses[count]
+		}
+		count++
+
+		w.WriteHeader(status)
+	}))
+
+	return ts, ch
+}
+
+func (t *testDispatcher) saveOperation(op element.Operation) {
+	bs, err := t.s.Batch()
+	t.NoError(err)
+
+	t.NoError(op.Save(bs))
+	t.NoError(bs.Write())
+}
+
+func (t *testDispatcher) wait(ch <-chan received) received {
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(time.Second * 2):
+		t.Fail("delivery timed out")
+	}
+
+	return received{}
+}
+
+func (t *testDispatcher) TestDeliverSigned() {
+	ts, ch := t.server()
+	defer ts.Close()
+
+	address := "GDIRF4UWPACXPPI4GW7CMTACTCNDIKJEHZK44RITZB4TD3YUM6CCVNGJ"
+	t.NoError(t.d.AddHook(Hook{ID: "a", URL: ts.URL, Secret: "showme", Addresses: []string{address}}))
+	t.d.Start(context.Background())
+
+	op := element.Operation{Hash: "op0", Type: sebakoperation.TypePayment, Source: address, Amount: 10}
+	t.saveOperation(op)
+
+	r := t.wait(ch)
+	t.Equal("a", r.header.Get(HeaderHook))
+	t.Equal("sha256="+Sign("showme", r.body), r.header.Get(HeaderSignature))
+	t.Equal(r.payload.Delivery, r.header.Get(HeaderDelivery))
+	t.Equal(op.Hash, r.payload.Operation.Hash)
+}
+
+func (t *testDispatcher) TestNotMatched() {
+	ts, ch := t.server()
+	defer ts.Close()
+
+	t.NoError(t.d.AddHook(Hook{ID: "a", URL: ts.URL, MinAmount: sebakcommon.Amount(100)}))
+	t.d.Start(context.Background())
+
+	t.saveOperation(element.Operation{Hash: "op0", Type: sebakoperation.TypePayment, Amount: 10})
+	t.saveOperation(element.Operation{Hash: "op1", Type: sebakoperation.TypePayment, Amount: 100})
+
+	r := t.wait(ch)
+	t.Equal("op1", r.payload.Operation.Hash)
+}
+
+func (t *testDispatcher) TestRetry() {
+	ts, ch := t.server(http.StatusInternalServerError, http.StatusInternalServerError)
+	defer ts.Close()
+
+	t.NoError(t.d.AddHook(Hook{ID: "a", URL: ts.URL}))
+	t.d.Start(context.Background())
+
+	t.saveOperation(element.Operation{Hash: "op0", Type: sebakoperation.TypePayment})
+
+	first := t.wait(ch)
+	t.wait(ch)
+	last := t.wait(ch)
+	t.Equal(first.payload.Delivery, last.payload.Delivery)
+
+	// NOTE the delivered one is removed from the queue
+	time.Sleep(time.Millisecond * 50)
+	deliveries, err := t.d.ready(time.Now().Add(time.Hour))
+	t.NoError(err)
+	t.Empty(deliveries)
+}
+
+// TestSlowHook checks the slow hook does not delay the deliveries of the other
+// hooks.
+func (t *testDispatcher) TestSlowHook() {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	ts, ch := t.server()
+	defer ts.Close()
+
+	t.NoError(t.d.AddHook(Hook{ID: "a", URL: slow.URL}))
+	t.NoError(t.d.AddHook(Hook{ID: "b", URL: ts.URL}))
+	t.d.Start(context.Background())
+
+	t.saveOperation(element.Operation{Hash: "op0", Type: sebakoperation.TypePayment})
+
+	select {
+	case r := <-ch:
+		t.Equal("b", r.header.Get(HeaderHook))
+	case <-time.After(time.Millisecond * 500):
+		t.Fail("delivery was delayed by the slow hook")
+	}
+}
+
+func (t *testDispatcher) TestRemoveStaticHook() {
+	t.NoError(t.d.Load([]Hook{{ID: "static", URL: "http://localhost"}}))
+
+	err := t.d.RemoveHook("static")
+	t.True(StaticHook.Equal(err))
+
+	err = t.d.RemoveHook("unknown")
+	t.True(HookNotFound.Equal(err))
+}
+
+func TestDispatcher(t *testing.T) {
+	suite.Run(t, new(testDispatcher))
+}
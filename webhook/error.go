@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"github.com/spikeekips/naru/common"
+)
+
+const (
+	InvalidHookCode = iota + 100
+	HookNotFoundCode
+	HookAlreadyExistsCode
+	StaticHookCode
+	DeliveryFailedCode
+)
+
+var (
+	InvalidHook       = common.NewError(InvalidHookCode, "invalid webhook")
+	HookNotFound      = common.NewError(HookNotFoundCode, "webhook not found")
+	HookAlreadyExists = common.NewError(HookAlreadyExistsCode, "webhook already exists")
+	StaticHook        = common.NewError(StaticHookCode, "webhook from config file can not be changed")
+	DeliveryFailed    = common.NewError(DeliveryFailedCode, "failed to deliver webhook")
+)
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net/url"
+
+	sebakcommon "boscoin.io/sebak/lib/common"
+	sebakoperation "boscoin.io/sebak/lib/transaction/operation"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/spikeekips/naru/element"
+	"github.com/spikeekips/naru/storage"
+)
+
+// Hook is the webhook registration. The operation is delivered when it matches
+// with all the filters; empty filter matches with any operation.
+type Hook struct {
+	ID        string                         `json:"id" yaml:"id"`
+	URL       string                         `json:"url" yaml:"url"`
+	Secret    string                         `json:"secret,omitempty" yaml:"secret"`
+	Addresses []string                       `json:"addresses,omitempty" yaml:"addresses"`
+	Types     []sebakoperation.OperationType `json:"types,omitempty" yaml:"types"`
+	MinAmount sebakcommon.Amount             `json:"min_amount,omitempty" yaml:"min_amount"`
+
+	static bool
+}
+
+func GetHookKey(id string) string {
+	return element.WebhookPrefix + id
+}
+
+func (h Hook) Validate() error {
+	if len(h.ID) < 1 {
+		return InvalidHook.New().SetData("error", "empty id")
+	}
+
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return InvalidHook.New().SetData("error", err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return InvalidHook.New().SetData("error", "url should be http or https")
+	}
+
+	return nil
+}
+
+// Static returns true if the hook is loaded from the config file; it can not
+// be removed by admin api.
+func (h Hook) Static() bool {
+	return h.static
+}
+
+func (h Hook) Match(op element.Operation) bool {
+	if len(h.Addresses) > 0 {
+		var found bool
+		for _, a := range h.Addresses {
+			if a == op.Source || a == op.Target {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(h.Types) > 0 {
+		var found bool
+		for _, t := range h.Types {
+			if t == op.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return op.Amount >= h.MinAmount
+}
+
+// WithoutSecret is used to show the hook in api.
+func (h Hook) WithoutSecret() Hook {
+	h.Secret = ""
+	return h
+}
+
+func (h Hook) Save(st storage.Storage) error {
+	return st.Insert(GetHookKey(h.ID), h)
+}
+
+// LoadHooksFile loads the hooks from yaml file.
+//
+//	hooks:
+//	  - id: payments
+//	    url: https://example.com/naru
+//	    secret: showme
+//	    addresses: [GDIRF4UWPACXPPI4GW7CMTACTCNDIKJEHZK44RITZB4TD3YUM6CCVNGJ]
+//	    types: [payment]
+//	    min_amount: 10000000
+func LoadHooksFile(path string) ([]Hook, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f struct {
+		Hooks []Hook `yaml:"hooks"`
+	}
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+
+	for i := range f.Hooks {
+		if err := f.Hooks[i].Validate(); err != nil {
+			return nil, err
+		}
+		f.Hooks[i].static = true
+	}
+
+	return f.Hooks, nil
+}
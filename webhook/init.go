@@ -0,0 +1,11 @@
+package webhook
+
+import (
+	logging "github.com/inconshreveable/log15"
+)
+
+var log logging.Logger = logging.New("module", "webhook")
+
+func Log() logging.Logger {
+	return log
+}